@@ -0,0 +1,60 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestActionFailedJSON(t *testing.T) {
+	var out, errOut bytes.Buffer
+	a := App{Out: &out, Err: &errOut}
+	code := a.actionFailed(GlobalFlags{JSON: true}, errors.New("boom"), exitFailure)
+	if code != exitFailure {
+		t.Fatalf("expected exit %d, got %d", exitFailure, code)
+	}
+	if got := strings.TrimSpace(out.String()); got != `{"ok":false,"error":"boom"}` {
+		t.Fatalf("unexpected stdout: %s", got)
+	}
+	if errOut.Len() != 0 {
+		t.Fatalf("expected empty stderr, got %q", errOut.String())
+	}
+}
+
+func TestActionSucceededJSON(t *testing.T) {
+	var out bytes.Buffer
+	a := App{Out: &out}
+	a.actionSucceeded(GlobalFlags{JSON: true}, []byte(`{"n":2}`))
+	if got := strings.TrimSpace(out.String()); got != `{"ok":true,"result":{"n":2}}` {
+		t.Fatalf("unexpected stdout: %s", got)
+	}
+	out.Reset()
+	a.actionSucceeded(GlobalFlags{}, nil)
+	if out.Len() != 0 {
+		t.Fatalf("expected no output without --json, got %q", out.String())
+	}
+}
+
+func TestLogResolved(t *testing.T) {
+	var errOut bytes.Buffer
+	a := App{Err: &errOut}
+	a.logResolved(GlobalFlags{Verbose: true}, 2, selectorFor(GlobalFlags{}, "Sign in"))
+	if got := errOut.String(); got != "resolved tab=2 selector=\"text=Sign in\"\n" {
+		t.Fatalf("unexpected stderr: %q", got)
+	}
+	errOut.Reset()
+	a.logResolved(GlobalFlags{Verbose: true}, 1, flagSelectorFor("#main"))
+	if got := errOut.String(); got != "resolved tab=1 selector=\"css=#main\"\n" {
+		t.Fatalf("unexpected stderr for --selector: %q", got)
+	}
+	if got := flagSelectorFor("text=Docs"); got != "text=Docs" {
+		t.Fatalf("expected prefixed --selector to be kept, got %q", got)
+	}
+	errOut.Reset()
+	a.logResolved(GlobalFlags{Verbose: true, Quiet: true}, 2, "")
+	a.logResolved(GlobalFlags{}, 2, "")
+	if errOut.Len() != 0 {
+		t.Fatalf("expected no output, got %q", errOut.String())
+	}
+}
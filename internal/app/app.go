@@ -1,13 +1,26 @@
 package app
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
+	"math"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/playwright-community/playwright-go"
@@ -19,50 +32,266 @@ import (
 )
 
 type GlobalFlags struct {
-	Profile    string
-	ProfileDir string
-	JSON       bool
-	Plain      bool
-	Quiet      bool
-	Verbose    bool
-	NoStart    bool
-	Save       bool
-	Browser    string
-	Channel    string
-	Headless   bool
-	Headed     bool
-	Tab        int
-	TTL        string
-	Selector   string
-	Main       bool
-	Timeout    string
+	Profile         string
+	ProfileDir      string
+	JSON            bool
+	Plain           bool
+	Quiet           bool
+	Verbose         bool
+	NoStart         bool
+	Save            bool
+	Browser         string
+	Channel         string
+	Headless        bool
+	Headed          bool
+	Tab             int
+	TTL             string
+	Selector        string
+	Main            bool
+	Shadow          bool
+	OnlyVisible     bool
+	Aria            bool
+	Timeout         string
+	DefaultTimeout  time.Duration
+	SelectorTimeout string
+	Retry           int
+	Repeat          int
+	RetryDelay      string
+	Viewport        string
+	Device          string
+	RawSelector     bool
+	Envelope        bool
+	DryRun          bool
+	NoAutoPrune     bool
+	Fresh           bool
+	Config          string
+	Proxy           string
+	ProxyBypass     string
+	DownloadDir     string
+	Headers         []string
+	Geo             string
+	Locale          string
+	Timezone        string
+	Home            string
+	Artifacts       bool
+	Command         string
+	LogLevel        string
+	NoDefaultTab    bool
+	Incognito       bool
+	SocketMode      string
+	ProfileMode     string
+	Output          string
 }
 
 type App struct {
 	Out io.Writer
 	Err io.Writer
+	// script, when set, is the connection of the www run script whose line
+	// is being run; commands use it instead of connecting themselves.
+	script *scriptConn
 }
 
-func (a App) prepare(flags GlobalFlags) (config.Config, profile.Store, daemon.Manager, error) {
-	cfg, err := config.Load(flags.ProfileDir, "")
+// scriptConn is the daemon connection and tab a www run script shares with
+// the commands on its lines.
+type scriptConn struct {
+	client *daemon.Client
+	tabID  int
+}
+
+func (a App) prepare(flags *GlobalFlags) (config.Config, profile.Store, daemon.Manager, error) {
+	cfg, err := config.Load(flags.Config, flags.ProfileDir, "")
 	if err != nil {
 		return config.Config{}, profile.Store{}, daemon.Manager{}, err
 	}
-	store := profile.Store{Root: cfg.ProfileDir, DefaultTTL: cfg.DefaultTTL}
+	if name, ok := cfg.ResolveProfile(flags.Profile); ok {
+		if flags.Verbose && !flags.Quiet {
+			fmt.Fprintf(a.Err, "profile alias %s -> %s\n", flags.Profile, name)
+		}
+		flags.Profile = name
+	}
+	flags.DefaultTimeout = cfg.DefaultTimeout
+	if flags.SocketMode != "" {
+		mode, err := config.ParseFileMode(flags.SocketMode)
+		if err != nil {
+			return config.Config{}, profile.Store{}, daemon.Manager{}, fmt.Errorf("--socket-mode: %w", err)
+		}
+		cfg.SocketMode = mode
+	}
+	if flags.ProfileMode != "" {
+		mode, err := config.ParseFileMode(flags.ProfileMode)
+		if err != nil {
+			return config.Config{}, profile.Store{}, daemon.Manager{}, fmt.Errorf("--profile-mode: %w", err)
+		}
+		cfg.ProfileMode = mode
+	}
+	if err := checkProfileDir(cfg.ProfileDir); err != nil {
+		return config.Config{}, profile.Store{}, daemon.Manager{}, err
+	}
+	store := profile.Store{Root: cfg.ProfileDir, DefaultTTL: cfg.DefaultTTL, Defaults: profileDefaults(cfg.Profiles), MaxAge: cfg.MaxAge}
 	if err := daemon.EnsureProfileDir(cfg.ProfileDir); err != nil {
 		return config.Config{}, profile.Store{}, daemon.Manager{}, err
 	}
-	mgr := daemon.Manager{ProfileDir: cfg.ProfileDir}
+	if cfg.AutoPrune && !flags.NoAutoPrune && !flags.DryRun && a.script == nil && profile.LocksEnforced {
+		go a.autoPrune(store, *flags)
+	}
+	if _, err := parseLogLevel(flags.LogLevel); err != nil {
+		return config.Config{}, profile.Store{}, daemon.Manager{}, err
+	}
+	if _, err := retryDelay(*flags); err != nil {
+		return config.Config{}, profile.Store{}, daemon.Manager{}, err
+	}
+	mgr := daemon.Manager{ProfileDir: cfg.ProfileDir, LogLevel: flags.LogLevel, NoDefaultTab: flags.NoDefaultTab, Incognito: flags.Incognito, SocketMode: flags.SocketMode, ProfileMode: flags.ProfileMode}
 	return cfg, store, mgr, nil
 }
 
+// autoPrune removes expired profiles for auto_prune = true. It runs alongside
+// the command rather than before it, so a prune cut short when the command
+// exits is finished by a later one. It skips the profile the command is about
+// to use and anything locked by a running daemon, and never fails the command.
+func (a App) autoPrune(store profile.Store, flags GlobalFlags) {
+	current := profile.SafeName(flags.Profile)
+	removed, err := store.Prune(func(p profile.Profile) bool {
+		return flags.Profile != "" && p.Name == current
+	})
+	if !flags.Verbose || flags.Quiet {
+		return
+	}
+	for _, p := range removed {
+		fmt.Fprintf(a.Err, "auto-pruned %s\n", p.Name)
+	}
+	if err != nil {
+		fmt.Fprintf(a.Err, "auto-prune: %v\n", err)
+	}
+}
+
+func profileDefaults(defaults map[string]config.ProfileDefaults) map[string]profile.Overrides {
+	if len(defaults) == 0 {
+		return nil
+	}
+	out := make(map[string]profile.Overrides, len(defaults))
+	for name, d := range defaults {
+		out[profile.SafeName(name)] = profile.Overrides{
+			Browser:   d.Browser,
+			Channel:   d.Channel,
+			Headless:  d.Headless,
+			TTL:       d.TTL,
+			UserAgent: d.UserAgent,
+		}
+	}
+	return out
+}
+
+func checkProfileDir(path string) error {
+	if path == "" {
+		return errors.New("profile dir required")
+	}
+	info, err := os.Stat(path)
+	switch {
+	case err == nil && !info.IsDir():
+		return fmt.Errorf("profile dir %s: not a directory", path)
+	case err != nil && !os.IsNotExist(err):
+		return fmt.Errorf("profile dir %s: %s", path, pathErrReason(err))
+	case err != nil:
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			return fmt.Errorf("profile dir %s cannot be created: %s", path, pathErrReason(err))
+		}
+	}
+	testFile := filepath.Join(path, ".www-writetest")
+	if err := os.WriteFile(testFile, []byte("ok"), 0o644); err != nil {
+		return fmt.Errorf("profile dir %s is not writable: %s", path, pathErrReason(err))
+	}
+	_ = os.Remove(testFile)
+	return nil
+}
+
+func pathErrReason(err error) string {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err.Error()
+	}
+	return err.Error()
+}
+
 const (
 	exitSuccess  = 0
 	exitFailure  = 1
 	exitUsage    = 2
 	exitNotFound = 3
+	exitTimeout  = 4
 )
 
+type configResult struct {
+	ProfileDir     string            `json:"profile_dir"`
+	DefaultTTL     string            `json:"default_ttl"`
+	DefaultTimeout string            `json:"default_timeout"`
+	IdleTimeout    string            `json:"idle_timeout"`
+	SocketMode     string            `json:"socket_mode"`
+	ProfileMode    string            `json:"profile_mode"`
+	AutoPrune      bool              `json:"auto_prune"`
+	MaxAge         string            `json:"max_age"`
+	Aliases        map[string]string `json:"aliases,omitempty"`
+	Files          []string          `json:"files"`
+}
+
+// runConfig prints the configuration after merging config files, env vars,
+// and flags, with unset values shown as the defaults the commands use.
+func (a App) runConfig(cfg config.Config, flags GlobalFlags) int {
+	result := configResult{
+		ProfileDir:     cfg.ProfileDir,
+		DefaultTTL:     cfg.DefaultTTL.String(),
+		DefaultTimeout: defaultActionTimeout.String(),
+		IdleTimeout:    daemon.DefaultIdleTimeout.String(),
+		SocketMode:     fmt.Sprintf("%04o", daemon.DefaultSocketMode),
+		ProfileMode:    fmt.Sprintf("%04o", daemon.DefaultDirMode),
+		AutoPrune:      cfg.AutoPrune,
+		MaxAge:         cfg.MaxAge.String(),
+		Aliases:        cfg.Aliases,
+		Files:          cfg.Files,
+	}
+	if cfg.DefaultTimeout > 0 {
+		result.DefaultTimeout = cfg.DefaultTimeout.String()
+	}
+	if cfg.IdleTimeout != nil {
+		result.IdleTimeout = cfg.IdleTimeout.String()
+	}
+	if cfg.SocketMode != 0 {
+		result.SocketMode = fmt.Sprintf("%04o", cfg.SocketMode)
+	}
+	if cfg.ProfileMode != 0 {
+		result.ProfileMode = fmt.Sprintf("%04o", cfg.ProfileMode)
+	}
+	if result.Files == nil {
+		result.Files = []string{}
+	}
+	if flags.JSON {
+		a.printJSON(flags, result)
+		return exitSuccess
+	}
+	fmt.Fprintf(a.Out, "profile_dir=%s\n", result.ProfileDir)
+	fmt.Fprintf(a.Out, "default_ttl=%s\n", result.DefaultTTL)
+	fmt.Fprintf(a.Out, "default_timeout=%s\n", result.DefaultTimeout)
+	fmt.Fprintf(a.Out, "idle_timeout=%s\n", result.IdleTimeout)
+	fmt.Fprintf(a.Out, "socket_mode=%s\n", result.SocketMode)
+	fmt.Fprintf(a.Out, "profile_mode=%s\n", result.ProfileMode)
+	fmt.Fprintf(a.Out, "auto_prune=%t\n", result.AutoPrune)
+	fmt.Fprintf(a.Out, "max_age=%s\n", result.MaxAge)
+	aliases := make([]string, 0, len(result.Aliases))
+	for alias := range result.Aliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		fmt.Fprintf(a.Out, "alias %s=%s\n", alias, result.Aliases[alias])
+	}
+	if len(result.Files) == 0 {
+		fmt.Fprintln(a.Out, "files=(none)")
+	}
+	for _, path := range result.Files {
+		fmt.Fprintf(a.Out, "file=%s\n", path)
+	}
+	return exitSuccess
+}
+
 func (a App) runInstall(flags GlobalFlags) int {
 	browsers := []string{}
 	if flags.Browser != "" {
@@ -73,8 +302,7 @@ func (a App) runInstall(flags GlobalFlags) int {
 		opts.Browsers = browsers
 	}
 	if err := playwright.Install(opts); err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
 	}
 	if !flags.Quiet {
 		if len(browsers) == 0 {
@@ -86,14 +314,72 @@ func (a App) runInstall(flags GlobalFlags) int {
 	return exitSuccess
 }
 
-func (a App) runDoctor(cfg config.Config, flags GlobalFlags) int {
-	type result struct {
-		ProfileDirWritable bool   `json:"profile_dir_writable"`
-		ProfileDir         string `json:"profile_dir"`
-		PlaywrightOK       bool   `json:"playwright_ok"`
-		BrowsersPath       string `json:"browsers_path"`
+type doctorResult struct {
+	ProfileDirWritable bool            `json:"profile_dir_writable"`
+	ProfileDir         string          `json:"profile_dir"`
+	PlaywrightOK       bool            `json:"playwright_ok"`
+	BrowsersPath       string          `json:"browsers_path"`
+	Browsers           map[string]bool `json:"browsers"`
+	Warnings           []string        `json:"warnings,omitempty"`
+	OK                 bool            `json:"ok"`
+}
+
+func (r *doctorResult) evaluate(strict bool) int {
+	r.Warnings = nil
+	for _, name := range []string{"chromium", "firefox", "webkit"} {
+		if !r.Browsers[name] {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("%s is not installed", name))
+		}
+	}
+	r.OK = r.ProfileDirWritable && r.PlaywrightOK
+	if strict && len(r.Warnings) > 0 {
+		r.OK = false
+	}
+	if r.OK {
+		return exitSuccess
+	}
+	return exitFailure
+}
+
+func playwrightBrowsersDir() string {
+	if dir := os.Getenv("PLAYWRIGHT_BROWSERS_PATH"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	if runtime.GOOS == "darwin" {
+		return filepath.Join(home, "Library", "Caches", "ms-playwright")
+	}
+	if cache := os.Getenv("XDG_CACHE_HOME"); cache != "" {
+		return filepath.Join(cache, "ms-playwright")
+	}
+	return filepath.Join(home, ".cache", "ms-playwright")
+}
+
+func installedBrowsers(dir string) map[string]bool {
+	browsers := map[string]bool{"chromium": false, "firefox": false, "webkit": false}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return browsers
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		for name := range browsers {
+			if strings.HasPrefix(entry.Name(), name+"-") {
+				browsers[name] = true
+			}
+		}
 	}
-	res := result{ProfileDir: cfg.ProfileDir, BrowsersPath: os.Getenv("PLAYWRIGHT_BROWSERS_PATH")}
+	return browsers
+}
+
+func (a App) runDoctor(cfg config.Config, flags GlobalFlags, strict bool) int {
+	res := doctorResult{ProfileDir: cfg.ProfileDir, BrowsersPath: os.Getenv("PLAYWRIGHT_BROWSERS_PATH")}
+	res.Browsers = installedBrowsers(playwrightBrowsersDir())
 	if err := os.MkdirAll(cfg.ProfileDir, 0o755); err == nil {
 		testFile := filepath.Join(cfg.ProfileDir, ".www-writetest")
 		if err := os.WriteFile(testFile, []byte("ok"), 0o644); err == nil {
@@ -105,10 +391,10 @@ func (a App) runDoctor(cfg config.Config, flags GlobalFlags) int {
 		res.PlaywrightOK = true
 		pw.Stop()
 	}
+	code := res.evaluate(strict)
 	if flags.JSON {
-		b, _ := json.MarshalIndent(res, "", "  ")
-		fmt.Fprintln(a.Out, string(b))
-		return exitSuccess
+		a.printJSON(flags, res)
+		return code
 	}
 	fmt.Fprintf(a.Out, "profile_dir=%s\n", res.ProfileDir)
 	fmt.Fprintf(a.Out, "profile_dir_writable=%t\n", res.ProfileDirWritable)
@@ -116,45 +402,142 @@ func (a App) runDoctor(cfg config.Config, flags GlobalFlags) int {
 	if res.BrowsersPath != "" {
 		fmt.Fprintf(a.Out, "browsers_path=%s\n", res.BrowsersPath)
 	}
-	return exitSuccess
+	for _, warning := range res.Warnings {
+		fmt.Fprintf(a.Out, "warning: %s\n", warning)
+	}
+	fmt.Fprintf(a.Out, "ok=%t\n", res.OK)
+	return code
 }
 
-func (a App) runStart(store profile.Store, mgr daemon.Manager, flags GlobalFlags) int {
+func (a App) runStart(store profile.Store, mgr daemon.Manager, flags GlobalFlags, trace *bool, dismiss []string, open bool, url string) int {
 	name := flags.Profile
 	if name == "" {
-		fmt.Fprintln(a.Err, "-p/--profile is required")
-		return exitUsage
+		return a.fail(flags, errors.New("-p/--profile is required"), exitUsage)
 	}
 	overrides, err := overridesFromFlags(flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitUsage
+		return a.fail(flags, err, exitUsage)
 	}
+	overrides.Trace = trace
+	overrides.AutoDismiss = dismiss
+	lock, err := store.LockShared(name)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer lock.Unlock()
+	prev, _ := store.Load(name)
+	wasRunning, _, _ := mgr.IsRunning(profile.SafeName(name))
 	p, _, err := store.Upsert(name, overrides)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
 	}
-	if err := mgr.Start(p.Name); err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+	if wasRunning && prev.Proxy != p.Proxy && !flags.Quiet {
+		fmt.Fprintf(a.Err, "warning: %s is already running with proxy %q; stop it to apply %q\n", p.Name, profile.RedactProxy(prev.Proxy), profile.RedactProxy(p.Proxy))
+	}
+	if wasRunning && flags.Incognito && !flags.Quiet {
+		fmt.Fprintf(a.Err, "warning: %s is already running; stop it to start an incognito session\n", p.Name)
+	}
+	flags.NoStart = false
+	if err := a.ensureRunning(mgr, p.Name, flags); err != nil {
+		return a.fail(flags, err, exitFailure)
 	}
 	_, _ = store.Touch(p.Name)
+	if url != "" || (open && !p.Headless) {
+		if err := a.openStartPage(mgr, p.Name, flags, open && !p.Headless, url); err != nil {
+			return a.fail(flags, err, exitFailure)
+		}
+	}
+	if flags.JSON {
+		result := startResult{Profile: p.Name, Started: true}
+		if info, err := mgr.LoadInfo(p.Name); err == nil {
+			result.PID = info.PID
+			result.Socket = info.Socket
+		}
+		a.printJSON(flags, result)
+		return exitSuccess
+	}
 	if !flags.Quiet {
 		fmt.Fprintf(a.Out, "started %s\n", p.Name)
 	}
 	return exitSuccess
 }
 
-func (a App) runStop(mgr daemon.Manager, flags GlobalFlags) int {
+// autoDismissSelectors resolves start --auto-dismiss into the selector list
+// saved on the profile: the configured list when enabled, empty to turn it off.
+func autoDismissSelectors(cfg config.Config, enabled bool) []string {
+	if !enabled {
+		return []string{}
+	}
+	return slices.Clone(cfg.AutoDismiss)
+}
+
+func (a App) openStartPage(mgr daemon.Manager, name string, flags GlobalFlags, front bool, url string) error {
+	client, err := a.newClient(mgr, name, flags)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return err
+	}
+	if flags.NoDefaultTab && flags.Tab == 0 {
+		tab, err := client.TabNewWithOptions(daemon.TabNewParams{URL: url, TimeoutMs: timeoutMs})
+		if err != nil {
+			return err
+		}
+		if front {
+			return client.Front(tab.ID, timeoutMs)
+		}
+		return nil
+	}
+	tabID := flags.Tab
+	if tabID == 0 {
+		status, err := client.Status()
+		if err != nil {
+			return err
+		}
+		if tabID, err = activeTabIDFromStatus(status); err != nil {
+			return err
+		}
+	}
+	if url != "" {
+		if err := client.Goto(tabID, url, timeoutMs); err != nil {
+			return err
+		}
+	}
+	if front {
+		return client.Front(tabID, timeoutMs)
+	}
+	return nil
+}
+
+type startResult struct {
+	Profile string `json:"profile"`
+	Started bool   `json:"started"`
+	PID     int    `json:"pid,omitempty"`
+	Socket  string `json:"socket,omitempty"`
+}
+
+type stopResult struct {
+	Profile string `json:"profile"`
+	Stopped bool   `json:"stopped"`
+}
+
+func (a App) runStop(store profile.Store, mgr daemon.Manager, flags GlobalFlags, force bool) int {
 	name := flags.Profile
 	if name == "" {
-		fmt.Fprintln(a.Err, "-p/--profile is required")
-		return exitUsage
+		return a.fail(flags, errors.New("-p/--profile is required"), exitUsage)
+	}
+	if isProfileGlob(name) {
+		return a.runStopMatching(store, mgr, flags, name, force)
 	}
 	if err := mgr.Stop(profile.SafeName(name)); err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
+	}
+	if flags.JSON {
+		a.printJSON(flags, stopResult{Profile: name, Stopped: true})
+		return exitSuccess
 	}
 	if !flags.Quiet {
 		fmt.Fprintf(a.Out, "stopped %s\n", name)
@@ -162,15 +545,99 @@ func (a App) runStop(mgr daemon.Manager, flags GlobalFlags) int {
 	return exitSuccess
 }
 
-func (a App) runPs(mgr daemon.Manager, flags GlobalFlags) int {
+// runStopMatching stops every running profile whose name matches pattern.
+func (a App) runStopMatching(store profile.Store, mgr daemon.Manager, flags GlobalFlags, pattern string, force bool) int {
+	matches, err := matchProfiles(store, pattern)
+	if err != nil {
+		return a.fail(flags, err, globErrorCode(err))
+	}
+	running := []string{}
+	for _, name := range matches {
+		ok, _, err := mgr.IsRunning(name)
+		if err != nil {
+			return a.fail(flags, err, exitFailure)
+		}
+		if ok {
+			running = append(running, name)
+		}
+	}
+	if len(running) == 0 {
+		return a.fail(flags, fmt.Errorf("no running profiles match %q", pattern), exitNotFound)
+	}
+	if len(running) > 1 && !force {
+		return a.fail(flags, fmt.Errorf("%q matches %d running profiles (%s); pass --force to stop them all", pattern, len(running), strings.Join(running, ", ")), exitUsage)
+	}
+	results := []stopResult{}
+	code := exitSuccess
+	for _, name := range running {
+		if err := mgr.Stop(name); err != nil {
+			fmt.Fprintf(a.Err, "%s: %v\n", name, err)
+			code = exitFailure
+			continue
+		}
+		results = append(results, stopResult{Profile: name, Stopped: true})
+		if !flags.JSON && !flags.Quiet {
+			fmt.Fprintf(a.Out, "stopped %s\n", name)
+		}
+	}
+	if flags.JSON {
+		a.printJSON(flags, results)
+	}
+	return code
+}
+
+// isProfileGlob reports whether name is a filepath.Match pattern rather than
+// a literal profile name.
+func isProfileGlob(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// matchProfiles returns the stored profiles whose names match pattern, and
+// an error when none do.
+func matchProfiles(store profile.Store, pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	profiles, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, p := range profiles {
+		if ok, _ := filepath.Match(pattern, p.Name); ok {
+			names = append(names, p.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no profiles match %q", pattern)
+	}
+	return names, nil
+}
+
+// globErrorCode maps a matchProfiles error to an exit code.
+func globErrorCode(err error) int {
+	if errors.Is(err, filepath.ErrBadPattern) {
+		return exitUsage
+	}
+	return exitNotFound
+}
+
+func (a App) runPs(mgr daemon.Manager, flags GlobalFlags, since string) int {
+	cutoff, err := sinceCutoff(since, time.Now().UTC())
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
 	infos, err := mgr.RunningProfiles()
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
+	}
+	if !cutoff.IsZero() {
+		infos = slices.DeleteFunc(infos, func(info daemon.Info) bool {
+			return info.StartedAt.Before(cutoff)
+		})
 	}
 	if flags.JSON {
-		b, _ := json.MarshalIndent(infos, "", "  ")
-		fmt.Fprintln(a.Out, string(b))
+		a.printJSON(flags, infos)
 		return exitSuccess
 	}
 	for _, info := range infos {
@@ -179,15 +646,29 @@ func (a App) runPs(mgr daemon.Manager, flags GlobalFlags) int {
 	return exitSuccess
 }
 
-func (a App) runList(store profile.Store, flags GlobalFlags) int {
+type listOptions struct {
+	Since      string
+	Sort       string
+	Reverse    bool
+	NameFilter string
+}
+
+func (a App) runList(store profile.Store, flags GlobalFlags, opts listOptions) int {
+	cutoff, err := sinceCutoff(opts.Since, time.Now().UTC())
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	if err := validateListSort(opts.Sort); err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
 	profiles, err := store.List()
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
 	}
+	profiles = filterProfiles(profiles, cutoff, opts.NameFilter)
+	sortProfiles(profiles, opts.Sort, opts.Reverse)
 	if flags.JSON {
-		b, _ := json.MarshalIndent(profiles, "", "  ")
-		fmt.Fprintln(a.Out, string(b))
+		a.printJSON(flags, redactProfiles(profiles))
 		return exitSuccess
 	}
 	for _, p := range profiles {
@@ -196,48 +677,188 @@ func (a App) runList(store profile.Store, flags GlobalFlags) int {
 	return exitSuccess
 }
 
+func redactProfiles(profiles []profile.Profile) []profile.Profile {
+	redacted := make([]profile.Profile, len(profiles))
+	for i, p := range profiles {
+		redacted[i] = p.Redacted()
+	}
+	return redacted
+}
+
+func validateListSort(key string) error {
+	switch key {
+	case "", "name", "last-used", "created":
+		return nil
+	}
+	return fmt.Errorf("invalid sort %q (use name, last-used, or created)", key)
+}
+
+// filterProfiles keeps profiles used since cutoff (when set) whose name
+// contains substr.
+func filterProfiles(profiles []profile.Profile, cutoff time.Time, substr string) []profile.Profile {
+	return slices.DeleteFunc(profiles, func(p profile.Profile) bool {
+		if !cutoff.IsZero() && p.LastUsed.Before(cutoff) {
+			return true
+		}
+		return substr != "" && !strings.Contains(p.Name, substr)
+	})
+}
+
+// sortProfiles orders profiles by name A-Z, or newest first for last-used
+// and created; reverse flips the order.
+func sortProfiles(profiles []profile.Profile, key string, reverse bool) {
+	less := func(a, b profile.Profile) bool { return a.Name < b.Name }
+	switch key {
+	case "last-used":
+		less = func(a, b profile.Profile) bool { return a.LastUsed.After(b.LastUsed) }
+	case "created":
+		less = func(a, b profile.Profile) bool { return a.CreatedAt.After(b.CreatedAt) }
+	}
+	sort.SliceStable(profiles, func(i, j int) bool {
+		if reverse {
+			return less(profiles[j], profiles[i])
+		}
+		return less(profiles[i], profiles[j])
+	})
+}
+
+// sinceCutoff turns --since into the earliest time to keep; zero when unset.
+func sinceCutoff(value string, now time.Time) (time.Time, error) {
+	if strings.TrimSpace(value) == "" {
+		return time.Time{}, nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || d <= 0 {
+		return time.Time{}, fmt.Errorf("invalid --since %q: expected a positive duration like 30m or 24h", value)
+	}
+	return now.Add(-d), nil
+}
+
 func (a App) runShow(store profile.Store, flags GlobalFlags, args []string) int {
 	if len(args) < 1 {
-		fmt.Fprintln(a.Err, "profile name required")
-		return exitUsage
+		return a.fail(flags, errors.New("profile name required"), exitUsage)
 	}
 	p, err := store.Load(args[0])
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitNotFound
+		return a.fail(flags, err, exitNotFound)
 	}
+	p = p.Redacted()
 	if flags.JSON {
-		b, _ := json.MarshalIndent(p, "", "  ")
-		fmt.Fprintln(a.Out, string(b))
+		a.printJSON(flags, p)
 		return exitSuccess
 	}
 	fmt.Fprintf(a.Out, "name=%s\n", p.Name)
 	fmt.Fprintf(a.Out, "browser=%s channel=%s\n", p.Browser, p.Channel)
 	fmt.Fprintf(a.Out, "headless=%t\n", p.Headless)
+	fmt.Fprintf(a.Out, "viewport=%s\n", profile.FormatViewport(p.Width, p.Height))
+	if p.Device != "" {
+		fmt.Fprintf(a.Out, "device=%s\n", p.Device)
+	}
+	fmt.Fprintf(a.Out, "trace=%t\n", p.Trace)
+	if p.Proxy != "" {
+		fmt.Fprintf(a.Out, "proxy=%s\n", p.Proxy)
+	}
+	if p.ProxyBypass != "" {
+		fmt.Fprintf(a.Out, "proxy_bypass=%s\n", p.ProxyBypass)
+	}
+	if p.UserAgent != "" {
+		fmt.Fprintf(a.Out, "user_agent=%s\n", p.UserAgent)
+	}
+	if len(p.ExtraHeaders) > 0 {
+		names := make([]string, 0, len(p.ExtraHeaders))
+		for name := range p.ExtraHeaders {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(a.Out, "headers=%s\n", strings.Join(names, ","))
+	}
+	if p.Geolocation != nil {
+		fmt.Fprintf(a.Out, "geo=%s\n", formatGeo(*p.Geolocation))
+	}
+	if p.Locale != "" {
+		fmt.Fprintf(a.Out, "locale=%s\n", p.Locale)
+	}
+	if p.Timezone != "" {
+		fmt.Fprintf(a.Out, "timezone=%s\n", p.Timezone)
+	}
+	if p.Home != "" {
+		fmt.Fprintf(a.Out, "home=%s\n", p.Home)
+	}
+	if len(p.AutoDismiss) > 0 {
+		fmt.Fprintf(a.Out, "auto_dismiss=%s\n", strings.Join(p.AutoDismiss, ","))
+	}
+	fmt.Fprintf(a.Out, "download_dir=%s\n", store.DownloadDir(p))
 	fmt.Fprintf(a.Out, "created_at=%s\n", p.CreatedAt.Format(time.RFC3339))
 	fmt.Fprintf(a.Out, "last_used=%s\n", p.LastUsed.Format(time.RFC3339))
 	fmt.Fprintf(a.Out, "ttl=%s\n", profile.FormatTTL(p.TTL))
 	return exitSuccess
 }
 
-func (a App) runRemove(store profile.Store, mgr daemon.Manager, flags GlobalFlags, args []string) int {
+func (a App) runArtifacts(store profile.Store, flags GlobalFlags, name string) int {
+	if _, err := store.Load(name); err != nil {
+		return a.fail(flags, err, exitNotFound)
+	}
+	artifacts, err := store.Artifacts(name)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	if flags.JSON {
+		if artifacts == nil {
+			artifacts = []profile.Artifact{}
+		}
+		a.printJSON(flags, artifacts)
+		return exitSuccess
+	}
+	for _, artifact := range artifacts {
+		fmt.Fprintf(a.Out, "%s %d %s\n", artifact.Path, artifact.Bytes, artifact.ModTime.Format(time.RFC3339))
+	}
+	return exitSuccess
+}
+
+func (a App) runRemove(store profile.Store, mgr daemon.Manager, flags GlobalFlags, args []string, force bool) int {
 	if len(args) == 0 {
-		fmt.Fprintln(a.Err, "profile name required")
-		return exitUsage
+		return a.fail(flags, errors.New("profile name required"), exitUsage)
+	}
+	names := []string{}
+	for _, arg := range args {
+		if !isProfileGlob(arg) {
+			if !slices.Contains(names, arg) {
+				names = append(names, arg)
+			}
+			continue
+		}
+		matches, err := matchProfiles(store, arg)
+		if err != nil {
+			return a.fail(flags, err, globErrorCode(err))
+		}
+		if len(matches) > 1 && !force {
+			return a.fail(flags, fmt.Errorf("%q matches %d profiles (%s); pass --force to remove them all", arg, len(matches), strings.Join(matches, ", ")), exitUsage)
+		}
+		for _, name := range matches {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
 	}
-	for _, name := range args {
+	for _, name := range names {
 		running, _, err := mgr.IsRunning(name)
 		if err != nil {
-			fmt.Fprintln(a.Err, err)
-			return exitFailure
+			return a.fail(flags, err, exitFailure)
 		}
 		if running {
-			fmt.Fprintf(a.Err, "%s is running; stop first\n", name)
-			return exitFailure
+			return a.fail(flags, fmt.Errorf("%s is running; stop first", name), exitFailure)
 		}
-		if err := store.Remove(name); err != nil {
-			fmt.Fprintln(a.Err, err)
-			return exitFailure
+		lock, err := store.TryLockExclusive(name)
+		if errors.Is(err, profile.ErrProfileBusy) {
+			return a.fail(flags, fmt.Errorf("%s is in use; try again later", name), exitFailure)
+		}
+		if err != nil {
+			return a.fail(flags, err, exitFailure)
+		}
+		err = store.Remove(name)
+		_ = lock.Unlock()
+		if err != nil {
+			return a.fail(flags, err, exitFailure)
 		}
 		if !flags.Quiet {
 			fmt.Fprintf(a.Out, "removed %s\n", name)
@@ -249,8 +870,7 @@ func (a App) runRemove(store profile.Store, mgr daemon.Manager, flags GlobalFlag
 func (a App) runPrune(store profile.Store, mgr daemon.Manager, flags GlobalFlags, dryRun bool, force bool) int {
 	profiles, err := store.List()
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
 	}
 	removed := []profile.Profile{}
 	for _, p := range profiles {
@@ -259,23 +879,48 @@ func (a App) runPrune(store profile.Store, mgr daemon.Manager, flags GlobalFlags
 		}
 		running, _, err := mgr.IsRunning(p.Name)
 		if err != nil {
-			fmt.Fprintln(a.Err, err)
-			return exitFailure
+			return a.fail(flags, err, exitFailure)
 		}
 		if running && !force {
 			continue
 		}
 		if !dryRun {
-			if err := store.Remove(p.Name); err != nil {
-				fmt.Fprintln(a.Err, err)
-				return exitFailure
+			if running {
+				if err := mgr.StopWait(p.Name); err != nil {
+					return a.fail(flags, err, exitFailure)
+				}
+			}
+			lock, err := store.TryLockExclusive(p.Name)
+			// A stopped daemon releases its lock as it exits, just after
+			// its socket goes away.
+			for wait := 0; running && errors.Is(err, profile.ErrProfileBusy) && wait < 20; wait++ {
+				time.Sleep(100 * time.Millisecond)
+				lock, err = store.TryLockExclusive(p.Name)
+			}
+			if errors.Is(err, profile.ErrProfileBusy) {
+				if !flags.Quiet {
+					fmt.Fprintf(a.Err, "skipped %s: in use\n", p.Name)
+				}
+				continue
+			}
+			if err != nil {
+				return a.fail(flags, err, exitFailure)
+			}
+			// Another command may have used the profile since the listing.
+			if current, err := store.Load(p.Name); err != nil || !store.IsExpired(current) {
+				_ = lock.Unlock()
+				continue
+			}
+			err = store.Remove(p.Name)
+			_ = lock.Unlock()
+			if err != nil {
+				return a.fail(flags, err, exitFailure)
 			}
 		}
 		removed = append(removed, p)
 	}
 	if flags.JSON {
-		b, _ := json.MarshalIndent(removed, "", "  ")
-		fmt.Fprintln(a.Out, string(b))
+		a.printJSON(flags, redactProfiles(removed))
 		return exitSuccess
 	}
 	for _, p := range removed {
@@ -284,32 +929,147 @@ func (a App) runPrune(store profile.Store, mgr daemon.Manager, flags GlobalFlags
 	return exitSuccess
 }
 
-func (a App) runTabNew(store profile.Store, mgr daemon.Manager, flags GlobalFlags, url string) int {
-	name := flags.Profile
-	if name == "" {
-		fmt.Fprintln(a.Err, "-p/--profile is required")
-		return exitUsage
-	}
-	_, _, err := store.Upsert(name, profile.Overrides{})
+func (a App) runStatus(store profile.Store, mgr daemon.Manager, flags GlobalFlags) int {
+	client, err := a.prepareClientNoTab(store, mgr, flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
 	}
-	if err := ensureRunning(mgr, name, flags.NoStart); err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+	defer client.Close()
+	status, err := client.Status()
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
 	}
-	client, err := daemon.NewClient(mgr.SocketPath(profile.SafeName(name)))
+	if flags.JSON {
+		a.printJSON(flags, status)
+		return exitSuccess
+	}
+	active := 0
+	for _, tab := range status.Tabs {
+		if tab.Active {
+			active = tab.ID
+		}
+	}
+	fmt.Fprintf(a.Out, "profile=%s\n", status.Profile)
+	fmt.Fprintf(a.Out, "active_tab=%d\n", active)
+	fmt.Fprintf(a.Out, "tabs=%d\n", len(status.Tabs))
+	for _, tab := range status.Tabs {
+		marker := ""
+		if tab.Active {
+			marker = "*"
+		}
+		if tab.Crashed {
+			marker += " [crashed]"
+		}
+		if tab.Unresponsive {
+			marker += " [unresponsive]"
+		}
+		fmt.Fprintf(a.Out, "%d%s %s\t%s\n", tab.ID, marker, tab.URL, tab.Title)
+	}
+	return exitSuccess
+}
+
+// healthTimeout bounds www health when --timeout is not set; a daemon that
+// needs longer than this to answer a ping is not usable for a batch anyway.
+const healthTimeout = 2 * time.Second
+
+type healthResult struct {
+	Profile string `json:"profile"`
+	daemon.PingResult
+}
+
+// runHealth pings a running daemon without starting one, so a stopped or
+// wedged profile fails instead of being brought up.
+func (a App) runHealth(mgr daemon.Manager, flags GlobalFlags) int {
+	name := flags.Profile
+	if name == "" {
+		return a.fail(flags, errors.New("-p/--profile is required"), exitUsage)
+	}
+	timeout := healthTimeout
+	if strings.TrimSpace(flags.Timeout) != "" {
+		d, err := parseDurationFlag(flags.Timeout)
+		if err != nil {
+			return a.fail(flags, err, exitUsage)
+		}
+		timeout = d
+	}
+	safe := profile.SafeName(name)
+	running, _, err := mgr.IsAlive(safe)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
+	}
+	if !running {
+		return a.fail(flags, fmt.Errorf("%s is %w", name, errNotRunning), exitFailure)
+	}
+	client, err := a.newClient(mgr, name, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
 	}
 	defer client.Close()
+	ping, err := client.Ping(timeout)
+	if err != nil {
+		return a.fail(flags, fmt.Errorf("%s is not responding: %w", name, err), exitFailure)
+	}
+	if flags.JSON {
+		a.printJSON(flags, healthResult{Profile: name, PingResult: ping})
+		return exitSuccess
+	}
+	if !flags.Quiet {
+		fmt.Fprintf(a.Out, "ok pid=%d uptime=%s tabs=%d\n", ping.PID, time.Duration(ping.Uptime)*time.Second, ping.TabCount)
+	}
+	return exitSuccess
+}
 
-	tab, err := client.TabNew(url)
+// runRecycle restarts the browser of a running daemon; like health it does
+// not start one, since a fresh daemon has nothing to recycle.
+func (a App) runRecycle(store profile.Store, mgr daemon.Manager, flags GlobalFlags) int {
+	name := flags.Profile
+	if name == "" {
+		return a.fail(flags, errors.New("-p/--profile is required"), exitUsage)
+	}
+	safe := profile.SafeName(name)
+	running, _, err := mgr.IsRunning(safe)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
+	}
+	if !running {
+		return a.fail(flags, fmt.Errorf("%s is %w", name, errNotRunning), exitFailure)
+	}
+	client, err := a.newClient(mgr, name, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	status, err := client.Recycle()
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(name)
+	if flags.JSON {
+		a.printJSON(flags, status)
+		return exitSuccess
+	}
+	if !flags.Quiet {
+		fmt.Fprintf(a.Out, "recycled %s\n", name)
+	}
+	return exitSuccess
+}
+
+func (a App) runTabNew(store profile.Store, mgr daemon.Manager, flags GlobalFlags, params daemon.TabNewParams) int {
+	if flags.Profile == "" {
+		return a.fail(flags, errors.New("-p/--profile is required"), exitUsage)
+	}
+	client, err := a.prepareClientNoTab(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	if params.TimeoutMs, err = actionTimeoutMs(flags); err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+
+	tab, err := client.TabNewWithOptions(params)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
 	}
 	fmt.Fprintf(a.Out, "%d\n", tab.ID)
 	return exitSuccess
@@ -318,18 +1078,15 @@ func (a App) runTabNew(store profile.Store, mgr daemon.Manager, flags GlobalFlag
 func (a App) runTabList(store profile.Store, mgr daemon.Manager, flags GlobalFlags) int {
 	client, err := a.prepareClientNoTab(store, mgr, flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
 	}
 	defer client.Close()
 	tabs, err := client.TabList()
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
 	}
 	if flags.JSON {
-		b, _ := json.MarshalIndent(tabs, "", "  ")
-		fmt.Fprintln(a.Out, string(b))
+		a.printJSON(flags, tabs)
 		return exitSuccess
 	}
 	for _, tab := range tabs {
@@ -337,6 +1094,12 @@ func (a App) runTabList(store profile.Store, mgr daemon.Manager, flags GlobalFla
 		if tab.Active {
 			marker = "*"
 		}
+		if tab.Crashed {
+			marker += " [crashed]"
+		}
+		if tab.Unresponsive {
+			marker += " [unresponsive]"
+		}
 		fmt.Fprintf(a.Out, "%d%s %s\n", tab.ID, marker, tab.URL)
 	}
 	return exitSuccess
@@ -345,312 +1108,2269 @@ func (a App) runTabList(store profile.Store, mgr daemon.Manager, flags GlobalFla
 func (a App) runTabClose(store profile.Store, mgr daemon.Manager, flags GlobalFlags, tab int) int {
 	client, err := a.prepareClientNoTab(store, mgr, flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
 	}
 	defer client.Close()
 	if err := client.TabClose(tab); err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
 	}
 	return exitSuccess
 }
 
-func (a App) runTabSwitch(store profile.Store, mgr daemon.Manager, flags GlobalFlags, tab int) int {
+func (a App) runTabActivate(store profile.Store, mgr daemon.Manager, flags GlobalFlags, params daemon.TabActivateParams) int {
 	client, err := a.prepareClientNoTab(store, mgr, flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
 	}
 	defer client.Close()
-	if err := client.TabSwitch(tab); err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+	if params.TimeoutMs, err = actionTimeoutMs(flags); err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	tab, err := client.TabActivate(params)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(flags.Profile)
+	if flags.JSON {
+		a.printJSON(flags, tab)
+		return exitSuccess
 	}
+	fmt.Fprintf(a.Out, "%d\n", tab.ID)
 	return exitSuccess
 }
 
-func (a App) runGoto(store profile.Store, mgr daemon.Manager, flags GlobalFlags, url string) int {
-	client, tabID, err := a.prepareClient(store, mgr, flags)
+func (a App) runTabSwitch(store profile.Store, mgr daemon.Manager, flags GlobalFlags, tab int) int {
+	client, err := a.prepareClientNoTab(store, mgr, flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
 	}
 	defer client.Close()
-	timeoutMs, err := actionTimeoutMs(flags)
-	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitUsage
-	}
-	if err := client.Goto(tabID, url, timeoutMs); err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+	if err := client.TabSwitch(tab); err != nil {
+		return a.fail(flags, err, exitFailure)
 	}
-	_, _ = store.Touch(flags.Profile)
 	return exitSuccess
 }
 
-func (a App) runClick(store profile.Store, mgr daemon.Manager, flags GlobalFlags, selector string) int {
+func (a App) runGoto(store profile.Store, mgr daemon.Manager, flags GlobalFlags, url string, waitUntil string) int {
+	switch waitUntil {
+	case "", "load", "domcontentloaded", "networkidle":
+	default:
+		return a.actionFailed(flags, fmt.Errorf("invalid wait state %q: expected load, domcontentloaded, or networkidle", waitUntil), exitUsage)
+	}
 	client, tabID, err := a.prepareClient(store, mgr, flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.actionFailed(flags, err, exitFailure)
 	}
 	defer client.Close()
+	a.logResolved(flags, tabID, "")
 	timeoutMs, err := actionTimeoutMs(flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitUsage
+		return a.actionFailed(flags, err, exitUsage)
 	}
-	if err := client.Click(tabID, normalizeSelector(selector), timeoutMs); err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+	params := daemon.GotoParams{Tab: tabID, URL: url, WaitUntil: waitUntil, TimeoutMs: timeoutMs}
+	if flags.Repeat > 1 {
+		code := a.runRepeat(flags, func() error { return client.GotoWithOptions(params) })
+		_, _ = store.Touch(flags.Profile)
+		return code
+	}
+	err = a.withRetry(flags, func() error {
+		return client.GotoWithOptions(params)
+	})
+	if err != nil {
+		return a.actionFailed(flags, err, exitFailure)
 	}
 	_, _ = store.Touch(flags.Profile)
+	a.actionSucceeded(flags, nil)
 	return exitSuccess
 }
 
-func (a App) runFill(store profile.Store, mgr daemon.Manager, flags GlobalFlags, selector string, value string) int {
+func (a App) runClick(store profile.Store, mgr daemon.Manager, flags GlobalFlags, params daemon.ClickParams) int {
 	client, tabID, err := a.prepareClient(store, mgr, flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.actionFailed(flags, err, exitFailure)
 	}
 	defer client.Close()
 	timeoutMs, err := actionTimeoutMs(flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitUsage
+		return a.actionFailed(flags, err, exitUsage)
+	}
+	params.Tab = tabID
+	params.Selector = selectorFor(flags, params.Selector)
+	a.logResolved(flags, tabID, params.Selector)
+	params.Raw = flags.RawSelector
+	params.TimeoutMs = timeoutMs
+	params.SelectorTimeoutMs, err = selectorTimeoutMs(flags)
+	if err != nil {
+		return a.actionFailed(flags, err, exitUsage)
 	}
-	if err := client.Fill(tabID, normalizeSelector(selector), value, timeoutMs); err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+	if flags.Repeat > 1 {
+		if params.ExpectPopup {
+			return a.actionFailed(flags, errors.New("--repeat cannot be combined with --expect-popup"), exitUsage)
+		}
+		code := a.runRepeat(flags, func() error { return client.ClickWithOptions(params) })
+		_, _ = store.Touch(flags.Profile)
+		return code
+	}
+	if params.ExpectPopup {
+		var tab daemon.TabInfo
+		err := a.withRetry(flags, func() error {
+			var err error
+			tab, err = client.ClickPopup(params)
+			return err
+		})
+		if err != nil {
+			return a.actionFailed(flags, err, exitFailure)
+		}
+		_, _ = store.Touch(flags.Profile)
+		if flags.JSON {
+			b, _ := json.Marshal(tab)
+			a.actionSucceeded(flags, b)
+			return exitSuccess
+		}
+		fmt.Fprintf(a.Out, "%d\n", tab.ID)
+		return exitSuccess
+	}
+	if err := a.withRetry(flags, func() error { return client.ClickWithOptions(params) }); err != nil {
+		return a.actionFailed(flags, err, exitFailure)
 	}
 	_, _ = store.Touch(flags.Profile)
+	a.actionSucceeded(flags, nil)
 	return exitSuccess
 }
 
-func (a App) runShot(store profile.Store, mgr daemon.Manager, flags GlobalFlags, path string, fullPage bool, selector string) int {
+func (a App) runFill(store profile.Store, mgr daemon.Manager, flags GlobalFlags, selector string, value string, nth *int) int {
 	client, tabID, err := a.prepareClient(store, mgr, flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.actionFailed(flags, err, exitFailure)
 	}
 	defer client.Close()
+	selector = selectorFor(flags, selector)
+	a.logResolved(flags, tabID, selector)
 	timeoutMs, err := actionTimeoutMs(flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitUsage
+		return a.actionFailed(flags, err, exitUsage)
 	}
-	if err := client.Shot(tabID, path, fullPage, selector, timeoutMs); err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+	selectorMs, err := selectorTimeoutMs(flags)
+	if err != nil {
+		return a.actionFailed(flags, err, exitUsage)
+	}
+	params := daemon.FillParams{Tab: tabID, Selector: selector, Value: value, Nth: nth, TimeoutMs: timeoutMs, SelectorTimeoutMs: selectorMs}
+	if err := a.withRetry(flags, func() error { return client.FillWithOptions(params) }); err != nil {
+		return a.actionFailed(flags, err, exitFailure)
 	}
 	_, _ = store.Touch(flags.Profile)
+	a.actionSucceeded(flags, nil)
 	return exitSuccess
 }
 
-func (a App) runExtract(store profile.Store, mgr daemon.Manager, flags GlobalFlags) int {
+func (a App) runDrag(store profile.Store, mgr daemon.Manager, flags GlobalFlags, from string, to string) int {
 	client, tabID, err := a.prepareClient(store, mgr, flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
 	}
 	defer client.Close()
+	from = selectorFor(flags, from)
+	to = selectorFor(flags, to)
+	a.logResolved(flags, tabID, from)
 	timeoutMs, err := actionTimeoutMs(flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitUsage
+		return a.fail(flags, err, exitUsage)
 	}
-	result, err := client.ExtractWithOptions(tabID, flags.Selector, flags.Main, timeoutMs)
+	selectorMs, err := selectorTimeoutMs(flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitUsage)
 	}
-	if flags.JSON {
-		fmt.Fprintln(a.Out, string(result))
-		return exitSuccess
+	if err := client.Drag(daemon.DragParams{Tab: tabID, From: from, To: to, TimeoutMs: timeoutMs, SelectorTimeoutMs: selectorMs}); err != nil {
+		return a.fail(flags, err, exitFailure)
 	}
-	fmt.Fprintln(a.Out, string(result))
 	_, _ = store.Touch(flags.Profile)
 	return exitSuccess
 }
 
-func (a App) runRead(store profile.Store, mgr daemon.Manager, flags GlobalFlags) int {
+// runFocus focuses the first match of selector, or blurs it when blur is set.
+func (a App) runFocus(store profile.Store, mgr daemon.Manager, flags GlobalFlags, selector string, blur bool) int {
 	client, tabID, err := a.prepareClient(store, mgr, flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
 	}
 	defer client.Close()
+	selector = selectorFor(flags, selector)
+	a.logResolved(flags, tabID, selector)
 	timeoutMs, err := actionTimeoutMs(flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitUsage
+		return a.fail(flags, err, exitUsage)
 	}
-	result, err := client.ExtractWithOptions(tabID, flags.Selector, flags.Main, timeoutMs)
+	selectorMs, err := selectorTimeoutMs(flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitUsage)
 	}
-	var parsed struct {
-		Text string `json:"text"`
+	params := daemon.FocusParams{Tab: tabID, Selector: selector, TimeoutMs: timeoutMs, SelectorTimeoutMs: selectorMs}
+	call := client.Focus
+	if blur {
+		call = client.Blur
 	}
-	if err := json.Unmarshal(result, &parsed); err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+	if err := call(params); err != nil {
+		return a.fail(flags, err, exitFailure)
 	}
-	fmt.Fprintln(a.Out, parsed.Text)
 	_, _ = store.Touch(flags.Profile)
 	return exitSuccess
 }
 
-func (a App) runURL(store profile.Store, mgr daemon.Manager, flags GlobalFlags) int {
+func (a App) runMouse(store profile.Store, mgr daemon.Manager, flags GlobalFlags, xText string, yText string) int {
+	x, errX := strconv.ParseFloat(xText, 64)
+	y, errY := strconv.ParseFloat(yText, 64)
+	if errX != nil || errY != nil {
+		return a.fail(flags, fmt.Errorf("invalid position %s %s: expected numbers in CSS pixels", xText, yText), exitUsage)
+	}
 	client, tabID, err := a.prepareClient(store, mgr, flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitFailure)
 	}
 	defer client.Close()
-	value, err := client.URL(tabID)
+	a.logResolved(flags, tabID, "")
+	timeoutMs, err := actionTimeoutMs(flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.fail(flags, err, exitUsage)
 	}
-	fmt.Fprintln(a.Out, value)
+	if err := client.MouseMove(daemon.MouseParams{Tab: tabID, X: x, Y: y, TimeoutMs: timeoutMs}); err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(flags.Profile)
 	return exitSuccess
 }
 
-func (a App) runLinks(store profile.Store, mgr daemon.Manager, flags GlobalFlags, filter string) int {
+func (a App) runSelect(store profile.Store, mgr daemon.Manager, flags GlobalFlags, selector string, values []string, add bool) int {
 	client, tabID, err := a.prepareClient(store, mgr, flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.actionFailed(flags, err, exitFailure)
 	}
 	defer client.Close()
-	links, err := client.Links(tabID, filter)
+	selector = selectorFor(flags, selector)
+	a.logResolved(flags, tabID, selector)
+	timeoutMs, err := actionTimeoutMs(flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.actionFailed(flags, err, exitUsage)
 	}
+	selectorMs, err := selectorTimeoutMs(flags)
+	if err != nil {
+		return a.actionFailed(flags, err, exitUsage)
+	}
+	params := daemon.SelectParams{Tab: tabID, Selector: selector, Values: values, Add: add, TimeoutMs: timeoutMs, SelectorTimeoutMs: selectorMs}
+	selected, err := client.Select(params)
+	if err != nil {
+		return a.actionFailed(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(flags.Profile)
 	if flags.JSON {
-		b, _ := json.MarshalIndent(links, "", "  ")
-		fmt.Fprintln(a.Out, string(b))
+		a.printJSON(flags, selected)
 		return exitSuccess
 	}
-	for _, link := range links {
-		fmt.Fprintf(a.Out, "%s\t%s\n", link.Text, link.Href)
+	for _, value := range selected {
+		fmt.Fprintln(a.Out, value)
 	}
 	return exitSuccess
 }
 
-func (a App) runEval(store profile.Store, mgr daemon.Manager, flags GlobalFlags, js string) int {
+func (a App) runUpload(store profile.Store, mgr daemon.Manager, flags GlobalFlags, selector string, paths []string, nth *int) int {
+	absPaths := make([]string, 0, len(paths))
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return a.actionFailed(flags, err, exitUsage)
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return a.actionFailed(flags, err, exitUsage)
+		}
+		if info.IsDir() {
+			return a.actionFailed(flags, fmt.Errorf("%s is a directory", path), exitUsage)
+		}
+		absPaths = append(absPaths, abs)
+	}
 	client, tabID, err := a.prepareClient(store, mgr, flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.actionFailed(flags, err, exitFailure)
 	}
 	defer client.Close()
+	selector = selectorFor(flags, selector)
+	a.logResolved(flags, tabID, selector)
 	timeoutMs, err := actionTimeoutMs(flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitUsage
+		return a.actionFailed(flags, err, exitUsage)
 	}
-	result, err := client.Eval(tabID, js, timeoutMs)
+	selectorMs, err := selectorTimeoutMs(flags)
 	if err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.actionFailed(flags, err, exitUsage)
+	}
+	params := daemon.UploadParams{Tab: tabID, Selector: selector, Paths: absPaths, Nth: nth, TimeoutMs: timeoutMs, SelectorTimeoutMs: selectorMs}
+	if err := client.Upload(params); err != nil {
+		return a.actionFailed(flags, err, exitFailure)
 	}
-	fmt.Fprintln(a.Out, string(result))
 	_, _ = store.Touch(flags.Profile)
+	a.actionSucceeded(flags, nil)
 	return exitSuccess
 }
 
-func (a App) prepareClient(store profile.Store, mgr daemon.Manager, flags GlobalFlags) (*daemon.Client, int, error) {
-	name := flags.Profile
-	if name == "" {
-		return nil, 0, errors.New("-p/--profile is required")
-	}
-	if _, _, err := store.Upsert(name, profile.Overrides{}); err != nil {
-		return nil, 0, err
-	}
-	if err := ensureRunning(mgr, name, flags.NoStart); err != nil {
-		return nil, 0, err
+func (a App) runFillLabel(store profile.Store, mgr daemon.Manager, flags GlobalFlags, label string, value string) int {
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
 	}
-	client, err := daemon.NewClient(mgr.SocketPath(profile.SafeName(name)))
+	defer client.Close()
+	a.logResolved(flags, tabID, "")
+	timeoutMs, err := actionTimeoutMs(flags)
 	if err != nil {
-		return nil, 0, err
+		return a.fail(flags, err, exitUsage)
 	}
-	tabID, err := resolveTabID(client, flags.Tab)
+	selectorMs, err := selectorTimeoutMs(flags)
 	if err != nil {
-		_ = client.Close()
-		return nil, 0, err
+		return a.fail(flags, err, exitUsage)
 	}
-	return client, tabID, nil
+	params := daemon.FillLabelParams{Tab: tabID, Label: label, Value: value, TimeoutMs: timeoutMs, SelectorTimeoutMs: selectorMs}
+	if err := a.withRetry(flags, func() error { return client.FillLabelWithOptions(params) }); err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(flags.Profile)
+	return exitSuccess
 }
 
-func (a App) prepareClientNoTab(store profile.Store, mgr daemon.Manager, flags GlobalFlags) (*daemon.Client, error) {
-	name := flags.Profile
-	if name == "" {
-		return nil, errors.New("-p/--profile is required")
+func (a App) runShot(store profile.Store, mgr daemon.Manager, flags GlobalFlags, params daemon.ShotParams, clip string, burst int, interval string) int {
+	shotType, err := screenshotType(params.Path, params.Type)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
 	}
-	if _, _, err := store.Upsert(name, profile.Overrides{}); err != nil {
-		return nil, err
+	params.Type = shotType
+	if params.Quality != nil {
+		if shotType != "jpeg" {
+			return a.fail(flags, errors.New("--quality only applies to jpeg screenshots"), exitUsage)
+		}
+		if *params.Quality < 0 || *params.Quality > 100 {
+			return a.fail(flags, errors.New("--quality must be between 0 and 100"), exitUsage)
+		}
 	}
-	if err := ensureRunning(mgr, name, flags.NoStart); err != nil {
-		return nil, err
+	if strings.TrimSpace(clip) != "" {
+		if params.Selector != "" {
+			return a.fail(flags, errors.New("--clip and --selector cannot be combined"), exitUsage)
+		}
+		rect, err := parseClip(clip)
+		if err != nil {
+			return a.fail(flags, err, exitUsage)
+		}
+		params.Clip = rect
 	}
-	client, err := daemon.NewClient(mgr.SocketPath(profile.SafeName(name)))
-	if err != nil {
-		return nil, err
+	if burst < 0 {
+		return a.fail(flags, errors.New("--burst must be positive"), exitUsage)
 	}
-	return client, nil
-}
-
-func actionTimeoutMs(flags GlobalFlags) (int, error) {
-	if strings.TrimSpace(flags.Timeout) == "" {
-		return int((20 * time.Second).Milliseconds()), nil
+	every := 500 * time.Millisecond
+	if strings.TrimSpace(interval) != "" {
+		d, err := parseDurationFlag(interval)
+		if err != nil {
+			return a.fail(flags, fmt.Errorf("invalid interval: %w", err), exitUsage)
+		}
+		every = d
 	}
-	d, err := time.ParseDuration(flags.Timeout)
+	params.Path, err = a.artifactPath(store, flags, params.Path)
 	if err != nil {
-		return 0, fmt.Errorf("invalid timeout: %w", err)
+		return a.fail(flags, err, exitUsage)
 	}
-	if d <= 0 {
-		return 0, nil
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
 	}
-	return int(d.Milliseconds()), nil
-}
-
-func (a App) runServe(store profile.Store, flags GlobalFlags) int {
-	name := flags.Profile
-	if name == "" {
-		fmt.Fprintln(a.Err, "-p/--profile is required")
-		return exitUsage
+	defer client.Close()
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
 	}
-	p, err := store.Load(name)
+	params.Tab = tabID
+	a.logResolved(flags, tabID, flagSelectorFor(params.Selector))
+	params.TimeoutMs = timeoutMs
+	params.SelectorTimeoutMs, err = selectorTimeoutMs(flags)
 	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	if burst == 0 {
+		if err := client.ShotWithOptions(params); err != nil {
+			return a.fail(flags, err, exitFailure)
+		}
+		_, _ = store.Touch(flags.Profile)
+		return exitSuccess
+	}
+	path := params.Path
+	started := time.Now()
+	for i := 1; i <= burst; i++ {
+		if wait := time.Until(started.Add(time.Duration(i-1) * every)); wait > 0 {
+			time.Sleep(wait)
+		}
+		params.Path = burstPath(path, i)
+		if err := client.ShotWithOptions(params); err != nil {
+			return a.fail(flags, err, exitFailure)
+		}
+		if !flags.Quiet {
+			fmt.Fprintln(a.Out, params.Path)
+		}
+	}
+	_, _ = store.Touch(flags.Profile)
+	return exitSuccess
+}
+
+func screenshotType(path string, value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "png":
+		return "png", nil
+	case "jpeg", "jpg":
+		return "jpeg", nil
+	case "":
+	default:
+		return "", fmt.Errorf("invalid type %q: expected png or jpeg", value)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg":
+		return "jpeg", nil
+	}
+	return "png", nil
+}
+
+func parseClip(value string) (*browser.Rect, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid clip %q: expected X,Y,W,H", value)
+	}
+	nums := make([]int, 4)
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid clip %q: expected four non-negative integers X,Y,W,H", value)
+		}
+		nums[i] = n
+	}
+	return &browser.Rect{X: nums[0], Y: nums[1], Width: nums[2], Height: nums[3]}, nil
+}
+
+func burstPath(path string, n int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%03d%s", strings.TrimSuffix(path, ext), n, ext)
+}
+
+func (a App) runPDF(store profile.Store, mgr daemon.Manager, flags GlobalFlags, path string, opts browser.PDFOptions) int {
+	switch strings.ToLower(opts.Format) {
+	case "":
+	case "a4":
+		opts.Format = "A4"
+	case "letter":
+		opts.Format = "Letter"
+	default:
+		return a.fail(flags, fmt.Errorf("invalid format %q: expected A4 or Letter", opts.Format), exitUsage)
+	}
+	absPath, err := a.artifactPath(store, flags, path)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	a.logResolved(flags, tabID, "")
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	if err := client.PDF(tabID, absPath, opts, timeoutMs); err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(flags.Profile)
+	return exitSuccess
+}
+
+// artifactPath makes an output path absolute so the daemon writes it where
+// the caller expects. With --artifacts, relative paths land in the profile's
+// artifacts directory instead of the working directory.
+func (a App) artifactPath(store profile.Store, flags GlobalFlags, path string) (string, error) {
+	if !flags.Artifacts || filepath.IsAbs(path) {
+		return filepath.Abs(path)
+	}
+	if flags.Profile == "" {
+		return "", errors.New("-p/--profile is required")
+	}
+	full := filepath.Join(store.ArtifactsDir(flags.Profile), path)
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		return "", err
+	}
+	if flags.Verbose && !flags.Quiet {
+		fmt.Fprintf(a.Err, "writing %s\n", full)
+	}
+	return full, nil
+}
+
+func (a App) runExtract(store profile.Store, mgr daemon.Manager, flags GlobalFlags, format string) int {
+	if err := validateExtractFormat(format); err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	a.logResolved(flags, tabID, flagSelectorFor(flags.Selector))
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	params := extractParams(tabID, flags, format, timeoutMs)
+	if flags.Repeat > 1 {
+		code := a.runRepeat(flags, func() error {
+			_, err := client.ExtractWithParams(params)
+			return err
+		})
+		_, _ = store.Touch(flags.Profile)
+		return code
+	}
+	result, err := client.ExtractWithParams(params)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(flags.Profile)
+	if format == "text" && !flags.JSON {
+		var parsed browser.ExtractResult
+		if err := json.Unmarshal(result, &parsed); err != nil {
+			return a.fail(flags, err, exitFailure)
+		}
+		return a.writeOutput(flags, func(a App) {
+			fmt.Fprintln(a.Out, parsed.Text)
+		})
+	}
+	return a.writeOutput(flags, func(a App) {
+		if flags.JSON {
+			a.printJSON(flags, result)
+			return
+		}
+		fmt.Fprintln(a.Out, string(result))
+	})
+}
+
+// runRead prints the main content of the current page. With pages > 1 it
+// then follows the page's "next" link and appends each following page's
+// content, up to pages in total, stopping early when there is no next link
+// or it leads back to a page already read.
+func (a App) runRead(store profile.Store, mgr daemon.Manager, flags GlobalFlags, format string, pages int) int {
+	if err := validateExtractFormat(format); err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	if pages < 1 {
+		return a.fail(flags, fmt.Errorf("invalid --max %d: expected at least 1", pages), exitUsage)
+	}
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	a.logResolved(flags, tabID, "")
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	var results []json.RawMessage
+	var texts []string
+	visited := map[string]bool{}
+	for {
+		result, err := client.ExtractWithParams(extractParams(tabID, flags, format, timeoutMs))
+		if err != nil {
+			return a.fail(flags, err, exitFailure)
+		}
+		var parsed browser.ExtractResult
+		if err := json.Unmarshal(result, &parsed); err != nil {
+			return a.fail(flags, err, exitFailure)
+		}
+		results = append(results, result)
+		texts = append(texts, parsed.Text)
+		visited[pageKey(parsed.URL)] = true
+		if len(results) >= pages {
+			break
+		}
+		next := nextPageURL(parsed, visited)
+		if next == "" {
+			break
+		}
+		visited[pageKey(next)] = true
+		if err := client.Goto(tabID, next, timeoutMs); err != nil {
+			return a.fail(flags, err, exitFailure)
+		}
+	}
+	_, _ = store.Touch(flags.Profile)
+	if format == "json" {
+		return a.writeOutput(flags, func(a App) {
+			if pages > 1 {
+				a.printJSON(flags, results)
+				return
+			}
+			a.printJSON(flags, results[0])
+		})
+	}
+	return a.writeOutput(flags, func(a App) {
+		fmt.Fprintln(a.Out, strings.Join(texts, "\n\n"))
+	})
+}
+
+var nextLinkText = regexp.MustCompile(`(?i)^next\b|→`)
+
+// nextPageURL picks the link to the following page: a link with rel=next,
+// otherwise one whose text starts with "Next" or contains an arrow. Links
+// back to visited pages and non-http links are ignored.
+func nextPageURL(result browser.ExtractResult, visited map[string]bool) string {
+	usable := func(href string) bool {
+		return (strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://")) && !visited[pageKey(href)]
+	}
+	for _, link := range result.Links {
+		if slices.Contains(strings.Fields(strings.ToLower(link.Rel)), "next") && usable(link.Href) {
+			return link.Href
+		}
+	}
+	for _, link := range result.Links {
+		if nextLinkText.MatchString(strings.TrimSpace(link.Text)) && usable(link.Href) {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+// pageKey identifies a page by its URL without the fragment.
+func pageKey(url string) string {
+	if i := strings.Index(url, "#"); i >= 0 {
+		return url[:i]
+	}
+	return url
+}
+
+type readURLResult struct {
+	URL    string          `json:"url"`
+	Title  string          `json:"title,omitempty"`
+	Text   string          `json:"text,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+func readURLList(path string, args []string) ([]string, error) {
+	urls := append([]string{}, args...)
+	if path == "" {
+		return urls, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	return urls, nil
+}
+
+func (a App) runReadURLs(store profile.Store, mgr daemon.Manager, flags GlobalFlags, format string, urls []string, concurrency int) int {
+	if err := validateExtractFormat(format); err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(urls) {
+		concurrency = len(urls)
+	}
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	a.logResolved(flags, tabID, "")
+	out, closeOut, err := a.outputWriter(flags.Output)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer closeOut()
+	a.Out = out
+
+	jobs := make(chan string)
+	var mu sync.Mutex
+	failed := 0
+	emit := func(res readURLResult) {
+		b, _ := json.Marshal(res)
+		mu.Lock()
+		defer mu.Unlock()
+		if res.Error != "" {
+			failed++
+		}
+		fmt.Fprintln(a.Out, string(b))
+	}
+	work := func(c *daemon.Client, tab int) {
+		for url := range jobs {
+			emit(readURL(c, tab, url, flags, format, timeoutMs))
+		}
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		work(client, tabID)
+	}()
+	for i := 1; i < concurrency; i++ {
+		c, err := a.newClient(mgr, flags.Profile, flags)
+		if err != nil {
+			fmt.Fprintln(a.Err, err)
+			break
+		}
+		tab, err := c.TabNew("")
+		if err != nil {
+			fmt.Fprintln(a.Err, err)
+			_ = c.Close()
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer c.Close()
+			defer func() { _ = c.TabClose(tab.ID) }()
+			work(c, tab.ID)
+		}()
+	}
+	for _, url := range urls {
+		jobs <- url
+	}
+	close(jobs)
+	wg.Wait()
+	_, _ = store.Touch(flags.Profile)
+	if failed > 0 {
+		return exitFailure
+	}
+	return exitSuccess
+}
+
+func readURL(client *daemon.Client, tab int, url string, flags GlobalFlags, format string, timeoutMs int) readURLResult {
+	res := readURLResult{URL: url}
+	if err := client.GotoWithOptions(daemon.GotoParams{Tab: tab, URL: url, TimeoutMs: timeoutMs}); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	raw, err := client.ExtractWithParams(extractParams(tab, flags, format, timeoutMs))
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if format == "json" {
+		res.Result = raw
+		return res
+	}
+	var parsed struct {
+		Title string `json:"title"`
+		Text  string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Title = parsed.Title
+	res.Text = parsed.Text
+	return res
+}
+
+// scriptLine is one command from a www run script.
+type scriptLine struct {
+	N    int
+	Args []string
+}
+
+// readScript parses newline-delimited commands from path, or from stdin when
+// path is "-". Blank lines and lines starting with # are skipped.
+func readScript(path string, stdin io.Reader) ([]scriptLine, error) {
+	var b []byte
+	var err error
+	if path == "-" {
+		b, err = io.ReadAll(stdin)
+	} else {
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lines []scriptLine
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		args, err := splitScriptLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		lines = append(lines, scriptLine{N: i + 1, Args: args})
+	}
+	return lines, nil
+}
+
+// splitScriptLine splits on whitespace, keeping single- or double-quoted
+// text together.
+func splitScriptLine(line string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			cur.WriteRune(r)
+		case r == '"' || r == '\'':
+			inArg = true
+			quote = r
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			inArg = true
+			cur.WriteRune(r)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+type scriptResult struct {
+	Line    int             `json:"line"`
+	Command string          `json:"command"`
+	OK      bool            `json:"ok"`
+	Error   string          `json:"error,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+}
+
+// runScript executes script lines in order over one daemon connection. Each
+// line is run as the www command of the same name, with the run's global
+// flags in base, so it takes the same arguments and flags; sleep DURATION is
+// also available. Timeouts come from the run's own flags. A nonzero deadline
+// bounds the whole script: each line's timeout is capped to the time left,
+// and the run stops with exitTimeout once it is used up.
+func (a App) runScript(store profile.Store, mgr daemon.Manager, flags GlobalFlags, base []string, lines []scriptLine, continueOnError bool, deadlineFlag string) int {
+	var deadline time.Duration
+	if deadlineFlag != "" {
+		d, err := parseDurationFlag(deadlineFlag)
+		if err != nil || d <= 0 {
+			return a.fail(flags, fmt.Errorf("invalid --deadline %q: expected a positive duration such as 2m", deadlineFlag), exitUsage)
+		}
+		deadline = d
+	}
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	selectorMs, err := selectorTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	a.logResolved(flags, tabID, "")
+	conn := &scriptConn{client: client, tabID: tabID}
+	var until time.Time
+	if deadline > 0 {
+		until = time.Now().Add(deadline)
+	}
+	code := exitSuccess
+	results := make([]scriptResult, 0, len(lines))
+	for _, line := range lines {
+		result := scriptResult{Line: line.N, Command: line.Args[0]}
+		lineTimeoutMs, lineSelectorMs := timeoutMs, selectorMs
+		var raw json.RawMessage
+		var err error
+		if !until.IsZero() {
+			left := time.Until(until)
+			lineTimeoutMs = min(lineTimeoutMs, max(int(left.Milliseconds()), 1))
+			if lineSelectorMs > lineTimeoutMs {
+				lineSelectorMs = lineTimeoutMs
+			}
+			if left <= 0 {
+				err = fmt.Errorf("deadline of %s reached before line %d: %w", deadline, line.N, os.ErrDeadlineExceeded)
+			}
+		}
+		if err == nil {
+			raw, err = a.runScriptLine(conn, flags, base, line, lineTimeoutMs, lineSelectorMs, until)
+			if err != nil && !until.IsZero() && !time.Now().Before(until) {
+				err = fmt.Errorf("deadline of %s reached during line %d: %w", deadline, line.N, errors.Join(os.ErrDeadlineExceeded, err))
+			}
+		}
+		if err != nil {
+			result.Error = err.Error()
+			code = exitFailure
+			if errors.Is(err, os.ErrDeadlineExceeded) && !until.IsZero() {
+				code = exitTimeout
+			}
+			if !flags.JSON {
+				fmt.Fprintf(a.Err, "line %d: %s: %v\n", line.N, line.Args[0], err)
+			}
+		} else {
+			result.OK = true
+			result.Result = raw
+		}
+		results = append(results, result)
+		if err != nil && (!continueOnError || code == exitTimeout) {
+			break
+		}
+	}
+	_, _ = store.Touch(flags.Profile)
+	if flags.JSON {
+		a.printJSON(flags, results)
+	}
+	return code
+}
+
+// scriptExcluded lists commands a script cannot run.
+var scriptExcluded = map[string]bool{"run": true, "serve": true}
+
+// runScriptLine runs one script line through the www command tree on the
+// script's connection. Without --json the command prints as usual; with it,
+// what the command printed becomes the line's result. until, when set, is the
+// script's deadline; it only matters for sleep, which has no timeout of its
+// own.
+func (a App) runScriptLine(conn *scriptConn, flags GlobalFlags, base []string, line scriptLine, timeoutMs int, selectorMs int, until time.Time) (json.RawMessage, error) {
+	verb := line.Args[0]
+	if verb == "sleep" {
+		return nil, scriptSleep(line.Args[1:], until)
+	}
+	if scriptExcluded[verb] {
+		return nil, fmt.Errorf("%s cannot be used in a script", verb)
+	}
+	args := append(slices.Clone(base), "--profile="+flags.Profile, fmt.Sprintf("--timeout=%dms", timeoutMs))
+	if flags.ProfileDir != "" {
+		args = append(args, "--profile-dir="+flags.ProfileDir)
+	}
+	if selectorMs > 0 {
+		args = append(args, fmt.Sprintf("--selector-timeout=%dms", selectorMs))
+	}
+	if flags.JSON {
+		args = append(args, "--json")
+	}
+	args = append(args, line.Args...)
+
+	var out, errOut bytes.Buffer
+	lineApp := App{Out: a.Out, Err: &errOut, script: conn}
+	if flags.JSON {
+		lineApp.Out = &out
+	}
+	var lineFlags GlobalFlags
+	root := newRootCmd(lineApp, &lineFlags)
+	root.SetArgs(args)
+	if err := root.Execute(); err != nil {
+		var exit exitError
+		if !errors.As(err, &exit) {
+			return nil, err
+		}
+		return nil, scriptLineError(errOut.String(), exit.code)
+	}
+	if !flags.JSON {
+		_, _ = a.Err.Write(errOut.Bytes())
+		return nil, nil
+	}
+	printed := bytes.TrimSpace(out.Bytes())
+	switch {
+	case len(printed) == 0:
+		return nil, nil
+	case json.Valid(printed):
+		return printed, nil
+	}
+	return json.Marshal(string(printed))
+}
+
+// scriptLineError turns what a failed command printed to stderr, plain or as
+// a --json error, back into an error.
+func scriptLineError(printed string, code int) error {
+	printed = strings.TrimSpace(printed)
+	var parsed jsonError
+	if json.Unmarshal([]byte(printed), &parsed) == nil && parsed.Error.Message != "" {
+		printed = parsed.Error.Message
+	}
+	if printed == "" {
+		return fmt.Errorf("exit %d", code)
+	}
+	return errors.New(printed)
+}
+
+// scriptSleep pauses a script for DURATION, cut short by the deadline.
+func scriptSleep(args []string, until time.Time) error {
+	if len(args) != 1 {
+		return errors.New("usage: sleep DURATION")
+	}
+	d, err := parseDurationFlag(args[0])
+	if err != nil {
+		return err
+	}
+	if !until.IsZero() && time.Until(until) < d {
+		time.Sleep(time.Until(until))
+		return os.ErrDeadlineExceeded
+	}
+	time.Sleep(d)
+	return nil
+}
+
+func validateExtractFormat(format string) error {
+	switch format {
+	case "", "text", "json", "markdown":
+		return nil
+	default:
+		return fmt.Errorf("invalid format %q: expected text, json, or markdown", format)
+	}
+}
+
+func extractParams(tabID int, flags GlobalFlags, format string, timeoutMs int) daemon.ExtractParams {
+	params := daemon.ExtractParams{Tab: tabID, Selector: flags.Selector, Main: flags.Main, Shadow: flags.Shadow, OnlyVisible: flags.OnlyVisible, Aria: flags.Aria, TimeoutMs: timeoutMs}
+	if format == "markdown" {
+		params.Format = format
+	}
+	return params
+}
+
+type articleResult struct {
+	URL         string `json:"url"`
+	Canonical   string `json:"canonical,omitempty"`
+	Title       string `json:"title"`
+	Byline      string `json:"byline,omitempty"`
+	Lang        string `json:"lang,omitempty"`
+	Description string `json:"description,omitempty"`
+	Text        string `json:"text"`
+}
+
+func articleFromExtract(result browser.ExtractResult) articleResult {
+	return articleResult{
+		URL:         result.URL,
+		Canonical:   result.Canonical,
+		Title:       strings.TrimSpace(result.Title),
+		Byline:      result.Byline,
+		Lang:        result.Lang,
+		Description: result.Meta["description"],
+		Text:        strings.TrimSpace(result.Text),
+	}
+}
+
+func (a App) runArticle(store profile.Store, mgr daemon.Manager, flags GlobalFlags, format string) int {
+	switch format {
+	case "", "text", "markdown":
+	default:
+		return a.fail(flags, fmt.Errorf("invalid format %q: expected text or markdown", format), exitUsage)
+	}
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	a.logResolved(flags, tabID, flagSelectorFor(flags.Selector))
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	flags.Main = true
+	raw, err := client.ExtractWithParams(extractParams(tabID, flags, format, timeoutMs))
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	var result browser.ExtractResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(flags.Profile)
+	a.printJSON(flags, articleFromExtract(result))
+	return exitSuccess
+}
+
+func (a App) runURL(store profile.Store, mgr daemon.Manager, flags GlobalFlags) int {
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	a.logResolved(flags, tabID, "")
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	value, err := client.URL(tabID, timeoutMs)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	fmt.Fprintln(a.Out, value)
+	return exitSuccess
+}
+
+func (a App) runLinks(store profile.Store, mgr daemon.Manager, flags GlobalFlags, filter string, format string) int {
+	if err := validateRowFormat(format, flags); err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	a.logResolved(flags, tabID, "")
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	links, err := client.LinksWithOptions(daemon.LinksParams{Tab: tabID, Filter: filter, Shadow: flags.Shadow, OnlyVisible: flags.OnlyVisible, TimeoutMs: timeoutMs})
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	return a.writeOutput(flags, func(a App) {
+		if flags.JSON {
+			a.printJSON(flags, links)
+			return
+		}
+		if format == "csv" {
+			rows := make([][]string, 0, len(links))
+			for _, link := range links {
+				rows = append(rows, []string{link.Text, link.Href})
+			}
+			if err := writeCSV(a.Out, []string{"text", "href"}, rows); err != nil {
+				fmt.Fprintln(a.Err, err)
+			}
+			return
+		}
+		for _, link := range links {
+			fmt.Fprintf(a.Out, "%s\t%s\n", link.Text, link.Href)
+		}
+	})
+}
+
+func (a App) runTables(store profile.Store, mgr daemon.Manager, flags GlobalFlags, numberFields []string, format string) int {
+	if err := validateRowFormat(format, flags); err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	a.logResolved(flags, tabID, flagSelectorFor(flags.Selector))
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	tables, err := client.Tables(tabID, flags.Selector, timeoutMs)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	if flags.JSON {
+		if len(numberFields) > 0 {
+			a.printJSON(flags, parseTableNumbers(tables, numberFields))
+			return exitSuccess
+		}
+		a.printJSON(flags, tables)
+		return exitSuccess
+	}
+	for i, table := range parseTableNumbers(tables, numberFields) {
+		if i > 0 {
+			fmt.Fprintln(a.Out)
+		}
+		if format == "csv" {
+			if err := writeCSV(a.Out, table.Headers, table.values()); err != nil {
+				return a.fail(flags, err, exitFailure)
+			}
+			continue
+		}
+		if table.Caption != "" {
+			fmt.Fprintf(a.Out, "# %s\n", table.Caption)
+		}
+		fmt.Fprintln(a.Out, strings.Join(table.Headers, "\t"))
+		for _, values := range table.values() {
+			fmt.Fprintln(a.Out, strings.Join(values, "\t"))
+		}
+	}
+	return exitSuccess
+}
+
+// validateRowFormat checks --format for commands that print rows; csv is a
+// separate encoding from --json, so asking for both is a usage error.
+func validateRowFormat(format string, flags GlobalFlags) error {
+	switch format {
+	case "text":
+		return nil
+	case "csv":
+		if flags.JSON {
+			return errors.New("--format csv cannot be combined with --json")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid format %q: expected text or csv", format)
+	}
+}
+
+// writeCSV writes a header row followed by rows, quoting fields as needed.
+func writeCSV(w io.Writer, header []string, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+type numberTable struct {
+	Caption string           `json:"caption,omitempty"`
+	Headers []string         `json:"headers"`
+	Rows    []map[string]any `json:"rows"`
+}
+
+// values returns each row as strings in header order.
+func (t numberTable) values() [][]string {
+	result := make([][]string, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		values := make([]string, len(t.Headers))
+		for j, header := range t.Headers {
+			switch v := row[header].(type) {
+			case string:
+				values[j] = v
+			case float64:
+				values[j] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+		result = append(result, values)
+	}
+	return result
+}
+
+func parseTableNumbers(tables []browser.ExtractTable, fields []string) []numberTable {
+	result := make([]numberTable, 0, len(tables))
+	for _, table := range tables {
+		parsed := map[string]bool{}
+		headers := make([]string, 0, len(table.Headers))
+		for _, header := range table.Headers {
+			headers = append(headers, header)
+			for _, field := range fields {
+				if strings.EqualFold(strings.TrimSpace(field), header) {
+					parsed[header] = true
+					headers = append(headers, header+"_number")
+					break
+				}
+			}
+		}
+		rows := make([]map[string]any, 0, len(table.Rows))
+		for _, row := range table.Rows {
+			out := make(map[string]any, len(row)+len(parsed))
+			for key, value := range row {
+				out[key] = value
+				if !parsed[key] {
+					continue
+				}
+				if n, ok := parseLocaleNumber(value); ok {
+					out[key+"_number"] = n
+				} else {
+					out[key+"_number"] = nil
+				}
+			}
+			rows = append(rows, out)
+		}
+		result = append(result, numberTable{Caption: table.Caption, Headers: headers, Rows: rows})
+	}
+	return result
+}
+
+// parseLocaleNumber reads the first number in value, allowing currency or
+// unit text around it and either "." or "," as the decimal point. A lone
+// separator followed by exactly three digits is a thousands separator unless
+// the integer part is empty or 0. Text with a second number after the first,
+// such as a range or a date, is rejected.
+func parseLocaleNumber(value string) (float64, bool) {
+	runes := []rune(value)
+	start := slices.IndexFunc(runes, isASCIIDigit)
+	if start < 0 {
+		return 0, false
+	}
+	if start > 0 && (runes[start-1] == '.' || runes[start-1] == ',') {
+		start--
+	}
+	negative := strings.ContainsAny(string(runes[:start]), "-\u2212(")
+	end := start
+	for end < len(runes) {
+		r := runes[end]
+		if isASCIIDigit(r) || strings.ContainsRune(".,'\u00a0\u202f", r) || (r == ' ' && end+1 < len(runes) && isASCIIDigit(runes[end+1])) {
+			end++
+			continue
+		}
+		break
+	}
+	if slices.ContainsFunc(runes[end:], isASCIIDigit) {
+		return 0, false
+	}
+	var b strings.Builder
+	for _, r := range strings.TrimRight(string(runes[start:end]), ".,'\u00a0\u202f ") {
+		if isASCIIDigit(r) || r == '.' || r == ',' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	lastDot := strings.LastIndex(digits, ".")
+	lastComma := strings.LastIndex(digits, ",")
+	decimalIdx := -1
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		decimalIdx = max(lastDot, lastComma)
+	case lastDot >= 0 || lastComma >= 0:
+		idx := max(lastDot, lastComma)
+		whole := digits[:idx]
+		if strings.Count(digits, digits[idx:idx+1]) == 1 && (len(digits)-idx-1 != 3 || whole == "" || whole == "0") {
+			decimalIdx = idx
+		}
+	}
+	var normalized strings.Builder
+	for i, r := range digits {
+		switch {
+		case isASCIIDigit(r):
+			normalized.WriteRune(r)
+		case i == decimalIdx:
+			normalized.WriteByte('.')
+		}
+	}
+	n, err := strconv.ParseFloat(normalized.String(), 64)
+	if err != nil {
+		return 0, false
+	}
+	if negative {
+		n = -n
+	}
+	return n, true
+}
+
+func isASCIIDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func (a App) runForms(store profile.Store, mgr daemon.Manager, flags GlobalFlags) int {
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	a.logResolved(flags, tabID, "")
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	forms, err := client.Forms(tabID, timeoutMs)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	if flags.JSON {
+		a.printJSON(flags, forms)
+		return exitSuccess
+	}
+	for i, form := range forms {
+		if i > 0 {
+			fmt.Fprintln(a.Out)
+		}
+		fmt.Fprintf(a.Out, "form %d method=%s action=%s\n", i+1, strings.ToUpper(form.Method), form.Action)
+		for _, input := range form.Inputs {
+			fmt.Fprintf(a.Out, "  %s\t%s\t%s\n", input.Type, input.Name, input.Label)
+		}
+		for _, submit := range form.Submits {
+			fmt.Fprintf(a.Out, "  submit\t%s\n", submit)
+		}
+	}
+	return exitSuccess
+}
+
+func (a App) runOutline(store profile.Store, mgr daemon.Manager, flags GlobalFlags) int {
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	a.logResolved(flags, tabID, "")
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	headings, err := client.Outline(tabID, timeoutMs)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(flags.Profile)
+	if flags.JSON {
+		a.printJSON(flags, headings)
+		return exitSuccess
+	}
+	fmt.Fprint(a.Out, renderOutline(headings))
+	return exitSuccess
+}
+
+func (a App) runContent(store profile.Store, mgr daemon.Manager, flags GlobalFlags) int {
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	a.logResolved(flags, tabID, flagSelectorFor(flags.Selector))
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	html, err := client.Content(daemon.ContentParams{Tab: tabID, Selector: flags.Selector, TimeoutMs: timeoutMs})
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(flags.Profile)
+	return a.writeOutput(flags, func(a App) {
+		fmt.Fprint(a.Out, html)
+	})
+}
+
+func renderOutline(headings []browser.Heading) string {
+	base := 6
+	for _, h := range headings {
+		base = min(base, h.Level)
+	}
+	var b strings.Builder
+	for _, h := range headings {
+		b.WriteString(strings.Repeat("  ", h.Level-base))
+		b.WriteString(h.Text)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func (a App) runExists(store profile.Store, mgr daemon.Manager, flags GlobalFlags, selector string) int {
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	selector = selectorFor(flags, selector)
+	a.logResolved(flags, tabID, selector)
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	count, err := client.Count(tabID, selector, timeoutMs)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(flags.Profile)
+	if !flags.Quiet {
+		fmt.Fprintln(a.Out, count)
+	}
+	if count == 0 {
+		return exitNotFound
+	}
+	return exitSuccess
+}
+
+func (a App) runText(store profile.Store, mgr daemon.Manager, flags GlobalFlags, selector string) int {
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	selector = selectorFor(flags, selector)
+	a.logResolved(flags, tabID, selector)
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	text, err := client.Text(tabID, selector, timeoutMs)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(flags.Profile)
+	if text.Count == 0 {
+		if !flags.Quiet {
+			return a.fail(flags, fmt.Errorf("%w for %s", browser.ErrNoMatch, selector), exitNotFound)
+		}
+		return exitNotFound
+	}
+	if text.Count > 1 && !flags.Quiet {
+		fmt.Fprintf(a.Err, "%d elements match; using the first\n", text.Count)
+	}
+	if flags.JSON {
+		a.printJSON(flags, text)
+		return exitSuccess
+	}
+	fmt.Fprintln(a.Out, text.Text)
+	return exitSuccess
+}
+
+func (a App) runAttr(store profile.Store, mgr daemon.Manager, flags GlobalFlags, selector string, name string) int {
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	selector = selectorFor(flags, selector)
+	a.logResolved(flags, tabID, selector)
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	attr, err := client.Attr(tabID, selector, name, timeoutMs)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(flags.Profile)
+	if !attr.Found {
+		if !flags.Quiet {
+			return a.fail(flags, fmt.Errorf("%w for %s", browser.ErrNoMatch, selector), exitNotFound)
+		}
+		return exitNotFound
+	}
+	if flags.JSON {
+		a.printJSON(flags, attr)
+		return exitSuccess
+	}
+	fmt.Fprintln(a.Out, attr.Value)
+	return exitSuccess
+}
+
+func (a App) runAttrs(store profile.Store, mgr daemon.Manager, flags GlobalFlags, selector string, nth int) int {
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	selector = selectorFor(flags, selector)
+	a.logResolved(flags, tabID, selector)
+	params := daemon.AttrsParams{Tab: tabID, Selector: selector}
+	if nth >= 0 {
+		params.Nth = &nth
+	}
+	params.TimeoutMs, err = actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	attrs, err := client.Attrs(params)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(flags.Profile)
+	if len(attrs) == 0 {
+		if !flags.Quiet {
+			return a.fail(flags, fmt.Errorf("%w for %s", browser.ErrNoMatch, selector), exitNotFound)
+		}
+		return exitNotFound
+	}
+	if len(attrs) == 1 {
+		a.printJSON(flags, attrs[0])
+		return exitSuccess
+	}
+	a.printJSON(flags, attrs)
+	return exitSuccess
+}
+
+type storageItemOutput struct {
+	Origin string `json:"origin"`
+	Key    string `json:"key"`
+	Value  any    `json:"value"`
+}
+
+func storageValue(value string) any {
+	if json.Valid([]byte(value)) {
+		return json.RawMessage(value)
+	}
+	return value
+}
+
+func (a App) runStorageItem(store profile.Store, mgr daemon.Manager, flags GlobalFlags, params daemon.StorageItemParams) int {
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	a.logResolved(flags, tabID, "")
+	params.Tab = tabID
+	params.TimeoutMs, err = actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	result, err := client.StorageItem(params)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(flags.Profile)
+	if params.Op == "list" {
+		if flags.JSON {
+			items := make(map[string]any, len(result.Items))
+			for k, v := range result.Items {
+				items[k] = storageValue(v)
+			}
+			a.printJSON(flags, items)
+			return exitSuccess
+		}
+		keys := make([]string, 0, len(result.Items))
+		for k := range result.Items {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(a.Out, "%s=%s\n", k, result.Items[k])
+		}
+		return exitSuccess
+	}
+	if !result.Found {
+		if !flags.Quiet {
+			return a.fail(flags, fmt.Errorf("no storage item %q for %s", params.Key, result.Origin), exitNotFound)
+		}
+		return exitNotFound
+	}
+	if flags.JSON {
+		a.printJSON(flags, storageItemOutput{Origin: result.Origin, Key: params.Key, Value: storageValue(result.Value)})
+		return exitSuccess
+	}
+	if params.Op == "get" {
+		fmt.Fprintln(a.Out, result.Value)
+	}
+	return exitSuccess
+}
+
+func (a App) runBox(store profile.Store, mgr daemon.Manager, flags GlobalFlags, selector string) int {
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	selector = selectorFor(flags, selector)
+	a.logResolved(flags, tabID, selector)
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	selectorMs, err := selectorTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	box, err := client.BoxWithOptions(daemon.BoxParams{Tab: tabID, Selector: selector, TimeoutMs: timeoutMs, SelectorTimeoutMs: selectorMs})
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	a.printJSON(flags, box)
+	if box == nil {
+		return exitNotFound
+	}
+	return exitSuccess
+}
+
+func (a App) runEval(store profile.Store, mgr daemon.Manager, flags GlobalFlags, js string, arg string) int {
+	params := daemon.EvalParams{JS: js}
+	if arg != "" {
+		if !json.Valid([]byte(arg)) {
+			return a.actionFailed(flags, fmt.Errorf("invalid --arg: %q is not valid JSON", arg), exitUsage)
+		}
+		params.Args = json.RawMessage(arg)
+	}
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.actionFailed(flags, err, exitFailure)
+	}
+	defer client.Close()
+	a.logResolved(flags, tabID, "")
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.actionFailed(flags, err, exitUsage)
+	}
+	params.Tab = tabID
+	params.TimeoutMs = timeoutMs
+	result, err := client.EvalWithOptions(params)
+	if err != nil {
+		return a.actionFailed(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(flags.Profile)
+	if flags.JSON {
+		a.actionSucceeded(flags, result)
+		return exitSuccess
+	}
+	fmt.Fprintln(a.Out, string(result))
+	return exitSuccess
+}
+
+func (a App) runWaitURL(store profile.Store, mgr daemon.Manager, flags GlobalFlags, pattern string, regex bool) int {
+	if regex {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return a.fail(flags, fmt.Errorf("invalid --regex pattern: %w", err), exitUsage)
+		}
+	}
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	a.logResolved(flags, tabID, "")
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	if err := client.WaitForURL(daemon.WaitURLParams{Tab: tabID, Pattern: pattern, Regex: regex, TimeoutMs: timeoutMs}); err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(flags.Profile)
+	return exitSuccess
+}
+
+func (a App) runWaitIdle(store profile.Store, mgr daemon.Manager, flags GlobalFlags) int {
+	client, tabID, err := a.prepareClient(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	a.logResolved(flags, tabID, "")
+	timeoutMs, err := actionTimeoutMs(flags)
+	if err != nil {
+		return a.fail(flags, err, exitUsage)
+	}
+	if err := client.WaitIdle(tabID, timeoutMs); err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	_, _ = store.Touch(flags.Profile)
+	return exitSuccess
+}
+
+func (a App) runCookiesExport(store profile.Store, mgr daemon.Manager, flags GlobalFlags, format string, path string) int {
+	if format != "json" && format != "netscape" {
+		return a.fail(flags, fmt.Errorf("invalid format %q: expected json or netscape", format), exitUsage)
+	}
+	client, err := a.prepareClientNoTab(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	cookies, err := client.Cookies()
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	var buf bytes.Buffer
+	if format == "netscape" {
+		if err := browser.WriteNetscapeCookies(&buf, cookies); err != nil {
+			return a.fail(flags, err, exitFailure)
+		}
+	} else {
+		b, _ := json.MarshalIndent(cookies, "", "  ")
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	if !flags.Quiet {
+		fmt.Fprintf(a.Out, "exported %d cookies to %s\n", len(cookies), path)
+	}
+	return exitSuccess
+}
+
+func (a App) runNet(store profile.Store, mgr daemon.Manager, flags GlobalFlags, limit int) int {
+	client, err := a.prepareClientNoTab(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	records, err := client.NetLog(limit)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	if flags.JSON {
+		a.printJSON(flags, records)
+		return exitSuccess
+	}
+	for _, record := range records {
+		fmt.Fprintf(a.Out, "%d %s %s\n", record.Status, record.Method, record.URL)
+	}
+	return exitSuccess
+}
+
+func (a App) runDownloads(store profile.Store, mgr daemon.Manager, flags GlobalFlags, limit int) int {
+	client, err := a.prepareClientNoTab(store, mgr, flags)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	defer client.Close()
+	downloads, err := client.Downloads(limit)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	if flags.JSON {
+		a.printJSON(flags, downloads)
+		return exitSuccess
+	}
+	for _, d := range downloads {
+		if d.Error != "" {
+			fmt.Fprintf(a.Out, "failed %s %s: %s\n", d.Filename, d.URL, d.Error)
+			continue
+		}
+		fmt.Fprintf(a.Out, "%s %d %dms %s\n", d.Path, d.Bytes, d.DurationMs, d.URL)
+	}
+	return exitSuccess
+}
+
+type actionStatus struct {
+	OK     bool            `json:"ok"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Code   string          `json:"code,omitempty"`
+}
+
+func (a App) actionSucceeded(flags GlobalFlags, result json.RawMessage) {
+	if !flags.JSON {
+		return
+	}
+	if flags.Envelope {
+		a.printEnvelope(flags, jsonEnvelope{OK: true, Data: result})
+		return
+	}
+	b, _ := json.Marshal(actionStatus{OK: true, Result: result})
+	fmt.Fprintln(a.Out, string(b))
+}
+
+func (a App) actionFailed(flags GlobalFlags, err error, code int) int {
+	if !flags.JSON {
 		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return code
+	}
+	if flags.Envelope {
+		a.printEnvelope(flags, jsonEnvelope{OK: false, Error: err.Error(), Code: classifyError(err)})
+		return code
+	}
+	b, _ := json.Marshal(actionStatus{OK: false, Error: err.Error(), Code: classifyError(err)})
+	fmt.Fprintln(a.Out, string(b))
+	return code
+}
+
+// errNotRunning marks commands that need a daemon that is not running.
+var errNotRunning = errors.New("not running")
+
+// validateDevice checks --device before it is saved to the profile; tests
+// replace it to avoid starting the Playwright driver.
+var validateDevice = browser.ValidateDevice
+
+// codeNotRunning is the JSON error code for errNotRunning and for sockets
+// nobody is listening on.
+const codeNotRunning = "not_running"
+
+type jsonError struct {
+	Error jsonErrorBody `json:"error"`
+}
+
+type jsonErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// classifyError returns a stable code for err: the daemon's code when it sent
+// one, otherwise one derived locally, or "" when none applies.
+func classifyError(err error) string {
+	if code := daemon.ErrorCode(err); code != "" {
+		return code
+	}
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, errNotRunning), errors.As(err, &opErr) && opErr.Op == "dial":
+		return codeNotRunning
+	case errors.Is(err, browser.ErrNoMatch):
+		return daemon.CodeSelectorNoMatch
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return daemon.CodeTimeout
+	}
+	return ""
+}
+
+// fail reports err and returns code. With --json the error goes to stderr as
+// {"error": {"code", "message"}}; codes not classified by classifyError fall
+// back to usage, not_found, or error by exit code.
+func (a App) fail(flags GlobalFlags, err error, code int) int {
+	if !flags.JSON {
+		fmt.Fprintln(a.Err, err)
+		return code
+	}
+	kind := classifyError(err)
+	if kind == "" {
+		switch code {
+		case exitUsage:
+			kind = "usage"
+		case exitNotFound:
+			kind = "not_found"
+		default:
+			kind = "error"
+		}
+	}
+	b, _ := json.Marshal(jsonError{Error: jsonErrorBody{Code: kind, Message: err.Error()}})
+	fmt.Fprintln(a.Err, string(b))
+	return code
+}
+
+type jsonEnvelope struct {
+	Command string `json:"command"`
+	Profile string `json:"profile,omitempty"`
+	OK      bool   `json:"ok"`
+	Data    any    `json:"data,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Code    string `json:"code,omitempty"`
+}
+
+// outputWriter returns where --output sends results: a.Out when path is
+// empty or "-", otherwise a new file at path, creating parent directories.
+// Call the returned function to close it.
+func (a App) outputWriter(path string) (io.Writer, func() error, error) {
+	if path == "" || path == "-" {
+		return a.Out, func() error { return nil }, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
+
+// writeOutput runs print with a.Out pointed at --output.
+func (a App) writeOutput(flags GlobalFlags, print func(App)) int {
+	out, closeOut, err := a.outputWriter(flags.Output)
+	if err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	a.Out = out
+	print(a)
+	if err := closeOut(); err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	return exitSuccess
+}
+
+func (a App) printJSON(flags GlobalFlags, v any) {
+	if flags.Envelope {
+		a.printEnvelope(flags, jsonEnvelope{OK: true, Data: v})
+		return
+	}
+	if raw, ok := v.(json.RawMessage); ok {
+		fmt.Fprintln(a.Out, string(raw))
+		return
+	}
+	b, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Fprintln(a.Out, string(b))
+}
+
+func (a App) printEnvelope(flags GlobalFlags, env jsonEnvelope) {
+	env.Command = flags.Command
+	env.Profile = flags.Profile
+	b, _ := json.MarshalIndent(env, "", "  ")
+	fmt.Fprintln(a.Out, string(b))
+}
+
+func (a App) prepareClient(store profile.Store, mgr daemon.Manager, flags GlobalFlags) (*daemon.Client, int, error) {
+	if a.script != nil {
+		tabID := flags.Tab
+		if tabID == 0 {
+			tabID = a.script.tabID
+		}
+		return a.script.client.Borrow(), tabID, nil
+	}
+	name := flags.Profile
+	if name == "" {
+		return nil, 0, errors.New("-p/--profile is required")
+	}
+	if err := a.upsertRunning(store, mgr, name, flags); err != nil {
+		return nil, 0, err
+	}
+	client, err := a.newClient(mgr, name, flags)
+	if err != nil {
+		return nil, 0, err
+	}
+	tabID, err := resolveTabID(client, flags.Tab)
+	if err != nil {
+		_ = client.Close()
+		return nil, 0, err
+	}
+	return client, tabID, nil
+}
+
+func (a App) prepareClientNoTab(store profile.Store, mgr daemon.Manager, flags GlobalFlags) (*daemon.Client, error) {
+	if a.script != nil {
+		return a.script.client.Borrow(), nil
+	}
+	name := flags.Profile
+	if name == "" {
+		return nil, errors.New("-p/--profile is required")
+	}
+	if err := a.upsertRunning(store, mgr, name, flags); err != nil {
+		return nil, err
+	}
+	return a.newClient(mgr, name, flags)
+}
+
+// newClient connects to the daemon of profile name. Every command builds its
+// clients here so --dry-run applies to all of them.
+func (a App) newClient(mgr daemon.Manager, name string, flags GlobalFlags) (*daemon.Client, error) {
+	client, err := daemon.NewClient(mgr.SocketPath(profile.SafeName(name)))
+	if err != nil {
+		return nil, err
+	}
+	if flags.DryRun {
+		client.DryRun = a.Out
+	}
+	return client, nil
+}
+
+// defaultActionTimeout bounds each action when neither --timeout nor
+// default_timeout is set.
+const defaultActionTimeout = daemon.DefaultActionTimeout
+
+func actionTimeoutMs(flags GlobalFlags) (int, error) {
+	if strings.TrimSpace(flags.Timeout) == "" {
+		if flags.DefaultTimeout > 0 {
+			return int(flags.DefaultTimeout.Milliseconds()), nil
+		}
+		return int(defaultActionTimeout.Milliseconds()), nil
+	}
+	d, err := parseDurationFlag(flags.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout: %w", err)
+	}
+	if d <= 0 {
+		return 0, nil
+	}
+	return int(d.Milliseconds()), nil
+}
+
+func selectorTimeoutMs(flags GlobalFlags) (int, error) {
+	if strings.TrimSpace(flags.SelectorTimeout) == "" {
+		return 0, nil
+	}
+	d, err := parseDurationFlag(flags.SelectorTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid selector timeout: %w", err)
+	}
+	if d <= 0 {
+		return 0, nil
+	}
+	return int(d.Milliseconds()), nil
+}
+
+func retryDelay(flags GlobalFlags) (time.Duration, error) {
+	if strings.TrimSpace(flags.RetryDelay) == "" {
+		return time.Second, nil
+	}
+	d, err := parseDurationFlag(flags.RetryDelay)
+	if err != nil {
+		return 0, fmt.Errorf("invalid retry delay: %w", err)
+	}
+	return d, nil
+}
+
+func (a App) withRetry(flags GlobalFlags, fn func() error) error {
+	delay, err := retryDelay(flags)
+	if err != nil {
+		return err
+	}
+	err = fn()
+	for attempt := 1; err != nil && attempt <= flags.Retry; attempt++ {
+		if flags.Verbose && !flags.Quiet {
+			fmt.Fprintf(a.Err, "retry %d/%d after error: %v\n", attempt, flags.Retry, err)
+		}
+		time.Sleep(delay)
+		err = fn()
+	}
+	return err
+}
+
+type repeatStats struct {
+	Runs      int     `json:"runs"`
+	OK        int     `json:"ok"`
+	Failed    int     `json:"failed"`
+	MinMs     int64   `json:"min_ms"`
+	MaxMs     int64   `json:"max_ms"`
+	AvgMs     float64 `json:"avg_ms"`
+	LastError string  `json:"last_error,omitempty"`
+	totalMs   int64
+}
+
+func (s *repeatStats) add(elapsed time.Duration, err error) {
+	ms := elapsed.Milliseconds()
+	if s.Runs == 0 || ms < s.MinMs {
+		s.MinMs = ms
+	}
+	if ms > s.MaxMs {
+		s.MaxMs = ms
+	}
+	s.Runs++
+	s.totalMs += ms
+	s.AvgMs = float64(s.totalMs) / float64(s.Runs)
+	if err != nil {
+		s.Failed++
+		s.LastError = err.Error()
+		return
+	}
+	s.OK++
+}
+
+func (a App) runRepeat(flags GlobalFlags, fn func() error) int {
+	var stats repeatStats
+	for i := 0; i < flags.Repeat; i++ {
+		started := time.Now()
+		err := fn()
+		stats.add(time.Since(started), err)
+		if err != nil && flags.Verbose && !flags.Quiet {
+			fmt.Fprintf(a.Err, "run %d failed: %v\n", i+1, err)
+		}
+	}
+	code := exitSuccess
+	if stats.Failed > 0 {
+		code = exitFailure
+	}
+	if flags.JSON {
+		a.printJSON(flags, stats)
+		return code
+	}
+	fmt.Fprintf(a.Out, "runs=%d ok=%d failed=%d min=%dms max=%dms avg=%.1fms\n", stats.Runs, stats.OK, stats.Failed, stats.MinMs, stats.MaxMs, stats.AvgMs)
+	if stats.LastError != "" {
+		fmt.Fprintf(a.Out, "last_error=%s\n", stats.LastError)
+	}
+	return code
+}
+
+func parseDurationFlag(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if n, err := strconv.Atoi(value); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a duration; use seconds or a unit, e.g. 5, 5s, or 500ms", value)
+	}
+	return d, nil
+}
+
+func (a App) runServe(cfg config.Config, store profile.Store, flags GlobalFlags) int {
+	name := flags.Profile
+	if name == "" {
+		return a.fail(flags, errors.New("-p/--profile is required"), exitUsage)
+	}
+	lock, err := store.LockShared(name)
+	if err != nil {
+		return a.failServe(store.Root, flags, err, exitFailure)
+	}
+	defer lock.Unlock()
+	p, err := store.Load(name)
+	if err != nil {
+		return a.failServe(store.Root, flags, err, exitFailure)
 	}
 	socket := filepath.Join(store.ProfileDir(p.Name), "daemon.sock")
-	info := daemon.Info{PID: os.Getpid(), Socket: socket, StartedAt: daemon.NowUTC()}
+	info := daemon.Info{PID: os.Getpid(), Socket: socket, StartedAt: daemon.NowUTC(), NoDefaultTab: flags.NoDefaultTab, Incognito: flags.Incognito}
 	if path, modTime, err := daemon.CurrentBinaryInfo(); err == nil {
 		info.BinaryPath = path
 		info.BinaryModTime = modTime
 	}
 	if err := daemon.WriteInfo(filepath.Join(store.ProfileDir(p.Name), "daemon.json"), info); err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+		return a.failServe(store.Root, flags, err, exitFailure)
 	}
-	opts := browser.StartOptions{Browser: p.Browser, Channel: p.Channel, Headless: p.Headless, StorageIn: store.StorageStatePath(p.Name)}
-	if err := daemon.ServeProfile(socket, p.Name, browser.PlaywrightEngine{}, opts); err != nil {
-		fmt.Fprintln(a.Err, err)
-		return exitFailure
+	opts := browser.StartOptions{Browser: p.Browser, Channel: p.Channel, Headless: p.Headless, StorageIn: store.StorageStatePath(p.Name), Width: p.Width, Height: p.Height, Device: p.Device, Proxy: p.Proxy, ProxyBypass: p.ProxyBypass, UserAgent: p.UserAgent, DownloadDir: store.DownloadDir(p), ExtraHeaders: p.ExtraHeaders, Locale: p.Locale, Timezone: p.Timezone}
+	if p.Geolocation != nil {
+		opts.Geolocation = &browser.Geolocation{Latitude: p.Geolocation.Latitude, Longitude: p.Geolocation.Longitude}
+	}
+	if p.Trace {
+		opts.Trace = true
+		opts.NetLog = filepath.Join(store.ProfileDir(p.Name), "network.log")
+	}
+	level, err := parseLogLevel(flags.LogLevel)
+	if err != nil {
+		return a.failServe(store.Root, flags, err, exitUsage)
+	}
+	logger := slog.New(slog.NewJSONHandler(a.Err, &slog.HandlerOptions{Level: level}))
+	serveOpts := daemon.ServeOptions{Logger: logger, SocketMode: cfg.SocketMode, DirMode: cfg.ProfileMode, IdleTimeout: cfg.IdleTimeout, NoDefaultTab: flags.NoDefaultTab, Home: p.Home, AutoDismiss: p.AutoDismiss, Ephemeral: flags.Incognito}
+	if err := daemon.ServeProfile(socket, p.Name, browser.PlaywrightEngine{}, opts, serveOpts); err != nil {
+		return a.fail(flags, err, exitFailure)
+	}
+	return exitSuccess
+}
+
+// failServe records err for the client waiting on the daemon to start, so
+// start reports it instead of timing out, and then fails like a.fail.
+func (a App) failServe(profileDir string, flags GlobalFlags, err error, code int) int {
+	if flags.Profile != "" && profileDir != "" {
+		daemon.Manager{ProfileDir: profileDir}.RecordStartupError(profile.SafeName(flags.Profile), err)
+	}
+	return a.fail(flags, err, code)
+}
+
+func parseLogLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if strings.TrimSpace(value) == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+		return 0, fmt.Errorf("invalid log level %q (use debug, info, warn, or error)", value)
+	}
+	return level, nil
+}
+
+func (a App) runLogs(mgr daemon.Manager, flags GlobalFlags, lines int) int {
+	if flags.Profile == "" {
+		return a.fail(flags, errors.New("-p/--profile is required"), exitUsage)
+	}
+	tail, err := mgr.LogTail(profile.SafeName(flags.Profile), lines)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return a.fail(flags, fmt.Errorf("no daemon log for %s", flags.Profile), exitNotFound)
+		}
+		return a.fail(flags, err, exitFailure)
+	}
+	if tail != "" {
+		fmt.Fprintln(a.Out, tail)
 	}
 	return exitSuccess
 }
 
-func ensureRunning(mgr daemon.Manager, name string, noStart bool) error {
+func (a App) upsertRunning(store profile.Store, mgr daemon.Manager, name string, flags GlobalFlags) error {
+	lock, err := store.LockShared(name)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	if _, _, err := store.Upsert(name, profile.Overrides{}); err != nil {
+		return err
+	}
+	return a.ensureRunning(mgr, name, flags)
+}
+
+func (a App) ensureRunning(mgr daemon.Manager, name string, flags GlobalFlags) error {
+	prev, _ := mgr.LoadInfo(name)
+	stopped := false
+	if flags.Fresh {
+		running, _, err := mgr.IsRunning(name)
+		if err != nil {
+			return err
+		}
+		if err := mgr.StopWait(name); err != nil {
+			return err
+		}
+		stopped = running
+	}
+	restarted, lostTabs, err := mgr.StopIfOutdated(name)
+	if err != nil {
+		return err
+	}
+	if stopped || restarted {
+		mgr.NoDefaultTab = mgr.NoDefaultTab || prev.NoDefaultTab
+		mgr.Incognito = mgr.Incognito || prev.Incognito
+	}
 	running, _, err := mgr.IsRunning(name)
 	if err != nil {
 		return err
@@ -658,10 +3378,31 @@ func ensureRunning(mgr daemon.Manager, name string, noStart bool) error {
 	if running {
 		return nil
 	}
-	if noStart {
-		return errors.New("profile is not running")
+	if flags.NoStart {
+		if restarted && !flags.Quiet {
+			fmt.Fprintf(a.Err, "daemon for %s stopped due to binary change%s\n", name, tabsLostNote(lostTabs))
+		}
+		return fmt.Errorf("profile is %w", errNotRunning)
+	}
+	if err := mgr.Start(name); err != nil {
+		return err
+	}
+	if restarted && !flags.Quiet {
+		fmt.Fprintf(a.Err, "daemon for %s restarted due to binary change%s\n", name, tabsLostNote(lostTabs))
 	}
-	return mgr.Start(name)
+	return nil
+}
+
+// tabsLostNote describes the tabs an outdated daemon had open when it was
+// stopped, or is empty when it had none.
+func tabsLostNote(tabs int) string {
+	switch {
+	case tabs == 1:
+		return "; 1 tab lost"
+	case tabs > 1:
+		return fmt.Sprintf("; %d tabs lost", tabs)
+	}
+	return ""
 }
 
 func resolveTabID(client *daemon.Client, requested int) (int, error) {
@@ -676,15 +3417,59 @@ func resolveTabID(client *daemon.Client, requested int) (int, error) {
 }
 
 func resolveTabIDFromStatus(status daemon.StatusResult) (int, error) {
-	if len(status.Tabs) == 1 {
-		return status.Tabs[0].ID, nil
+	tabs := make([]daemon.TabInfo, 0, len(status.Tabs))
+	for _, tab := range status.Tabs {
+		if !tab.Crashed {
+			tabs = append(tabs, tab)
+		}
 	}
-	if len(status.Tabs) == 0 {
-		return 0, errors.New("no tabs available")
+	if len(tabs) == 1 {
+		return tabs[0].ID, nil
+	}
+	if len(tabs) == 0 {
+		return 0, daemon.ErrNoTabs
 	}
 	return 0, errors.New("multiple tabs; use --tab")
 }
 
+// activeTabIDFromStatus picks the daemon's active tab, falling back to the
+// only open tab when none is marked active.
+func activeTabIDFromStatus(status daemon.StatusResult) (int, error) {
+	for _, tab := range status.Tabs {
+		if tab.Active && !tab.Crashed {
+			return tab.ID, nil
+		}
+	}
+	return resolveTabIDFromStatus(status)
+}
+
+func (a App) logResolved(flags GlobalFlags, tabID int, selector string) {
+	if !flags.Verbose || flags.Quiet {
+		return
+	}
+	if selector == "" {
+		fmt.Fprintf(a.Err, "resolved tab=%d\n", tabID)
+		return
+	}
+	fmt.Fprintf(a.Err, "resolved tab=%d selector=%q\n", tabID, selector)
+}
+
+func selectorFor(flags GlobalFlags, value string) string {
+	if flags.RawSelector {
+		return value
+	}
+	return normalizeSelector(value)
+}
+
+// flagSelectorFor is the resolved form of --selector for verbose logs. Unlike
+// positional selectors it is never matched as text, so a bare value is CSS.
+func flagSelectorFor(value string) string {
+	if value == "" || strings.HasPrefix(value, "text=") || strings.HasPrefix(value, "css=") {
+		return value
+	}
+	return "css=" + value
+}
+
 func normalizeSelector(value string) string {
 	if strings.HasPrefix(value, "text=") {
 		return value
@@ -715,11 +3500,145 @@ func overridesFromFlags(flags GlobalFlags) (profile.Overrides, error) {
 		overrides.Headless = &headless
 	}
 	if flags.TTL != "" {
-		d, err := time.ParseDuration(flags.TTL)
+		d, err := parseDurationFlag(flags.TTL)
 		if err != nil {
 			return overrides, fmt.Errorf("invalid ttl: %w", err)
 		}
 		overrides.TTL = &d
 	}
+	if flags.Viewport != "" {
+		width, height, err := parseViewport(flags.Viewport)
+		if err != nil {
+			return overrides, err
+		}
+		overrides.Width = width
+		overrides.Height = height
+	}
+	if flags.Device != "" {
+		device := strings.TrimSpace(flags.Device)
+		if err := validateDevice(device); err != nil {
+			return overrides, err
+		}
+		overrides.Device = device
+	}
+	if flags.Proxy != "" {
+		proxy, err := parseProxy(flags.Proxy)
+		if err != nil {
+			return overrides, err
+		}
+		overrides.Proxy = proxy
+	}
+	if flags.ProxyBypass != "" {
+		overrides.ProxyBypass = strings.TrimSpace(flags.ProxyBypass)
+	}
+	if flags.DownloadDir != "" {
+		dir, err := filepath.Abs(flags.DownloadDir)
+		if err != nil {
+			return overrides, err
+		}
+		overrides.DownloadDir = dir
+	}
+	if len(flags.Headers) > 0 {
+		headers, err := parseHeaders(flags.Headers)
+		if err != nil {
+			return overrides, err
+		}
+		overrides.ExtraHeaders = headers
+	}
+	if flags.Geo != "" {
+		geo, err := parseGeo(flags.Geo)
+		if err != nil {
+			return overrides, err
+		}
+		overrides.Geolocation = geo
+	}
+	if flags.Locale != "" {
+		overrides.Locale = strings.TrimSpace(flags.Locale)
+	}
+	if flags.Timezone != "" {
+		overrides.Timezone = strings.TrimSpace(flags.Timezone)
+	}
+	if flags.Home != "" {
+		home := strings.TrimSpace(flags.Home)
+		if u, err := url.Parse(home); err != nil || u.Scheme == "" {
+			return overrides, fmt.Errorf("invalid home %q: expected an absolute URL like https://example.com", flags.Home)
+		}
+		overrides.Home = home
+	}
 	return overrides, nil
 }
+
+func parseGeo(value string) (*profile.Geolocation, error) {
+	latText, lonText, ok := strings.Cut(value, ",")
+	if !ok {
+		return nil, fmt.Errorf("invalid geo %q: expected LAT,LON", value)
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latText), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid geo %q: expected LAT,LON", value)
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(lonText), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid geo %q: expected LAT,LON", value)
+	}
+	if math.IsNaN(lat) || math.IsNaN(lon) || math.IsInf(lat, 0) || math.IsInf(lon, 0) {
+		return nil, fmt.Errorf("invalid geo %q: expected finite numbers", value)
+	}
+	if lat < -90 || lat > 90 {
+		return nil, fmt.Errorf("invalid geo %q: latitude must be between -90 and 90", value)
+	}
+	if lon < -180 || lon > 180 {
+		return nil, fmt.Errorf("invalid geo %q: longitude must be between -180 and 180", value)
+	}
+	return &profile.Geolocation{Latitude: lat, Longitude: lon}, nil
+}
+
+func formatGeo(geo profile.Geolocation) string {
+	return strconv.FormatFloat(geo.Latitude, 'f', -1, 64) + "," + strconv.FormatFloat(geo.Longitude, 'f', -1, 64)
+}
+
+func parseHeaders(values []string) (map[string]string, error) {
+	headers := make(map[string]string, len(values))
+	for _, value := range values {
+		name, val, ok := strings.Cut(value, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid header %q: expected \"Name: Value\"", value)
+		}
+		headers[name] = strings.TrimSpace(val)
+	}
+	return headers, nil
+}
+
+func parseProxy(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if !strings.Contains(value, "://") {
+		value = "http://" + value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid proxy %q: expected SCHEME://HOST:PORT", value)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return "", fmt.Errorf("invalid proxy scheme %q: expected http, https, or socks5", u.Scheme)
+	}
+	return value, nil
+}
+
+func parseViewport(value string) (int, int, error) {
+	w, h, ok := strings.Cut(strings.ToLower(strings.TrimSpace(value)), "x")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid viewport %q: expected WIDTHxHEIGHT", value)
+	}
+	width, err := strconv.Atoi(w)
+	if err != nil || width <= 0 {
+		return 0, 0, fmt.Errorf("invalid viewport %q: expected WIDTHxHEIGHT", value)
+	}
+	height, err := strconv.Atoi(h)
+	if err != nil || height <= 0 {
+		return 0, 0, fmt.Errorf("invalid viewport %q: expected WIDTHxHEIGHT", value)
+	}
+	return width, height, nil
+}
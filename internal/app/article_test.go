@@ -0,0 +1,32 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/patrickjm/www/internal/browser"
+)
+
+func TestArticleFromExtract(t *testing.T) {
+	got := articleFromExtract(browser.ExtractResult{
+		URL:       "https://example.com/post?utm=1",
+		Title:     "  A Post ",
+		Text:      "\nBody text\n",
+		Meta:      map[string]string{"description": "Summary", "author": "Ada"},
+		Canonical: "https://example.com/post",
+		Lang:      "en",
+		Byline:    "Ada",
+		Links:     []browser.ExtractLink{{Text: "x", Href: "y"}},
+	})
+	want := articleResult{
+		URL:         "https://example.com/post?utm=1",
+		Canonical:   "https://example.com/post",
+		Title:       "A Post",
+		Byline:      "Ada",
+		Lang:        "en",
+		Description: "Summary",
+		Text:        "Body text",
+	}
+	if got != want {
+		t.Fatalf("unexpected article:\n got %+v\nwant %+v", got, want)
+	}
+}
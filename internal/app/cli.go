@@ -7,6 +7,11 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/patrickjm/www/internal/browser"
+	"github.com/patrickjm/www/internal/config"
+	"github.com/patrickjm/www/internal/daemon"
 )
 
 type exitError struct {
@@ -22,6 +27,21 @@ var Version = "dev"
 func Execute(args []string, out io.Writer, errOut io.Writer) int {
 	app := App{Out: out, Err: errOut}
 	flags := GlobalFlags{}
+	root := newRootCmd(app, &flags)
+	root.SetArgs(args)
+	if err := root.Execute(); err != nil {
+		var exit exitError
+		if errors.As(err, &exit) {
+			return exit.code
+		}
+		return app.fail(flags, err, exitUsage)
+	}
+	return exitSuccess
+}
+
+// newRootCmd builds the www command tree. Commands parse into flags and run
+// with app, which www run also uses to run each script line as a command.
+func newRootCmd(app App, flags *GlobalFlags) *cobra.Command {
 	var showVersion bool
 
 	root := &cobra.Command{
@@ -32,12 +52,13 @@ func Execute(args []string, out io.Writer, errOut io.Writer) int {
 			return cmd.Help()
 		},
 	}
-	root.SetOut(out)
-	root.SetErr(errOut)
+	root.SetOut(app.Out)
+	root.SetErr(app.Err)
 
 	root.PersistentFlags().BoolVarP(&showVersion, "version", "V", false, "version")
 	root.PersistentFlags().StringVarP(&flags.Profile, "profile", "p", "", "profile name")
 	root.PersistentFlags().StringVarP(&flags.ProfileDir, "profile-dir", "D", "", "profile directory")
+	root.PersistentFlags().StringVar(&flags.Config, "config", "", "config file (overrides discovered config files)")
 	root.PersistentFlags().BoolVarP(&flags.JSON, "json", "j", false, "json output")
 	root.PersistentFlags().BoolVarP(&flags.Plain, "plain", "P", false, "plain output")
 	root.PersistentFlags().BoolVarP(&flags.Quiet, "quiet", "q", false, "quiet output")
@@ -52,13 +73,38 @@ func Execute(args []string, out io.Writer, errOut io.Writer) int {
 	root.PersistentFlags().StringVarP(&flags.TTL, "ttl", "L", "", "profile ttl")
 	root.PersistentFlags().StringVarP(&flags.Selector, "selector", "S", "", "selector")
 	root.PersistentFlags().BoolVarP(&flags.Main, "main", "m", false, "use main content")
-	root.PersistentFlags().StringVarP(&flags.Timeout, "timeout", "t", "", "action timeout")
+	root.PersistentFlags().BoolVar(&flags.Shadow, "shadow", false, "include content inside open shadow roots")
+	root.PersistentFlags().BoolVar(&flags.OnlyVisible, "only-visible", false, "collect only rendered links (skip hidden and offscreen elements)")
+	root.PersistentFlags().StringVarP(&flags.Timeout, "timeout", "t", "", "navigation and action timeout")
+	root.PersistentFlags().IntVar(&flags.Retry, "retry", 0, "retry goto, click, fill, and fill-label up to N times on error")
+	root.PersistentFlags().StringVar(&flags.RetryDelay, "retry-delay", "", "delay between retries (default 1s)")
+	root.PersistentFlags().StringVar(&flags.SelectorTimeout, "selector-timeout", "", "element wait timeout (defaults to --timeout)")
+	root.PersistentFlags().StringVar(&flags.Viewport, "viewport", "", "viewport size (WIDTHxHEIGHT)")
+	root.PersistentFlags().BoolVar(&flags.RawSelector, "raw-selector", false, "pass selectors to Playwright verbatim")
+	root.PersistentFlags().BoolVar(&flags.DryRun, "dry-run", false, "print state-changing requests instead of sending them")
+	root.PersistentFlags().BoolVar(&flags.Fresh, "fresh", false, "restart the profile daemon before running (discards open tabs)")
+	root.PersistentFlags().BoolVar(&flags.Envelope, "envelope", false, "wrap JSON output in {command, profile, ok, data}")
+	root.PersistentFlags().StringVar(&flags.Proxy, "proxy", "", "proxy server (e.g. http://host:port)")
+	root.PersistentFlags().StringVar(&flags.DownloadDir, "download-dir", "", "directory for files downloaded by the browser (default PROFILE/downloads)")
+	root.PersistentFlags().BoolVar(&flags.Artifacts, "artifacts", false, "write relative shot/pdf paths under the profile's artifacts directory")
+	root.PersistentFlags().StringVar(&flags.Home, "home", "", "URL the profile's first tab opens when the daemon starts")
+	root.PersistentFlags().StringVar(&flags.Geo, "geo", "", "emulated geolocation LAT,LON (grants the geolocation permission)")
+	root.PersistentFlags().StringVar(&flags.Locale, "locale", "", "browser locale (e.g. de-DE)")
+	root.PersistentFlags().StringVar(&flags.Timezone, "timezone", "", "browser timezone ID (e.g. Europe/Berlin)")
+	root.PersistentFlags().StringArrayVar(&flags.Headers, "header", nil, "extra HTTP header sent with every request (\"Name: Value\", repeatable)")
+	root.PersistentFlags().StringVar(&flags.ProxyBypass, "proxy-bypass", "", "comma-separated hosts that skip the proxy")
+	root.PersistentFlags().StringVar(&flags.Device, "device", "", "device preset (e.g. \"iPhone 13\")")
+	root.PersistentFlags().StringVar(&flags.LogLevel, "log-level", "", "daemon log level: debug, info, warn, error (default info)")
 
 	root.PersistentPreRunE = func(cmd *cobra.Command, _ []string) error {
 		if showVersion {
-			fmt.Fprintln(out, Version)
+			fmt.Fprintln(app.Out, Version)
 			return exitError{code: exitSuccess}
 		}
+		flags.Command = strings.TrimPrefix(cmd.CommandPath(), root.Name()+" ")
+		if flags.Envelope {
+			flags.JSON = true
+		}
 		return nil
 	}
 
@@ -66,80 +112,128 @@ func Execute(args []string, out io.Writer, errOut io.Writer) int {
 		Use:   "install",
 		Short: "Install Playwright driver and browsers",
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			code := app.runInstall(flags)
+			code := app.runInstall(*flags)
 			return exitOrNil(code)
 		},
 	})
 
 	root.AddCommand(&cobra.Command{
+		Use:   "config",
+		Short: "Show the effective configuration and which files it came from",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := config.Load(flags.Config, flags.ProfileDir, "")
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runConfig(cfg, *flags)
+			return exitOrNil(code)
+		},
+	})
+
+	doctorCmd := &cobra.Command{
 		Use:   "doctor",
 		Short: "Check install and environment health",
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			cfg, _, _, err := app.prepare(flags)
+			strict, _ := cmd.Flags().GetBool("strict")
+			cfg, err := config.Load(flags.Config, flags.ProfileDir, "")
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runDoctor(cfg, flags)
+			code := app.runDoctor(cfg, *flags, strict)
 			return exitOrNil(code)
 		},
-	})
+	}
+	doctorCmd.Flags().Bool("strict", false, "also fail when optional browsers are missing")
+	root.AddCommand(doctorCmd)
 
-	root.AddCommand(&cobra.Command{
+	startCmd := &cobra.Command{
 		Use:   "start",
 		Short: "Start a profile",
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			_, store, mgr, err := app.prepare(flags)
+			var trace *bool
+			if cmd.Flags().Changed("trace") {
+				value, _ := cmd.Flags().GetBool("trace")
+				trace = &value
+			}
+			cfg, store, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runStart(store, mgr, flags)
+			var dismiss []string
+			if cmd.Flags().Changed("auto-dismiss") {
+				value, _ := cmd.Flags().GetBool("auto-dismiss")
+				dismiss = autoDismissSelectors(cfg, value)
+			}
+			open, _ := cmd.Flags().GetBool("open")
+			url, _ := cmd.Flags().GetString("url")
+			code := app.runStart(store, mgr, *flags, trace, dismiss, open, url)
 			return exitOrNil(code)
 		},
-	})
+	}
+	startCmd.Flags().Bool("trace", false, "log network responses")
+	startCmd.Flags().Bool("auto-dismiss", false, "click common consent banner buttons after each navigation")
+	startCmd.Flags().BoolVar(&flags.NoDefaultTab, "no-default-tab", false, "start the daemon without opening a tab")
+	startCmd.Flags().BoolVar(&flags.Incognito, "incognito", false, "start without saved cookies and storage, and don't save any")
+	startCmd.Flags().StringVar(&flags.SocketMode, "socket-mode", "", "daemon socket permissions (default socket_mode or 0600)")
+	startCmd.Flags().StringVar(&flags.ProfileMode, "profile-mode", "", "profile directory permissions (default profile_mode or 0700)")
+	startCmd.Flags().Bool("open", false, "raise the browser window (headed only)")
+	startCmd.Flags().String("url", "", "initial URL")
+	root.AddCommand(startCmd)
 
-	root.AddCommand(&cobra.Command{
+	stopCmd := &cobra.Command{
 		Use:   "stop",
-		Short: "Stop a profile",
+		Short: "Stop a profile (-p accepts a glob such as 'test-*')",
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			_, _, mgr, err := app.prepare(flags)
+			force, _ := cmd.Flags().GetBool("force")
+			_, store, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runStop(mgr, flags)
+			code := app.runStop(store, mgr, *flags, force)
 			return exitOrNil(code)
 		},
-	})
+	}
+	stopCmd.Flags().Bool("force", false, "stop every running profile a glob matches")
+	root.AddCommand(stopCmd)
 
-	root.AddCommand(&cobra.Command{
+	psCmd := &cobra.Command{
 		Use:   "ps",
 		Short: "List running profiles",
 		RunE: func(cmd *cobra.Command, _ []string) error {
+			since, _ := cmd.Flags().GetString("since")
 			_, _, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runPs(mgr, flags)
+			code := app.runPs(mgr, *flags, since)
 			return exitOrNil(code)
 		},
-	})
+	}
+	psCmd.Flags().String("since", "", "only daemons started within DURATION (e.g. 1h)")
+	root.AddCommand(psCmd)
 
-	root.AddCommand(&cobra.Command{
+	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List profiles",
 		RunE: func(cmd *cobra.Command, _ []string) error {
+			var opts listOptions
+			opts.Since, _ = cmd.Flags().GetString("since")
+			opts.Sort, _ = cmd.Flags().GetString("sort")
+			opts.Reverse, _ = cmd.Flags().GetBool("reverse")
+			opts.NameFilter, _ = cmd.Flags().GetString("name-filter")
 			_, store, _, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runList(store, flags)
+			code := app.runList(store, *flags, opts)
 			return exitOrNil(code)
 		},
-	})
+	}
+	listCmd.Flags().String("since", "", "only profiles used within DURATION (e.g. 24h)")
+	listCmd.Flags().String("sort", "name", "sort by name, last-used, or created (newest first)")
+	listCmd.Flags().Bool("reverse", false, "reverse the sort order")
+	listCmd.Flags().String("name-filter", "", "only profiles whose name contains SUBSTR")
+	root.AddCommand(listCmd)
 
 	root.AddCommand(&cobra.Command{
 		Use:   "show NAME",
@@ -148,28 +242,43 @@ func Execute(args []string, out io.Writer, errOut io.Writer) int {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			_, store, _, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runShow(store, flags, args)
+			code := app.runShow(store, *flags, args)
 			return exitOrNil(code)
 		},
 	})
 
 	root.AddCommand(&cobra.Command{
+		Use:   "artifacts NAME",
+		Short: "List files saved in a profile's artifacts directory",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, store, _, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runArtifacts(store, *flags, args[0])
+			return exitOrNil(code)
+		},
+	})
+
+	rmCmd := &cobra.Command{
 		Use:   "rm NAME...",
-		Short: "Remove profiles",
+		Short: "Remove profiles (NAME may be a glob such as 'test-*')",
 		Args:  cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			force, _ := cmd.Flags().GetBool("force")
 			_, store, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runRemove(store, mgr, flags, args)
+			code := app.runRemove(store, mgr, *flags, args, force)
 			return exitOrNil(code)
 		},
-	})
+	}
+	rmCmd.Flags().Bool("force", false, "remove every profile a glob matches")
+	root.AddCommand(rmCmd)
 
 	pruneCmd := &cobra.Command{
 		Use:   "prune",
@@ -177,19 +286,67 @@ func Execute(args []string, out io.Writer, errOut io.Writer) int {
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			dryRun, _ := cmd.Flags().GetBool("dry-run")
 			force, _ := cmd.Flags().GetBool("force")
+			maxAge, _ := cmd.Flags().GetString("max-age")
+			flags.NoAutoPrune = true
 			_, store, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			if maxAge != "" {
+				d, err := parseDurationFlag(maxAge)
+				if err != nil || d <= 0 {
+					return exitError{code: app.fail(*flags, fmt.Errorf("invalid max age %q: expected a positive duration such as 720h", maxAge), exitUsage)}
+				}
+				store.MaxAge = d
 			}
-			code := app.runPrune(store, mgr, flags, dryRun, force)
+			code := app.runPrune(store, mgr, *flags, dryRun, force)
 			return exitOrNil(code)
 		},
 	}
 	pruneCmd.Flags().BoolP("dry-run", "n", false, "preview")
-	pruneCmd.Flags().BoolP("force", "f", false, "force removal")
+	pruneCmd.Flags().BoolP("force", "f", false, "stop running daemons of expired profiles and remove them too")
+	pruneCmd.Flags().String("max-age", "", "also remove profiles unused for longer than this (overrides max_age)")
 	root.AddCommand(pruneCmd)
 
+	root.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show daemon and tab state",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runStatus(store, mgr, *flags)
+			return exitOrNil(code)
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "health",
+		Short: "Check that a running daemon responds",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			_, _, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runHealth(mgr, *flags)
+			return exitOrNil(code)
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "recycle",
+		Short: "Restart the browser without stopping the daemon",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runRecycle(store, mgr, *flags)
+			return exitOrNil(code)
+		},
+	})
+
 	tabCmd := &cobra.Command{
 		Use:   "tab",
 		Short: "Manage tabs",
@@ -198,17 +355,19 @@ func Execute(args []string, out io.Writer, errOut io.Writer) int {
 		Use:   "new",
 		Short: "Create a new tab",
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			url, _ := cmd.Flags().GetString("url")
+			var params daemon.TabNewParams
+			params.URL, _ = cmd.Flags().GetString("url")
+			params.Background, _ = cmd.Flags().GetBool("background")
 			_, store, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runTabNew(store, mgr, flags, url)
+			code := app.runTabNew(store, mgr, *flags, params)
 			return exitOrNil(code)
 		},
 	}
 	tabNewCmd.Flags().StringP("url", "u", "", "navigate url")
+	tabNewCmd.Flags().Bool("background", false, "keep the current tab active")
 	tabCmd.AddCommand(tabNewCmd)
 
 	tabCmd.AddCommand(&cobra.Command{
@@ -217,10 +376,9 @@ func Execute(args []string, out io.Writer, errOut io.Writer) int {
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			_, store, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runTabList(store, mgr, flags)
+			code := app.runTabList(store, mgr, *flags)
 			return exitOrNil(code)
 		},
 	})
@@ -234,10 +392,9 @@ func Execute(args []string, out io.Writer, errOut io.Writer) int {
 			}
 			_, store, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runTabClose(store, mgr, flags, flags.Tab)
+			code := app.runTabClose(store, mgr, *flags, flags.Tab)
 			return exitOrNil(code)
 		},
 	})
@@ -251,57 +408,199 @@ func Execute(args []string, out io.Writer, errOut io.Writer) int {
 			}
 			_, store, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runTabSwitch(store, mgr, flags, flags.Tab)
+			code := app.runTabSwitch(store, mgr, *flags, flags.Tab)
 			return exitOrNil(code)
 		},
 	})
 
+	tabActivateCmd := &cobra.Command{
+		Use:   "activate URL",
+		Short: "Switch to the first tab whose URL matches, or open URL in a new tab",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			params := daemon.TabActivateParams{URL: args[0]}
+			params.Match, _ = cmd.Flags().GetString("match")
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runTabActivate(store, mgr, *flags, params)
+			return exitOrNil(code)
+		},
+	}
+	tabActivateCmd.Flags().String("match", "prefix", "how tab URLs are compared: prefix, contains, or exact")
+	tabCmd.AddCommand(tabActivateCmd)
+
 	root.AddCommand(tabCmd)
 
-	root.AddCommand(&cobra.Command{
+	gotoCmd := &cobra.Command{
 		Use:   "goto URL",
 		Short: "Navigate the active tab",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			waitUntil, _ := cmd.Flags().GetString("wait")
 			_, store, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runGoto(store, mgr, flags, args[0])
+			code := app.runGoto(store, mgr, *flags, args[0], waitUntil)
 			return exitOrNil(code)
 		},
-	})
+	}
+	gotoCmd.Flags().StringP("wait", "w", "", "wait until load|domcontentloaded|networkidle")
+	gotoCmd.Flags().IntVar(&flags.Repeat, "repeat", 0, "run N times and report success counts and timing")
+	root.AddCommand(gotoCmd)
 
-	root.AddCommand(&cobra.Command{
+	clickCmd := &cobra.Command{
 		Use:   "click TEXT|SELECTOR",
 		Short: "Click an element",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			params := daemon.ClickParams{Selector: args[0]}
+			params.ExpectPopup, _ = cmd.Flags().GetBool("expect-popup")
+			params.Exact, _ = cmd.Flags().GetBool("exact")
+			if nth, _ := cmd.Flags().GetInt("nth"); nth >= 0 {
+				params.Nth = &nth
+			}
 			_, store, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runClick(store, mgr, flags, args[0])
+			code := app.runClick(store, mgr, *flags, params)
 			return exitOrNil(code)
 		},
-	})
+	}
+	clickCmd.Flags().Bool("expect-popup", false, "wait for a popup and switch to it")
+	clickCmd.Flags().Bool("exact", false, "match TEXT exactly; skip substring and role fallbacks")
+	clickCmd.Flags().Int("nth", -1, "click one of several matches (0-based; default first)")
+	clickCmd.Flags().IntVar(&flags.Repeat, "repeat", 0, "run N times and report success counts and timing")
+	root.AddCommand(clickCmd)
 
-	root.AddCommand(&cobra.Command{
+	fillCmd := &cobra.Command{
 		Use:   "fill SELECTOR VALUE",
 		Short: "Fill an input",
 		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var nth *int
+			if n, _ := cmd.Flags().GetInt("nth"); n >= 0 {
+				nth = &n
+			}
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runFill(store, mgr, *flags, args[0], args[1], nth)
+			return exitOrNil(code)
+		},
+	}
+	fillCmd.Flags().Int("nth", -1, "fill one of several matches (0-based)")
+	root.AddCommand(fillCmd)
+
+	root.AddCommand(&cobra.Command{
+		Use:   "drag FROM TO",
+		Short: "Drag one element onto another",
+		Args:  cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			_, store, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runFill(store, mgr, flags, args[0], args[1])
+			code := app.runDrag(store, mgr, *flags, args[0], args[1])
+			return exitOrNil(code)
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "focus SELECTOR",
+		Short: "Focus an element",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runFocus(store, mgr, *flags, args[0], false)
+			return exitOrNil(code)
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "blur SELECTOR",
+		Short: "Remove focus from an element, firing its blur handlers",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runFocus(store, mgr, *flags, args[0], true)
+			return exitOrNil(code)
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "mouse X Y",
+		Short: "Move the mouse to a viewport position",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runMouse(store, mgr, *flags, args[0], args[1])
+			return exitOrNil(code)
+		},
+	})
+
+	uploadCmd := &cobra.Command{
+		Use:   "upload SELECTOR PATH...",
+		Short: "Set files on an <input type=file>",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var nth *int
+			if n, _ := cmd.Flags().GetInt("nth"); n >= 0 {
+				nth = &n
+			}
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runUpload(store, mgr, *flags, args[0], args[1:], nth)
+			return exitOrNil(code)
+		},
+	}
+	uploadCmd.Flags().Int("nth", -1, "set files on one of several matches (0-based)")
+	root.AddCommand(uploadCmd)
+
+	var selectAdd bool
+	selectCmd := &cobra.Command{
+		Use:   "select SELECTOR VALUE...",
+		Short: "Select options in a <select> by value or visible text",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runSelect(store, mgr, *flags, args[0], args[1:], selectAdd)
+			return exitOrNil(code)
+		},
+	}
+	selectCmd.Flags().BoolVar(&selectAdd, "add", false, "keep existing selections (multi-select only)")
+	root.AddCommand(selectCmd)
+
+	root.AddCommand(&cobra.Command{
+		Use:   "fill-label LABEL VALUE",
+		Short: "Fill an input by its label",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runFillLabel(store, mgr, *flags, args[0], args[1])
 			return exitOrNil(code)
 		},
 	})
@@ -311,47 +610,151 @@ func Execute(args []string, out io.Writer, errOut io.Writer) int {
 		Short: "Take a screenshot",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			fullPage, _ := cmd.Flags().GetBool("full-page")
+			params := daemon.ShotParams{Path: args[0], Selector: flags.Selector}
+			params.FullPage, _ = cmd.Flags().GetBool("full-page")
+			params.Highlight, _ = cmd.Flags().GetString("highlight")
+			params.HighlightColor, _ = cmd.Flags().GetString("highlight-color")
+			params.Type, _ = cmd.Flags().GetString("type")
+			params.Stable, _ = cmd.Flags().GetBool("stable")
+			if cmd.Flags().Changed("quality") {
+				quality, _ := cmd.Flags().GetInt("quality")
+				params.Quality = &quality
+			}
+			clip, _ := cmd.Flags().GetString("clip")
+			burst, _ := cmd.Flags().GetInt("burst")
+			interval, _ := cmd.Flags().GetString("interval")
 			_, store, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runShot(store, mgr, flags, args[0], fullPage, flags.Selector)
+			code := app.runShot(store, mgr, *flags, params, clip, burst, interval)
 			return exitOrNil(code)
 		},
 	}
 	shotCmd.Flags().BoolP("full-page", "F", false, "full page")
+	shotCmd.Flags().String("highlight", "", "outline elements matching selector")
+	shotCmd.Flags().String("highlight-color", "", "highlight outline color (default red)")
+	shotCmd.Flags().String("type", "", "image type png|jpeg (default from the file extension)")
+	shotCmd.Flags().Int("quality", 0, "jpeg quality 0-100")
+	shotCmd.Flags().String("clip", "", "capture a pixel region X,Y,W,H")
+	shotCmd.Flags().Bool("stable", false, "wait for web fonts and a settled layout before capturing")
+	shotCmd.Flags().Int("burst", 0, "take N screenshots into PATH-001.png, PATH-002.png, ...")
+	shotCmd.Flags().String("interval", "", "time between burst screenshots (default 500ms)")
 	root.AddCommand(shotCmd)
 
-	root.AddCommand(&cobra.Command{
+	pdfCmd := &cobra.Command{
+		Use:   "pdf PATH",
+		Short: "Export the page as PDF",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var opts browser.PDFOptions
+			opts.Format, _ = cmd.Flags().GetString("format")
+			opts.Landscape, _ = cmd.Flags().GetBool("landscape")
+			opts.PrintBackground, _ = cmd.Flags().GetBool("print-background")
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runPDF(store, mgr, *flags, args[0], opts)
+			return exitOrNil(code)
+		},
+	}
+	pdfCmd.Flags().String("format", "", "paper format (A4|Letter)")
+	pdfCmd.Flags().Bool("landscape", false, "landscape orientation")
+	pdfCmd.Flags().Bool("print-background", false, "print background graphics")
+	root.AddCommand(pdfCmd)
+
+	extractCmd := &cobra.Command{
 		Use:   "extract",
 		Short: "Extract page info",
 		RunE: func(cmd *cobra.Command, _ []string) error {
+			format, _ := cmd.Flags().GetString("format")
 			_, store, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runExtract(store, mgr, flags)
+			code := app.runExtract(store, mgr, *flags, format)
 			return exitOrNil(code)
 		},
-	})
+	}
+	extractCmd.Flags().String("format", "", "text format (text|json|markdown)")
+	extractCmd.Flags().BoolVar(&flags.Aria, "aria", false, "include roles and accessible names of landmarks and controls")
+	extractCmd.Flags().IntVar(&flags.Repeat, "repeat", 0, "run N times and report success counts and timing")
+	extractCmd.Flags().StringVarP(&flags.Output, "output", "o", "", "write the result to PATH instead of stdout")
+	root.AddCommand(extractCmd)
 
-	root.AddCommand(&cobra.Command{
-		Use:   "read",
+	readCmd := &cobra.Command{
+		Use:   "read [URL...]",
 		Short: "Read main content",
-		RunE: func(cmd *cobra.Command, _ []string) error {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
+			urlsFile, _ := cmd.Flags().GetString("urls-file")
+			concurrency, _ := cmd.Flags().GetInt("concurrency")
+			followNext, _ := cmd.Flags().GetBool("follow-next")
+			maxPages, _ := cmd.Flags().GetInt("max")
 			flags.Main = true
 			_, store, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			if cmd.Flags().Changed("max") && !followNext {
+				return exitError{code: app.fail(*flags, errors.New("--max requires --follow-next"), exitUsage)}
+			}
+			if followNext && (len(args) > 0 || urlsFile != "") {
+				return exitError{code: app.fail(*flags, errors.New("--follow-next reads from the current page and cannot be combined with URLs"), exitUsage)}
+			}
+			if len(args) > 0 || urlsFile != "" {
+				urls, err := readURLList(urlsFile, args)
+				if err != nil {
+					return exitError{code: app.fail(*flags, err, exitUsage)}
+				}
+				if len(urls) == 0 {
+					return exitError{code: app.fail(*flags, errors.New("no URLs to read"), exitUsage)}
+				}
+				code := app.runReadURLs(store, mgr, *flags, format, urls, concurrency)
+				return exitOrNil(code)
+			}
+			pages := 1
+			if followNext {
+				pages = maxPages
 			}
-			code := app.runRead(store, mgr, flags)
+			code := app.runRead(store, mgr, *flags, format, pages)
 			return exitOrNil(code)
 		},
-	})
+	}
+	readCmd.Flags().String("format", "", "output format (text|json|markdown)")
+	readCmd.Flags().String("urls-file", "", "read each URL in FILE (one per line) and print JSON Lines")
+	readCmd.Flags().StringVarP(&flags.Output, "output", "o", "", "write the result to PATH instead of stdout")
+	readCmd.Flags().Int("concurrency", 1, "number of tabs to read URLs in parallel")
+	readCmd.Flags().Bool("follow-next", false, "follow next-page links and append their content")
+	readCmd.Flags().Int("max", 10, "maximum pages to read with --follow-next")
+	root.AddCommand(readCmd)
+
+	runCmd := &cobra.Command{
+		Use:   "run SCRIPT",
+		Short: "Run newline-delimited commands from SCRIPT (- for stdin) over one connection",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			continueOnError, _ := cmd.Flags().GetBool("continue-on-error")
+			deadline, _ := cmd.Flags().GetString("deadline")
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			lines, err := readScript(args[0], cmd.InOrStdin())
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitUsage)}
+			}
+			if len(lines) == 0 {
+				return exitError{code: app.fail(*flags, errors.New("no commands to run"), exitUsage)}
+			}
+			code := app.runScript(store, mgr, *flags, scriptFlagArgs(cmd), lines, continueOnError, deadline)
+			return exitOrNil(code)
+		},
+	}
+	runCmd.Flags().Bool("continue-on-error", false, "keep running after a command fails")
+	runCmd.Flags().String("deadline", "", "abort the whole script after DURATION and exit 4")
+	root.AddCommand(runCmd)
 
 	root.AddCommand(&cobra.Command{
 		Use:   "url",
@@ -359,10 +762,9 @@ func Execute(args []string, out io.Writer, errOut io.Writer) int {
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			_, store, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runURL(store, mgr, flags)
+			code := app.runURL(store, mgr, *flags)
 			return exitOrNil(code)
 		},
 	})
@@ -372,59 +774,365 @@ func Execute(args []string, out io.Writer, errOut io.Writer) int {
 		Short: "List visible links",
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			filter, _ := cmd.Flags().GetString("filter")
+			format, _ := cmd.Flags().GetString("format")
 			_, store, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runLinks(store, mgr, flags, filter)
+			code := app.runLinks(store, mgr, *flags, filter, format)
 			return exitOrNil(code)
 		},
 	}
 	linksCmd.Flags().StringP("filter", "f", "", "filter")
+	linksCmd.Flags().String("format", "text", "output format: text or csv")
+	linksCmd.Flags().StringVarP(&flags.Output, "output", "o", "", "write the result to PATH instead of stdout")
 	root.AddCommand(linksCmd)
 
+	tablesCmd := &cobra.Command{
+		Use:   "tables",
+		Short: "Extract tables as rows keyed by header",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			numberFields, _ := cmd.Flags().GetStringSlice("parse-number")
+			format, _ := cmd.Flags().GetString("format")
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runTables(store, mgr, *flags, numberFields, format)
+			return exitOrNil(code)
+		},
+	}
+	tablesCmd.Flags().StringSlice("parse-number", nil, "parse a column as a number into FIELD_number (repeatable)")
+	tablesCmd.Flags().String("format", "text", "output format: text or csv")
+	root.AddCommand(tablesCmd)
+
 	root.AddCommand(&cobra.Command{
+		Use:   "forms",
+		Short: "List forms with their inputs",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runForms(store, mgr, *flags)
+			return exitOrNil(code)
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "outline",
+		Short: "List visible headings as an indented outline",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runOutline(store, mgr, *flags)
+			return exitOrNil(code)
+		},
+	})
+
+	articleCmd := &cobra.Command{
+		Use:   "article",
+		Short: "Print the main content with title, byline, canonical URL, and language as JSON",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			format, _ := cmd.Flags().GetString("format")
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runArticle(store, mgr, *flags, format)
+			return exitOrNil(code)
+		},
+	}
+	articleCmd.Flags().String("format", "", "format of the text field (text|markdown)")
+	root.AddCommand(articleCmd)
+
+	contentCmd := &cobra.Command{
+		Use:   "content",
+		Short: "Print the page HTML, or one element's outerHTML with --selector",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runContent(store, mgr, *flags)
+			return exitOrNil(code)
+		},
+	}
+	contentCmd.Flags().StringVarP(&flags.Output, "output", "o", "", "write the result to PATH instead of stdout")
+	root.AddCommand(contentCmd)
+
+	root.AddCommand(&cobra.Command{
+		Use:   "box SELECTOR",
+		Short: "Print an element bounding box",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runBox(store, mgr, *flags, args[0])
+			return exitOrNil(code)
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "text SELECTOR",
+		Short: "Print the text of the first matching element",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runText(store, mgr, *flags, args[0])
+			return exitOrNil(code)
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "attr SELECTOR NAME",
+		Short: "Print an attribute of the first matching element",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runAttr(store, mgr, *flags, args[0], args[1])
+			return exitOrNil(code)
+		},
+	})
+
+	attrsCmd := &cobra.Command{
+		Use:   "attrs SELECTOR",
+		Short: "Print all attributes and text of matching elements as JSON",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nth, _ := cmd.Flags().GetInt("nth")
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runAttrs(store, mgr, *flags, args[0], nth)
+			return exitOrNil(code)
+		},
+	}
+	attrsCmd.Flags().Int("nth", -1, "pick one match (0-based)")
+	root.AddCommand(attrsCmd)
+
+	root.AddCommand(&cobra.Command{
+		Use:   "exists SELECTOR",
+		Short: "Print how many elements match; exit 3 when none",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runExists(store, mgr, *flags, args[0])
+			return exitOrNil(code)
+		},
+	})
+
+	evalCmd := &cobra.Command{
 		Use:   "eval JS",
 		Short: "Evaluate JavaScript",
 		Args:  cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			arg, _ := cmd.Flags().GetString("arg")
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runEval(store, mgr, *flags, strings.Join(args, " "), arg)
+			return exitOrNil(code)
+		},
+	}
+	evalCmd.Flags().String("arg", "", "JSON value passed as the argument when JS is a function")
+	root.AddCommand(evalCmd)
+
+	root.AddCommand(&cobra.Command{
+		Use:   "wait-idle",
+		Short: "Wait until the page has no network requests for 500ms",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runWaitIdle(store, mgr, *flags)
+			return exitOrNil(code)
+		},
+	})
+
+	waitURLCmd := &cobra.Command{
+		Use:   "wait-url PATTERN",
+		Short: "Wait until the page URL matches a glob, or a regex with --regex",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			regex, _ := cmd.Flags().GetBool("regex")
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runWaitURL(store, mgr, *flags, args[0], regex)
+			return exitOrNil(code)
+		},
+	}
+	waitURLCmd.Flags().Bool("regex", false, "treat PATTERN as a regular expression")
+	root.AddCommand(waitURLCmd)
+
+	netCmd := &cobra.Command{
+		Use:   "net",
+		Short: "Show recent network responses",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			limit, _ := cmd.Flags().GetInt("lines")
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runNet(store, mgr, *flags, limit)
+			return exitOrNil(code)
+		},
+	}
+	netCmd.Flags().IntP("lines", "n", 20, "number of entries")
+	root.AddCommand(netCmd)
+
+	downloadsCmd := &cobra.Command{
+		Use:   "downloads",
+		Short: "List files downloaded by the browser",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			limit, _ := cmd.Flags().GetInt("lines")
+			_, store, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runDownloads(store, mgr, *flags, limit)
+			return exitOrNil(code)
+		},
+	}
+	downloadsCmd.Flags().IntP("lines", "n", 20, "number of entries")
+	root.AddCommand(downloadsCmd)
+
+	cookiesCmd := &cobra.Command{
+		Use:   "cookies",
+		Short: "Manage cookies",
+	}
+	cookiesExportCmd := &cobra.Command{
+		Use:   "export PATH",
+		Short: "Export context cookies",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
 			_, store, mgr, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.fail(*flags, err, exitFailure)}
 			}
-			code := app.runEval(store, mgr, flags, strings.Join(args, " "))
+			code := app.runCookiesExport(store, mgr, *flags, format, args[0])
 			return exitOrNil(code)
 		},
+	}
+	cookiesExportCmd.Flags().String("format", "json", "output format (json|netscape)")
+	cookiesCmd.AddCommand(cookiesExportCmd)
+	root.AddCommand(cookiesCmd)
+
+	var storageSession bool
+	storageCmd := &cobra.Command{
+		Use:   "storage-item",
+		Short: "Read and write localStorage for the active tab's origin",
+	}
+	storageCmd.PersistentFlags().BoolVar(&storageSession, "session", false, "use sessionStorage instead of localStorage")
+	storageRun := func(op string, key string, value string) error {
+		_, store, mgr, err := app.prepare(flags)
+		if err != nil {
+			return exitError{code: app.fail(*flags, err, exitFailure)}
+		}
+		code := app.runStorageItem(store, mgr, *flags, daemon.StorageItemParams{Op: op, Key: key, Value: value, Session: storageSession})
+		return exitOrNil(code)
+	}
+	storageCmd.AddCommand(&cobra.Command{
+		Use:   "get KEY",
+		Short: "Print a storage value (exit 3 when missing)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return storageRun("get", args[0], "")
+		},
+	})
+	storageCmd.AddCommand(&cobra.Command{
+		Use:   "set KEY VALUE",
+		Short: "Set a storage value",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return storageRun("set", args[0], args[1])
+		},
+	})
+	storageCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List storage keys and values",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return storageRun("list", "", "")
+		},
 	})
+	root.AddCommand(storageCmd)
+
+	var logLines int
+	logsCmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Print the tail of the profile daemon log",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			_, _, mgr, err := app.prepare(flags)
+			if err != nil {
+				return exitError{code: app.fail(*flags, err, exitFailure)}
+			}
+			code := app.runLogs(mgr, *flags, logLines)
+			return exitOrNil(code)
+		},
+	}
+	logsCmd.Flags().IntVarP(&logLines, "lines", "n", 50, "number of lines to show (0 for all)")
+	root.AddCommand(logsCmd)
 
 	serveCmd := &cobra.Command{
 		Use:    "serve",
 		Short:  "Internal daemon entrypoint",
 		Hidden: true,
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			_, store, _, err := app.prepare(flags)
+			cfg, store, _, err := app.prepare(flags)
 			if err != nil {
-				fmt.Fprintln(errOut, err)
-				return exitError{code: exitFailure}
+				return exitError{code: app.failServe(flags.ProfileDir, *flags, err, exitFailure)}
 			}
-			code := app.runServe(store, flags)
+			code := app.runServe(cfg, store, *flags)
 			return exitOrNil(code)
 		},
 	}
+	serveCmd.Flags().BoolVar(&flags.NoDefaultTab, "no-default-tab", false, "start without opening a tab")
+	serveCmd.Flags().BoolVar(&flags.Incognito, "incognito", false, "skip loading and saving storage state")
+	serveCmd.Flags().StringVar(&flags.SocketMode, "socket-mode", "", "daemon socket permissions")
+	serveCmd.Flags().StringVar(&flags.ProfileMode, "profile-mode", "", "profile directory permissions")
 	root.AddCommand(serveCmd)
 
-	root.SetArgs(args)
-	if err := root.Execute(); err != nil {
-		var exit exitError
-		if errors.As(err, &exit) {
-			return exit.code
+	return root
+}
+
+// scriptFlagArgs returns the global flags set on www run as arguments for
+// each script line, leaving out those runScript passes itself and those that
+// only make sense once per run.
+func scriptFlagArgs(cmd *cobra.Command) []string {
+	var args []string
+	cmd.Root().PersistentFlags().Visit(func(f *pflag.Flag) {
+		switch f.Name {
+		case "profile", "profile-dir", "json", "envelope", "timeout", "selector-timeout", "tab", "fresh", "version":
+			return
 		}
-		fmt.Fprintln(errOut, err)
-		return exitUsage
-	}
-	return exitSuccess
+		if values, ok := f.Value.(pflag.SliceValue); ok {
+			for _, value := range values.GetSlice() {
+				args = append(args, "--"+f.Name+"="+value)
+			}
+			return
+		}
+		args = append(args, "--"+f.Name+"="+f.Value.String())
+	})
+	return args
 }
 
 func exitOrNil(code int) error {
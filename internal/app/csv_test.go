@@ -0,0 +1,30 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	var out bytes.Buffer
+	rows := [][]string{{"Docs, guides", "https://example.com/docs"}, {`Say "hi"`, "https://example.com/hi"}}
+	if err := writeCSV(&out, []string{"text", "href"}, rows); err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+	want := "text,href\n\"Docs, guides\",https://example.com/docs\n\"Say \"\"hi\"\"\",https://example.com/hi\n"
+	if out.String() != want {
+		t.Fatalf("unexpected csv:\n%s", out.String())
+	}
+}
+
+func TestValidateRowFormat(t *testing.T) {
+	if err := validateRowFormat("csv", GlobalFlags{}); err != nil {
+		t.Fatalf("expected csv to be valid: %v", err)
+	}
+	if err := validateRowFormat("csv", GlobalFlags{JSON: true}); err == nil {
+		t.Fatalf("expected csv with --json to fail")
+	}
+	if err := validateRowFormat("tsv", GlobalFlags{}); err == nil {
+		t.Fatalf("expected unknown format to fail")
+	}
+}
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/patrickjm/www/internal/browser"
+	"github.com/patrickjm/www/internal/daemon"
+	"github.com/patrickjm/www/internal/profile"
+)
+
+// startAppDaemon serves a fake "demo" profile under a temp root and records
+// it with the manager, so commands find it already running. The browser is
+// started before serving, so tests can use the fake's pages right away. The
+// daemon is stopped when the test ends; the returned channel receives its
+// exit error.
+func startAppDaemon(t *testing.T, engine *browser.FakeEngine) (profile.Store, daemon.Manager, <-chan error) {
+	t.Helper()
+	root := t.TempDir()
+	store := profile.Store{Root: root}
+	mgr := daemon.Manager{ProfileDir: root}
+	if _, _, err := store.Upsert("demo", profile.Overrides{}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	socket := mgr.SocketPath("demo")
+	server := daemon.NewServer("demo", engine, "")
+	if err := server.Init(browser.StartOptions{}); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	errCh := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		errCh <- server.Serve(l)
+		close(done)
+	}()
+	if err := mgr.SaveInfo("demo", daemon.Info{PID: os.Getpid(), Socket: socket}); err != nil {
+		t.Fatalf("save info: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = mgr.Stop("demo")
+		<-done
+	})
+	return store, mgr, errCh
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/patrickjm/www/internal/daemon"
+	"github.com/patrickjm/www/internal/profile"
+)
+
+func TestRunStartRejectsUnknownDeviceBeforeSaving(t *testing.T) {
+	orig := validateDevice
+	validateDevice = func(name string) error {
+		return errors.New(`unknown device "iPhone 31". did you mean "iPhone 13"?`)
+	}
+	t.Cleanup(func() { validateDevice = orig })
+
+	store := profile.Store{Root: t.TempDir()}
+	mgr := daemon.Manager{ProfileDir: store.Root}
+	var stderr bytes.Buffer
+	a := App{Out: &bytes.Buffer{}, Err: &stderr}
+	code := a.runStart(store, mgr, GlobalFlags{Profile: "demo", Device: "iPhone 31"}, nil, nil, false, "")
+	if code != exitUsage {
+		t.Fatalf("expected exit %d, got %d (%s)", exitUsage, code, stderr.String())
+	}
+	if _, err := store.Load("demo"); !os.IsNotExist(err) {
+		t.Fatalf("expected profile not to be saved, got %v", err)
+	}
+}
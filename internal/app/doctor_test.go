@@ -0,0 +1,41 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInstalledBrowsers(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"chromium-1140", "ffmpeg-1010", "webkit-2035"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	browsers := installedBrowsers(dir)
+	if !browsers["chromium"] || browsers["firefox"] || !browsers["webkit"] {
+		t.Fatalf("unexpected browsers: %v", browsers)
+	}
+}
+
+func TestDoctorEvaluate(t *testing.T) {
+	all := map[string]bool{"chromium": true, "firefox": true, "webkit": true}
+	partial := map[string]bool{"chromium": true}
+
+	res := doctorResult{ProfileDirWritable: true, PlaywrightOK: true, Browsers: all}
+	if code := res.evaluate(true); code != exitSuccess || !res.OK {
+		t.Fatalf("expected healthy result, got code %d %+v", code, res)
+	}
+	res = doctorResult{ProfileDirWritable: true, PlaywrightOK: false, Browsers: all}
+	if code := res.evaluate(false); code != exitFailure || res.OK {
+		t.Fatalf("expected failure without playwright, got code %d", code)
+	}
+	res = doctorResult{ProfileDirWritable: true, PlaywrightOK: true, Browsers: partial}
+	if code := res.evaluate(false); code != exitSuccess || len(res.Warnings) != 2 {
+		t.Fatalf("expected warnings without failure, got code %d %+v", code, res)
+	}
+	if code := res.evaluate(true); code != exitFailure || res.OK {
+		t.Fatalf("expected --strict to fail on warnings, got code %d", code)
+	}
+}
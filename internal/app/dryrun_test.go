@@ -0,0 +1,36 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/patrickjm/www/internal/browser"
+	"github.com/patrickjm/www/internal/daemon"
+)
+
+func TestTabNewDryRun(t *testing.T) {
+	engine := &browser.FakeEngine{Session: &browser.FakeSession{}}
+	store, mgr, _ := startAppDaemon(t, engine)
+	var out bytes.Buffer
+	a := App{Out: &out, Err: &bytes.Buffer{}}
+	flags := GlobalFlags{Profile: "demo", DryRun: true}
+	if code := a.runTabNew(store, mgr, flags, daemon.TabNewParams{URL: "https://example.com"}); code != exitSuccess {
+		t.Fatalf("expected success, got %d", code)
+	}
+	if !strings.Contains(out.String(), `"method":"TabNew"`) {
+		t.Fatalf("expected the request to be printed, got %q", out.String())
+	}
+	client, err := daemon.NewClient(mgr.SocketPath("demo"))
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	defer client.Close()
+	tabs, err := client.TabList()
+	if err != nil {
+		t.Fatalf("tab list: %v", err)
+	}
+	if len(tabs) != 1 {
+		t.Fatalf("expected dry run to leave a single tab, got %d", len(tabs))
+	}
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/patrickjm/www/internal/browser"
+	"github.com/patrickjm/www/internal/daemon"
+)
+
+func TestPrintJSONEnvelope(t *testing.T) {
+	var out bytes.Buffer
+	a := App{Out: &out}
+	flags := GlobalFlags{JSON: true, Envelope: true, Command: "links", Profile: "demo"}
+	a.printJSON(flags, []string{"https://example.com"})
+	var env struct {
+		Command string   `json:"command"`
+		Profile string   `json:"profile"`
+		OK      bool     `json:"ok"`
+		Data    []string `json:"data"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &env); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if env.Command != "links" || env.Profile != "demo" || !env.OK || len(env.Data) != 1 {
+		t.Fatalf("unexpected envelope: %+v", env)
+	}
+}
+
+func TestPrintJSONRawWithoutEnvelope(t *testing.T) {
+	var out bytes.Buffer
+	a := App{Out: &out}
+	a.printJSON(GlobalFlags{JSON: true}, json.RawMessage(`{"text":"hi"}`))
+	if got := out.String(); got != "{\"text\":\"hi\"}\n" {
+		t.Fatalf("expected raw passthrough, got %q", got)
+	}
+}
+
+func TestActionFailedEnvelope(t *testing.T) {
+	var out bytes.Buffer
+	a := App{Out: &out}
+	a.actionFailed(GlobalFlags{JSON: true, Envelope: true, Command: "click"}, errors.New("boom"), exitFailure)
+	var env map[string]any
+	if err := json.Unmarshal(out.Bytes(), &env); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if env["command"] != "click" || env["ok"] != false || env["error"] != "boom" {
+		t.Fatalf("unexpected envelope: %v", env)
+	}
+}
+
+func TestFailJSON(t *testing.T) {
+	var errOut bytes.Buffer
+	a := App{Err: &errOut}
+	_, dialErr := net.Dial("unix", "/nonexistent/daemon.sock")
+	cases := []struct {
+		err  error
+		exit int
+		code string
+	}{
+		{errors.New("-p/--profile is required"), exitUsage, "usage"},
+		{fmt.Errorf("demo is %w", errNotRunning), exitFailure, "not_running"},
+		{dialErr, exitFailure, "not_running"},
+		{&daemon.RemoteError{Message: "tab not found", Code: daemon.CodeTabNotFound}, exitFailure, "tab_not_found"},
+		{fmt.Errorf("%w for #q", browser.ErrNoMatch), exitNotFound, "selector_no_match"},
+		{errors.New("boom"), exitFailure, "error"},
+	}
+	for _, tc := range cases {
+		errOut.Reset()
+		if got := a.fail(GlobalFlags{JSON: true}, tc.err, tc.exit); got != tc.exit {
+			t.Fatalf("expected exit %d, got %d", tc.exit, got)
+		}
+		var body jsonError
+		if err := json.Unmarshal(errOut.Bytes(), &body); err != nil {
+			t.Fatalf("unmarshal %q: %v", errOut.String(), err)
+		}
+		if body.Error.Code != tc.code || body.Error.Message != tc.err.Error() {
+			t.Fatalf("expected code %s for %v, got %+v", tc.code, tc.err, body)
+		}
+	}
+	errOut.Reset()
+	a.fail(GlobalFlags{}, errors.New("boom"), exitFailure)
+	if errOut.String() != "boom\n" {
+		t.Fatalf("expected plain error without --json, got %q", errOut.String())
+	}
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickjm/www/internal/browser"
+)
+
+func TestEnsureRunningFreshStopsOldDaemon(t *testing.T) {
+	_, mgr, errCh := startAppDaemon(t, &browser.FakeEngine{})
+	var errOut bytes.Buffer
+	a := App{Out: &bytes.Buffer{}, Err: &errOut}
+	err := a.ensureRunning(mgr, "demo", GlobalFlags{Fresh: true, NoStart: true})
+	if err == nil {
+		t.Fatalf("expected no-start error after stopping")
+	}
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("server error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected old daemon to stop")
+	}
+	if _, err := os.Stat(mgr.InfoPath("demo")); !os.IsNotExist(err) {
+		t.Fatalf("expected daemon info to be cleaned up, got %v", err)
+	}
+}
+
+func TestEnsureRunningFreshKeepsStartOptions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the daemon binary")
+	}
+	_, mgr, _ := startAppDaemon(t, &browser.FakeEngine{})
+	info, err := mgr.LoadInfo("demo")
+	if err != nil {
+		t.Fatalf("load info: %v", err)
+	}
+	info.NoDefaultTab = true
+	if err := mgr.SaveInfo("demo", info); err != nil {
+		t.Fatalf("save info: %v", err)
+	}
+	// The stand-in binary records its arguments and reports a startup error,
+	// so Start returns without waiting for a socket.
+	argsPath := filepath.Join(t.TempDir(), "args")
+	script := filepath.Join(t.TempDir(), "www")
+	body := "#!/bin/sh\necho \"$@\" > " + argsPath + "\necho fake > " + mgr.StartupErrorPath("demo") + "\n"
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatalf("write script: %v", err)
+	}
+	mgr.BinaryPath = script
+	a := App{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}
+	if err := a.ensureRunning(mgr, "demo", GlobalFlags{Fresh: true}); err == nil {
+		t.Fatalf("expected the stand-in daemon to report a startup error")
+	}
+	args, err := os.ReadFile(argsPath)
+	if err != nil {
+		t.Fatalf("read args: %v", err)
+	}
+	if !strings.Contains(string(args), "--no-default-tab") {
+		t.Fatalf("expected restart to keep --no-default-tab, got %q", args)
+	}
+}
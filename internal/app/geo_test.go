@@ -0,0 +1,21 @@
+package app
+
+import "testing"
+
+func TestParseGeo(t *testing.T) {
+	geo, err := parseGeo("52.52, 13.405")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if geo.Latitude != 52.52 || geo.Longitude != 13.405 {
+		t.Fatalf("unexpected geo: %+v", geo)
+	}
+	if got := formatGeo(*geo); got != "52.52,13.405" {
+		t.Fatalf("unexpected format: %s", got)
+	}
+	for _, value := range []string{"52.52", "north,13", "91,0", "0,-181", "NaN,0", "0,nan", "Inf,0", "0,-Inf"} {
+		if _, err := parseGeo(value); err == nil {
+			t.Fatalf("expected error for %q", value)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+//go:build !windows
+
+package app
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/patrickjm/www/internal/daemon"
+	"github.com/patrickjm/www/internal/profile"
+)
+
+func TestRemoveGlob(t *testing.T) {
+	root := t.TempDir()
+	store := profile.Store{Root: root}
+	mgr := daemon.Manager{ProfileDir: root}
+	for _, name := range []string{"test-1", "test-2", "work"} {
+		if _, _, err := store.Upsert(name, profile.Overrides{}); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+	}
+	var out, errOut bytes.Buffer
+	a := App{Out: &out, Err: &errOut}
+
+	if code := a.runRemove(store, mgr, GlobalFlags{}, []string{"nope-*"}, false); code != exitNotFound {
+		t.Fatalf("expected exit %d for no match, got %d", exitNotFound, code)
+	}
+	if code := a.runRemove(store, mgr, GlobalFlags{}, []string{"test-*"}, false); code != exitUsage {
+		t.Fatalf("expected exit %d without --force, got %d", exitUsage, code)
+	}
+	if !strings.Contains(errOut.String(), "matches 2 profiles (test-1, test-2)") {
+		t.Fatalf("unexpected stderr: %q", errOut.String())
+	}
+	if _, err := os.Stat(store.ProfilePath("test-1")); err != nil {
+		t.Fatalf("expected profiles to survive without --force: %v", err)
+	}
+
+	if code := a.runRemove(store, mgr, GlobalFlags{}, []string{"test-?", "test-1"}, true); code != exitSuccess {
+		t.Fatalf("expected removal with --force, got %d: %s", code, errOut.String())
+	}
+	if out.String() != "removed test-1\nremoved test-2\n" {
+		t.Fatalf("unexpected stdout: %q", out.String())
+	}
+	if _, err := os.Stat(store.ProfilePath("work")); err != nil {
+		t.Fatalf("expected unmatched profile to survive: %v", err)
+	}
+
+	out.Reset()
+	if code := a.runRemove(store, mgr, GlobalFlags{}, []string{"w*"}, false); code != exitSuccess || out.String() != "removed work\n" {
+		t.Fatalf("expected a single match to need no --force, got %d %q", code, out.String())
+	}
+}
+
+func TestStopGlobNoMatch(t *testing.T) {
+	root := t.TempDir()
+	store := profile.Store{Root: root}
+	mgr := daemon.Manager{ProfileDir: root}
+	if _, _, err := store.Upsert("test-1", profile.Overrides{}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	var errOut bytes.Buffer
+	a := App{Out: &bytes.Buffer{}, Err: &errOut}
+	if code := a.runStop(store, mgr, GlobalFlags{Profile: "other-*"}, false); code != exitNotFound {
+		t.Fatalf("expected exit %d, got %d", exitNotFound, code)
+	}
+	if code := a.runStop(store, mgr, GlobalFlags{Profile: "test-*"}, false); code != exitNotFound {
+		t.Fatalf("expected exit %d for stopped matches, got %d", exitNotFound, code)
+	}
+	if !strings.Contains(errOut.String(), `no running profiles match "test-*"`) {
+		t.Fatalf("unexpected stderr: %q", errOut.String())
+	}
+	if code := a.runStop(store, mgr, GlobalFlags{Profile: "test-["}, false); code != exitUsage {
+		t.Fatalf("expected invalid pattern to fail, got %d", code)
+	}
+}
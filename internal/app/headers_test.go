@@ -0,0 +1,18 @@
+package app
+
+import "testing"
+
+func TestParseHeaders(t *testing.T) {
+	got, err := parseHeaders([]string{"X-Test: 1", "Authorization:Bearer a:b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got["X-Test"] != "1" || got["Authorization"] != "Bearer a:b" {
+		t.Fatalf("unexpected headers: %v", got)
+	}
+	for _, value := range []string{"X-Test", ": v", "Bad Name: v"} {
+		if _, err := parseHeaders([]string{value}); err == nil {
+			t.Fatalf("expected error for %q", value)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+//go:build !windows
+
+package app
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickjm/www/internal/daemon"
+	"github.com/patrickjm/www/internal/profile"
+)
+
+func TestRemoveRefusesProfileInUse(t *testing.T) {
+	root := t.TempDir()
+	store := profile.Store{Root: root}
+	mgr := daemon.Manager{ProfileDir: root}
+	if _, _, err := store.Upsert("demo", profile.Overrides{}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	lock, err := store.LockShared("demo")
+	if err != nil {
+		t.Fatalf("shared lock: %v", err)
+	}
+	var out, errOut bytes.Buffer
+	a := App{Out: &out, Err: &errOut}
+	if code := a.runRemove(store, mgr, GlobalFlags{}, []string{"demo"}, false); code != exitFailure {
+		t.Fatalf("expected exit %d, got %d", exitFailure, code)
+	}
+	if !strings.Contains(errOut.String(), "in use") {
+		t.Fatalf("unexpected stderr: %q", errOut.String())
+	}
+	if _, err := os.Stat(store.ProfilePath("demo")); err != nil {
+		t.Fatalf("expected profile to survive: %v", err)
+	}
+	_ = lock.Unlock()
+	if code := a.runRemove(store, mgr, GlobalFlags{Quiet: true}, []string{"demo"}, false); code != exitSuccess {
+		t.Fatalf("expected removal after unlock, got %d: %s", code, errOut.String())
+	}
+}
+
+func TestPruneForceSkipsProfileInUse(t *testing.T) {
+	root := t.TempDir()
+	store := profile.Store{Root: root, DefaultTTL: time.Second}
+	mgr := daemon.Manager{ProfileDir: root}
+	for _, name := range []string{"busy", "idle"} {
+		p, _, err := store.Upsert(name, profile.Overrides{})
+		if err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+		p.LastUsed = time.Now().UTC().Add(-time.Hour)
+		if err := store.Save(p); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+	lock, err := store.LockShared("busy")
+	if err != nil {
+		t.Fatalf("shared lock: %v", err)
+	}
+	defer lock.Unlock()
+	idle, err := store.LockShared("idle")
+	if err != nil {
+		t.Fatalf("create idle lock file: %v", err)
+	}
+	_ = idle.Unlock()
+	var out, errOut bytes.Buffer
+	a := App{Out: &out, Err: &errOut}
+	if code := a.runPrune(store, mgr, GlobalFlags{}, false, true); code != exitSuccess {
+		t.Fatalf("expected exit %d, got %d: %s", exitSuccess, code, errOut.String())
+	}
+	if _, err := os.Stat(store.ProfilePath("busy")); err != nil {
+		t.Fatalf("expected busy profile to survive --force: %v", err)
+	}
+	if !strings.Contains(errOut.String(), "skipped busy: in use") {
+		t.Fatalf("unexpected stderr: %q", errOut.String())
+	}
+	if out.String() != "pruned idle\n" {
+		t.Fatalf("unexpected stdout: %q", out.String())
+	}
+	if _, err := os.Stat(store.LockPath("idle")); err != nil {
+		t.Fatalf("expected idle lock file to be kept: %v", err)
+	}
+}
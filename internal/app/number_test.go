@@ -0,0 +1,55 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/patrickjm/www/internal/browser"
+)
+
+func TestParseLocaleNumber(t *testing.T) {
+	cases := map[string]float64{
+		"1.234,56 €": 1234.56,
+		"$1,234.56":  1234.56,
+		"12,5":       12.5,
+		"3.14":       3.14,
+		"1,234":      1234,
+		"1.234.567":  1234567,
+		"-42":        -42,
+		"(7.50)":     -7.5,
+		"CHF 1'299":  1299,
+		"0.125":      0.125,
+		"$0.999":     0.999,
+		"0,125 €":    0.125,
+		".5":         0.5,
+		"1 234 kg":   1234,
+		"12%":        12,
+	}
+	for input, want := range cases {
+		got, ok := parseLocaleNumber(input)
+		if !ok || got != want {
+			t.Fatalf("parseLocaleNumber(%q) = %v, %v; want %v", input, got, ok, want)
+		}
+	}
+	for _, input := range []string{"n/a", "5-10", "2024-05-01", "3 of 4", ""} {
+		if got, ok := parseLocaleNumber(input); ok {
+			t.Fatalf("parseLocaleNumber(%q) = %v; want failure", input, got)
+		}
+	}
+}
+
+func TestParseTableNumbers(t *testing.T) {
+	tables := []browser.ExtractTable{{
+		Headers: []string{"Item", "Price"},
+		Rows:    []map[string]string{{"Item": "Tea", "Price": "4,20 €"}, {"Item": "Cake", "Price": "—"}},
+	}}
+	got := parseTableNumbers(tables, []string{"price"})
+	if len(got[0].Headers) != 3 || got[0].Headers[2] != "Price_number" {
+		t.Fatalf("unexpected headers: %v", got[0].Headers)
+	}
+	if got[0].Rows[0]["Price"] != "4,20 €" || got[0].Rows[0]["Price_number"] != 4.2 {
+		t.Fatalf("unexpected row: %v", got[0].Rows[0])
+	}
+	if v, ok := got[0].Rows[1]["Price_number"]; !ok || v != nil {
+		t.Fatalf("expected null for unparsable price, got %v", got[0].Rows[1])
+	}
+}
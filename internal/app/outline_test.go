@@ -0,0 +1,23 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/patrickjm/www/internal/browser"
+)
+
+func TestRenderOutline(t *testing.T) {
+	got := renderOutline([]browser.Heading{
+		{Level: 2, Text: "Guide"},
+		{Level: 3, Text: "Install"},
+		{Level: 4, Text: "macOS"},
+		{Level: 2, Text: "FAQ"},
+	})
+	want := "Guide\n  Install\n    macOS\nFAQ\n"
+	if got != want {
+		t.Fatalf("unexpected outline:\n%s", got)
+	}
+	if got := renderOutline(nil); got != "" {
+		t.Fatalf("expected empty outline, got %q", got)
+	}
+}
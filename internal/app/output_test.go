@@ -0,0 +1,42 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteOutput(t *testing.T) {
+	var out bytes.Buffer
+	a := App{Out: &out}
+	for _, path := range []string{"", "-"} {
+		out.Reset()
+		code := a.writeOutput(GlobalFlags{Output: path}, func(a App) {
+			fmt.Fprintln(a.Out, "hello")
+		})
+		if code != exitSuccess || out.String() != "hello\n" {
+			t.Fatalf("expected stdout for %q, got code %d %q", path, code, out.String())
+		}
+	}
+
+	out.Reset()
+	path := filepath.Join(t.TempDir(), "pages", "one.json")
+	code := a.writeOutput(GlobalFlags{Output: path}, func(a App) {
+		a.printJSON(GlobalFlags{}, map[string]string{"title": "One"})
+	})
+	if code != exitSuccess {
+		t.Fatalf("expected success, got %d", code)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("expected nothing on stdout, got %q", out.String())
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if string(b) != "{\n  \"title\": \"One\"\n}\n" {
+		t.Fatalf("unexpected file contents: %q", b)
+	}
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckProfileDirCreates(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested", "profiles")
+	if err := checkProfileDir(dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Fatalf("expected dir to be created")
+	}
+}
+
+func TestCheckProfileDirNotADirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	err := checkProfileDir(path)
+	if err == nil || !strings.Contains(err.Error(), "not a directory") || !strings.Contains(err.Error(), path) {
+		t.Fatalf("expected not a directory error naming path, got %v", err)
+	}
+}
+
+func TestCheckProfileDirReadOnly(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permission checks do not apply to root")
+	}
+	parent := t.TempDir()
+	if err := os.Chmod(parent, 0o555); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(parent, 0o755) })
+	err := checkProfileDir(parent)
+	if err == nil || !strings.Contains(err.Error(), "permission denied") || !strings.Contains(err.Error(), parent) {
+		t.Fatalf("expected permission denied error naming path, got %v", err)
+	}
+	err = checkProfileDir(filepath.Join(parent, "child"))
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("expected permission denied error, got %v", err)
+	}
+}
+
+func TestPrepareModeFlags(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", filepath.Join(dir, "home"))
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("WWW_PROFILE_DIR", "")
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte("profile_dir = \""+filepath.Join(dir, "profiles")+"\"\nsocket_mode = \"0600\"\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	a := App{Out: &strings.Builder{}, Err: &strings.Builder{}}
+	flags := GlobalFlags{Config: path, NoAutoPrune: true, SocketMode: "0660", ProfileMode: "0750"}
+	cfg, _, mgr, err := a.prepare(&flags)
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	if cfg.SocketMode != 0o660 || cfg.ProfileMode != 0o750 {
+		t.Fatalf("expected flags to override config, got %o %o", cfg.SocketMode, cfg.ProfileMode)
+	}
+	if mgr.SocketMode != "0660" || mgr.ProfileMode != "0750" {
+		t.Fatalf("expected manager to pass modes to serve, got %q %q", mgr.SocketMode, mgr.ProfileMode)
+	}
+	flags.SocketMode = "rw"
+	if _, _, _, err := a.prepare(&flags); err == nil || !strings.Contains(err.Error(), "--socket-mode") {
+		t.Fatalf("expected --socket-mode error, got %v", err)
+	}
+}
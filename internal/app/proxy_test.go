@@ -0,0 +1,21 @@
+package app
+
+import "testing"
+
+func TestParseProxy(t *testing.T) {
+	got, err := parseProxy("proxy.local:3128")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "http://proxy.local:3128" {
+		t.Fatalf("expected http scheme to be added, got %s", got)
+	}
+	if _, err := parseProxy("socks5://user:pw@proxy.local:1080"); err != nil {
+		t.Fatalf("unexpected error for socks5: %v", err)
+	}
+	for _, value := range []string{"ftp://proxy.local:21", "http://"} {
+		if _, err := parseProxy(value); err == nil {
+			t.Fatalf("expected error for %q", value)
+		}
+	}
+}
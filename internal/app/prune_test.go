@@ -0,0 +1,73 @@
+//go:build !windows
+
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/patrickjm/www/internal/profile"
+)
+
+func TestPrepareAutoPrune(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "profiles")
+	t.Setenv("HOME", filepath.Join(dir, "home"))
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("WWW_PROFILE_DIR", "")
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte("profile_dir = \""+root+"\"\nauto_prune = true\nmax_age = \"1h\"\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	store := profile.Store{Root: root}
+	for _, name := range []string{"stale", "busy", "current", "fresh"} {
+		p, _, err := store.Upsert(name, profile.Overrides{})
+		if err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+		if name != "fresh" {
+			p.LastUsed = time.Now().UTC().Add(-2 * time.Hour)
+			if err := store.Save(p); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+		}
+	}
+	lock, err := store.LockShared("busy")
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	defer lock.Unlock()
+
+	a := App{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}
+	flags := GlobalFlags{Config: path, Profile: "current", NoAutoPrune: true}
+	if _, _, _, err := a.prepare(&flags); err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	if _, err := os.Stat(store.ProfilePath("stale")); err != nil {
+		t.Fatalf("expected no pruning with NoAutoPrune: %v", err)
+	}
+
+	flags.NoAutoPrune = false
+	if _, _, _, err := a.prepare(&flags); err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		_, err := os.Stat(store.ProfileDir("stale"))
+		if os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected stale profile to be pruned, got %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, name := range []string{"busy", "current", "fresh"} {
+		if _, err := os.Stat(store.ProfilePath(name)); err != nil {
+			t.Fatalf("expected %s to survive: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/patrickjm/www/internal/browser"
+	"github.com/patrickjm/www/internal/daemon"
+)
+
+func TestReadURLList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.txt")
+	if err := os.WriteFile(path, []byte("https://a.example\n\n# skip\n  https://b.example  \n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	urls, err := readURLList(path, []string{"https://first.example"})
+	if err != nil {
+		t.Fatalf("read list: %v", err)
+	}
+	if strings.Join(urls, ",") != "https://first.example,https://a.example,https://b.example" {
+		t.Fatalf("unexpected urls: %v", urls)
+	}
+	if _, err := readURLList(filepath.Join(t.TempDir(), "missing"), nil); err == nil {
+		t.Fatalf("expected error for missing file")
+	}
+}
+
+func TestNextPageURL(t *testing.T) {
+	result := browser.ExtractResult{Links: []browser.ExtractLink{
+		{Text: "Nextcloud", Href: "https://nextcloud.example"},
+		{Text: "Next →", Href: "https://docs.example/2"},
+		{Text: "Continue", Href: "https://docs.example/3", Rel: "Next"},
+	}}
+	if got := nextPageURL(result, map[string]bool{}); got != "https://docs.example/3" {
+		t.Fatalf("expected rel=next link, got %q", got)
+	}
+	visited := map[string]bool{"https://docs.example/3": true}
+	if got := nextPageURL(result, visited); got != "https://docs.example/2" {
+		t.Fatalf("expected next text link, got %q", got)
+	}
+	visited[pageKey("https://docs.example/2#top")] = true
+	if got := nextPageURL(result, visited); got != "" {
+		t.Fatalf("expected no next page, got %q", got)
+	}
+	result.Links = []browser.ExtractLink{{Text: "Next", Href: "javascript:void(0)"}}
+	if got := nextPageURL(result, map[string]bool{}); got != "" {
+		t.Fatalf("expected non-http link to be ignored, got %q", got)
+	}
+}
+
+func TestRunExtractTextFormat(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	store, mgr, _ := startAppDaemon(t, engine)
+	engine.Session.Pages[0].ExtractRes = browser.ExtractResult{URL: "https://a.example", Text: "Hello there"}
+
+	var out bytes.Buffer
+	a := App{Out: &out, Err: &bytes.Buffer{}}
+	if code := a.runExtract(store, mgr, GlobalFlags{Profile: "demo"}, "text"); code != exitSuccess {
+		t.Fatalf("expected success, got %d", code)
+	}
+	if out.String() != "Hello there\n" {
+		t.Fatalf("expected only the text, got %q", out.String())
+	}
+	out.Reset()
+	if code := a.runExtract(store, mgr, GlobalFlags{Profile: "demo"}, ""); code != exitSuccess {
+		t.Fatalf("expected success, got %d", code)
+	}
+	var parsed browser.ExtractResult
+	if err := json.Unmarshal(out.Bytes(), &parsed); err != nil || parsed.Text != "Hello there" {
+		t.Fatalf("expected JSON by default, got %q (%v)", out.String(), err)
+	}
+}
+
+func TestRunReadFollowsNextPages(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	store, mgr, _ := startAppDaemon(t, engine)
+	page := engine.Session.Pages[0]
+	page.ExtractByURL = map[string]browser.ExtractResult{
+		"https://docs.example/1": {URL: "https://docs.example/1", Text: "one", Links: []browser.ExtractLink{
+			{Text: "Next", Href: "https://docs.example/2"},
+		}},
+		"https://docs.example/2": {URL: "https://docs.example/2", Text: "two", Links: []browser.ExtractLink{
+			{Text: "Next", Href: "https://docs.example/1#top"},
+		}},
+	}
+
+	if err := page.Goto("https://docs.example/1", browser.GotoOptions{}); err != nil {
+		t.Fatalf("goto: %v", err)
+	}
+
+	var out bytes.Buffer
+	a := App{Out: &out, Err: &bytes.Buffer{}}
+	if code := a.runRead(store, mgr, GlobalFlags{Profile: "demo"}, "json", 10); code != exitSuccess {
+		t.Fatalf("expected success, got %d", code)
+	}
+	var results []browser.ExtractResult
+	if err := json.Unmarshal(out.Bytes(), &results); err != nil {
+		t.Fatalf("decode: %v (%q)", err, out.String())
+	}
+	if len(results) != 2 || results[0].Text != "one" || results[1].Text != "two" {
+		t.Fatalf("expected two pages then a stop on the repeated URL, got %+v", results)
+	}
+	if url, _ := page.URL(); url != "https://docs.example/2" {
+		t.Fatalf("expected no navigation back to a read page, got %q", url)
+	}
+}
+
+func TestRunReadURLs(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	store, mgr, _ := startAppDaemon(t, engine)
+
+	var out bytes.Buffer
+	a := App{Out: &out, Err: &bytes.Buffer{}}
+	urls := []string{"https://a.example", "https://b.example", "https://c.example"}
+	code := a.runReadURLs(store, mgr, GlobalFlags{Profile: "demo", Main: true}, "", urls, 2)
+	if code != exitSuccess {
+		t.Fatalf("expected success, got %d", code)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 JSON lines, got %q", out.String())
+	}
+	seen := []string{}
+	for _, line := range lines {
+		var res readURLResult
+		if err := json.Unmarshal([]byte(line), &res); err != nil {
+			t.Fatalf("decode %q: %v", line, err)
+		}
+		if res.Error != "" {
+			t.Fatalf("unexpected error for %s: %s", res.URL, res.Error)
+		}
+		seen = append(seen, res.URL)
+	}
+	sort.Strings(seen)
+	if strings.Join(seen, ",") != strings.Join(urls, ",") {
+		t.Fatalf("unexpected urls: %v", seen)
+	}
+	client, err := daemon.NewClient(mgr.SocketPath("demo"))
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	defer client.Close()
+	tabs, err := client.TabList()
+	if err != nil {
+		t.Fatalf("tab list: %v", err)
+	}
+	if len(tabs) != 1 {
+		t.Fatalf("expected extra tabs to be closed, got %d", len(tabs))
+	}
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRepeatStats(t *testing.T) {
+	var stats repeatStats
+	stats.add(10*time.Millisecond, nil)
+	stats.add(30*time.Millisecond, errors.New("boom"))
+	stats.add(20*time.Millisecond, nil)
+	if stats.Runs != 3 || stats.OK != 2 || stats.Failed != 1 {
+		t.Fatalf("unexpected counts: %+v", stats)
+	}
+	if stats.MinMs != 10 || stats.MaxMs != 30 || stats.AvgMs != 20 {
+		t.Fatalf("unexpected timings: %+v", stats)
+	}
+	if stats.LastError != "boom" {
+		t.Fatalf("expected last error, got %q", stats.LastError)
+	}
+}
+
+func TestRunRepeatJSON(t *testing.T) {
+	var out bytes.Buffer
+	a := App{Out: &out, Err: &bytes.Buffer{}}
+	calls := 0
+	code := a.runRepeat(GlobalFlags{Repeat: 4, JSON: true}, func() error {
+		calls++
+		if calls == 2 {
+			return errors.New("flaky")
+		}
+		return nil
+	})
+	if code != exitFailure || calls != 4 {
+		t.Fatalf("expected failure exit after 4 runs, got code %d calls %d", code, calls)
+	}
+	var stats map[string]any
+	if err := json.Unmarshal(out.Bytes(), &stats); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if stats["runs"] != float64(4) || stats["failed"] != float64(1) || stats["last_error"] != "flaky" {
+		t.Fatalf("unexpected stats: %v", stats)
+	}
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/patrickjm/www/internal/daemon"
@@ -8,8 +9,8 @@ import (
 
 func TestResolveTabIDFromStatus(t *testing.T) {
 	_, err := resolveTabIDFromStatus(daemon.StatusResult{})
-	if err == nil {
-		t.Fatalf("expected error for no tabs")
+	if !errors.Is(err, daemon.ErrNoTabs) {
+		t.Fatalf("expected no tabs error, got %v", err)
 	}
 	id, err := resolveTabIDFromStatus(daemon.StatusResult{Tabs: []daemon.TabInfo{{ID: 3}}})
 	if err != nil {
@@ -22,4 +23,22 @@ func TestResolveTabIDFromStatus(t *testing.T) {
 	if err == nil {
 		t.Fatalf("expected error for multiple tabs")
 	}
+	id, err = resolveTabIDFromStatus(daemon.StatusResult{Tabs: []daemon.TabInfo{{ID: 1, Crashed: true}, {ID: 2}}})
+	if err != nil || id != 2 {
+		t.Fatalf("expected crashed tab to be skipped, got %d, %v", id, err)
+	}
+}
+
+func TestActiveTabIDFromStatus(t *testing.T) {
+	id, err := activeTabIDFromStatus(daemon.StatusResult{Tabs: []daemon.TabInfo{{ID: 1}, {ID: 2, Active: true}, {ID: 3}}})
+	if err != nil || id != 2 {
+		t.Fatalf("expected active tab 2, got %d, %v", id, err)
+	}
+	id, err = activeTabIDFromStatus(daemon.StatusResult{Tabs: []daemon.TabInfo{{ID: 1, Active: true, Crashed: true}, {ID: 2}}})
+	if err != nil || id != 2 {
+		t.Fatalf("expected crashed active tab to be skipped, got %d, %v", id, err)
+	}
+	if _, err := activeTabIDFromStatus(daemon.StatusResult{Tabs: []daemon.TabInfo{{ID: 1}, {ID: 2}}}); err == nil {
+		t.Fatalf("expected error for multiple tabs with none active")
+	}
 }
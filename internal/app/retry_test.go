@@ -0,0 +1,52 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWithRetry(t *testing.T) {
+	var errOut bytes.Buffer
+	a := App{Out: &bytes.Buffer{}, Err: &errOut}
+	calls := 0
+	err := a.withRetry(GlobalFlags{Retry: 2, RetryDelay: "1ms", Verbose: true}, func() error {
+		calls++
+		return fmt.Errorf("attempt %d failed", calls)
+	})
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+	if err == nil || err.Error() != "attempt 3 failed" {
+		t.Fatalf("expected last error, got %v", err)
+	}
+	if !strings.Contains(errOut.String(), "retry 2/2") {
+		t.Fatalf("expected retry notice, got %q", errOut.String())
+	}
+
+	calls = 0
+	err = a.withRetry(GlobalFlags{Retry: 5, RetryDelay: "1ms"}, func() error {
+		calls++
+		if calls < 2 {
+			return errors.New("flaky")
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("expected success on second attempt, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	_ = a.withRetry(GlobalFlags{}, func() error {
+		calls++
+		return errors.New("fail")
+	})
+	if calls != 1 {
+		t.Fatalf("expected a single attempt without --retry, got %d", calls)
+	}
+	if err := a.withRetry(GlobalFlags{Retry: 1, RetryDelay: "soon"}, func() error { return nil }); err == nil {
+		t.Fatalf("expected error for invalid retry delay")
+	}
+}
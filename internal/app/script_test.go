@@ -0,0 +1,133 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickjm/www/internal/browser"
+)
+
+func TestSplitScriptLine(t *testing.T) {
+	args, err := splitScriptLine(`fill "#q input" 'hello world' again`)
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	if strings.Join(args, "|") != "fill|#q input|hello world|again" {
+		t.Fatalf("unexpected args: %q", args)
+	}
+	if _, err := splitScriptLine(`click "Sign in`); err == nil {
+		t.Fatalf("expected error for unterminated quote")
+	}
+}
+
+func TestReadScript(t *testing.T) {
+	lines, err := readScript("-", strings.NewReader("# login\ngoto https://example.com\n\nclick Sign in\n"))
+	if err != nil {
+		t.Fatalf("read script: %v", err)
+	}
+	if len(lines) != 2 || lines[0].N != 2 || lines[1].N != 4 || strings.Join(lines[1].Args, " ") != "click Sign in" {
+		t.Fatalf("unexpected lines: %+v", lines)
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	engine := &browser.FakeEngine{Session: &browser.FakeSession{}}
+	store, mgr, _ := startAppDaemon(t, engine)
+
+	lines, err := readScript("-", strings.NewReader("goto https://example.com\nclick \"Sign in\"\nbogus\nfill css=#q \"hello  there\"\n"))
+	if err != nil {
+		t.Fatalf("read script: %v", err)
+	}
+	flags := GlobalFlags{Profile: "demo", ProfileDir: store.Root}
+	var errOut bytes.Buffer
+	a := App{Out: &bytes.Buffer{}, Err: &errOut}
+	if code := a.runScript(store, mgr, flags, nil, lines, false, ""); code != exitFailure {
+		t.Fatalf("expected failure, got %d", code)
+	}
+	page := engine.Session.Pages[0]
+	if len(page.Clicks) != 1 || page.Clicks[0] != "text=Sign in" || len(page.Fills) != 0 {
+		t.Fatalf("expected to stop at the bad line, got clicks=%v fills=%v", page.Clicks, page.Fills)
+	}
+	if !strings.Contains(errOut.String(), "line 3: bogus: unknown command") {
+		t.Fatalf("unexpected error output: %q", errOut.String())
+	}
+
+	var out bytes.Buffer
+	a = App{Out: &out, Err: &bytes.Buffer{}}
+	flags.JSON = true
+	if code := a.runScript(store, mgr, flags, nil, lines, true, ""); code != exitFailure {
+		t.Fatalf("expected failure, got %d", code)
+	}
+	if len(page.Fills) != 1 || page.Fills[0] != "css=#q=hello  there" {
+		t.Fatalf("expected fill after --continue-on-error, got %v", page.Fills)
+	}
+	var results []scriptResult
+	if err := json.Unmarshal(out.Bytes(), &results); err != nil {
+		t.Fatalf("decode: %v (%s)", err, out.String())
+	}
+	if len(results) != 4 || !results[0].OK || results[2].OK || !results[3].OK {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
+
+func TestRunScriptUsesCommandFlags(t *testing.T) {
+	engine := &browser.FakeEngine{Session: &browser.FakeSession{}}
+	store, mgr, _ := startAppDaemon(t, engine)
+
+	script := "goto --wait networkidle https://example.com\nclick --nth 1 --exact Next\nurl\n"
+	lines, err := readScript("-", strings.NewReader(script))
+	if err != nil {
+		t.Fatalf("read script: %v", err)
+	}
+	var out bytes.Buffer
+	a := App{Out: &out, Err: &bytes.Buffer{}}
+	flags := GlobalFlags{Profile: "demo", ProfileDir: store.Root, JSON: true}
+	if code := a.runScript(store, mgr, flags, []string{"--raw-selector=true"}, lines, false, ""); code != exitSuccess {
+		t.Fatalf("expected success, got %d: %s", code, out.String())
+	}
+	page := engine.Session.Pages[0]
+	if page.WaitUntil != "networkidle" {
+		t.Fatalf("expected goto --wait to reach the page, got %q", page.WaitUntil)
+	}
+	opts := page.ClickOpts
+	if len(page.Clicks) != 1 || page.Clicks[0] != "Next" || !opts.Raw || !opts.Exact || opts.Nth == nil || *opts.Nth != 1 {
+		t.Fatalf("expected click flags and the run's --raw-selector, got clicks=%v opts=%+v", page.Clicks, opts)
+	}
+	var results []scriptResult
+	if err := json.Unmarshal(out.Bytes(), &results); err != nil {
+		t.Fatalf("decode: %v (%s)", err, out.String())
+	}
+	if len(results) != 3 || string(results[2].Result) != `"https://example.com"` {
+		t.Fatalf("expected the url command's output as the last result, got %+v", results)
+	}
+}
+
+func TestRunScriptDeadline(t *testing.T) {
+	engine := &browser.FakeEngine{Session: &browser.FakeSession{}}
+	store, mgr, _ := startAppDaemon(t, engine)
+	lines, err := readScript("-", strings.NewReader("sleep 5s\ngoto https://example.com\n"))
+	if err != nil {
+		t.Fatalf("read script: %v", err)
+	}
+	var errOut bytes.Buffer
+	a := App{Out: &bytes.Buffer{}, Err: &errOut}
+	started := time.Now()
+	if code := a.runScript(store, mgr, GlobalFlags{Profile: "demo", ProfileDir: store.Root}, nil, lines, true, "100ms"); code != exitTimeout {
+		t.Fatalf("expected exit %d, got %d", exitTimeout, code)
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Fatalf("expected the deadline to cut the sleep short, took %s", elapsed)
+	}
+	if !strings.Contains(errOut.String(), "line 1: sleep: deadline of 100ms reached during line 1") {
+		t.Fatalf("unexpected error output: %q", errOut.String())
+	}
+	if url, _ := engine.Session.Pages[0].URL(); url != "" {
+		t.Fatalf("expected no goto after the deadline, got %q", url)
+	}
+	if code := a.runScript(store, mgr, GlobalFlags{Profile: "demo", ProfileDir: store.Root}, nil, lines, false, "soon"); code != exitUsage {
+		t.Fatalf("expected usage error for a bad deadline, got %d", code)
+	}
+}
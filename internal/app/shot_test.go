@@ -0,0 +1,53 @@
+package app
+
+import "testing"
+
+func TestBurstPath(t *testing.T) {
+	cases := map[string]string{
+		"out.png":        "out-003.png",
+		"/tmp/a.b/c.jpg": "/tmp/a.b/c-003.jpg",
+		"frame":          "frame-003",
+	}
+	for in, want := range cases {
+		if got := burstPath(in, 3); got != want {
+			t.Fatalf("burstPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseClip(t *testing.T) {
+	rect, err := parseClip("10, 20,300,400")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if rect.X != 10 || rect.Y != 20 || rect.Width != 300 || rect.Height != 400 {
+		t.Fatalf("unexpected rect: %+v", rect)
+	}
+	for _, bad := range []string{"", "1,2,3", "1,2,3,4,5", "a,2,3,4", "1,-2,3,4"} {
+		if _, err := parseClip(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestScreenshotType(t *testing.T) {
+	cases := []struct {
+		path, value, want string
+	}{
+		{"out.png", "", "png"},
+		{"out.JPG", "", "jpeg"},
+		{"out.jpeg", "", "jpeg"},
+		{"out", "", "png"},
+		{"out.png", "jpg", "jpeg"},
+		{"out.jpg", "png", "png"},
+	}
+	for _, tc := range cases {
+		got, err := screenshotType(tc.path, tc.value)
+		if err != nil || got != tc.want {
+			t.Fatalf("screenshotType(%q, %q) = %q, %v; want %q", tc.path, tc.value, got, err, tc.want)
+		}
+	}
+	if _, err := screenshotType("out.png", "gif"); err == nil {
+		t.Fatalf("expected error for unsupported type")
+	}
+}
@@ -1,6 +1,9 @@
 package app
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestActionTimeoutMsDefault(t *testing.T) {
 	ms, err := actionTimeoutMs(GlobalFlags{})
@@ -12,6 +15,23 @@ func TestActionTimeoutMsDefault(t *testing.T) {
 	}
 }
 
+func TestActionTimeoutMsConfigDefault(t *testing.T) {
+	ms, err := actionTimeoutMs(GlobalFlags{DefaultTimeout: time.Minute})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ms != 60000 {
+		t.Fatalf("expected config default 60000ms, got %d", ms)
+	}
+	ms, err = actionTimeoutMs(GlobalFlags{Timeout: "5s", DefaultTimeout: time.Minute})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ms != 5000 {
+		t.Fatalf("expected flag to override config default, got %d", ms)
+	}
+}
+
 func TestActionTimeoutMsParse(t *testing.T) {
 	ms, err := actionTimeoutMs(GlobalFlags{Timeout: "5s"})
 	if err != nil {
@@ -28,3 +48,53 @@ func TestActionTimeoutMsInvalid(t *testing.T) {
 		t.Fatalf("expected error")
 	}
 }
+
+func TestActionTimeoutMsBareInteger(t *testing.T) {
+	ms, err := actionTimeoutMs(GlobalFlags{Timeout: "5"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ms != 5000 {
+		t.Fatalf("expected 5000ms, got %d", ms)
+	}
+	ms, err = actionTimeoutMs(GlobalFlags{Timeout: "500ms"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ms != 500 {
+		t.Fatalf("expected 500ms, got %d", ms)
+	}
+}
+
+func TestOverridesTTLBareInteger(t *testing.T) {
+	overrides, err := overridesFromFlags(GlobalFlags{TTL: "3600"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if overrides.TTL == nil || *overrides.TTL != time.Hour {
+		t.Fatalf("expected 1h ttl, got %v", overrides.TTL)
+	}
+	if _, err := overridesFromFlags(GlobalFlags{TTL: "soon"}); err == nil {
+		t.Fatalf("expected error for invalid ttl")
+	}
+}
+
+func TestSinceCutoff(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cutoff, err := sinceCutoff("", now)
+	if err != nil || !cutoff.IsZero() {
+		t.Fatalf("expected no cutoff, got %v %v", cutoff, err)
+	}
+	cutoff, err = sinceCutoff("90m", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cutoff.Equal(now.Add(-90 * time.Minute)) {
+		t.Fatalf("unexpected cutoff: %v", cutoff)
+	}
+	for _, value := range []string{"soon", "-1h", "0s"} {
+		if _, err := sinceCutoff(value, now); err == nil {
+			t.Fatalf("expected error for %q", value)
+		}
+	}
+}
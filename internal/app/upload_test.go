@@ -0,0 +1,32 @@
+package app
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/patrickjm/www/internal/daemon"
+	"github.com/patrickjm/www/internal/profile"
+)
+
+func TestRunUploadMissingFile(t *testing.T) {
+	var errOut bytes.Buffer
+	a := App{Out: &bytes.Buffer{}, Err: &errOut}
+	missing := filepath.Join(t.TempDir(), "missing.txt")
+	code := a.runUpload(profile.Store{}, daemon.Manager{}, GlobalFlags{Profile: "demo"}, "css=input", []string{missing}, nil)
+	if code != exitUsage {
+		t.Fatalf("expected usage exit, got %d", code)
+	}
+	if !strings.Contains(errOut.String(), "missing.txt") {
+		t.Fatalf("expected missing path in error, got %q", errOut.String())
+	}
+}
+
+func TestRunUploadDirectory(t *testing.T) {
+	a := App{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}
+	code := a.runUpload(profile.Store{}, daemon.Manager{}, GlobalFlags{Profile: "demo"}, "css=input", []string{t.TempDir()}, nil)
+	if code != exitUsage {
+		t.Fatalf("expected usage exit for directory, got %d", code)
+	}
+}
@@ -0,0 +1,18 @@
+package app
+
+import "testing"
+
+func TestParseViewport(t *testing.T) {
+	width, height, err := parseViewport("1280x720")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if width != 1280 || height != 720 {
+		t.Fatalf("expected 1280x720, got %dx%d", width, height)
+	}
+	for _, value := range []string{"1280", "1280x", "x720", "0x720", "-1x5", "axb"} {
+		if _, _, err := parseViewport(value); err == nil {
+			t.Fatalf("expected error for %q", value)
+		}
+	}
+}
@@ -3,10 +3,28 @@ package browser
 import "encoding/json"
 
 type StartOptions struct {
-	Browser   string
-	Channel   string
-	Headless  bool
-	StorageIn string
+	Browser      string
+	Channel      string
+	Headless     bool
+	StorageIn    string
+	Width        int
+	Height       int
+	Device       string
+	Trace        bool
+	NetLog       string
+	Proxy        string
+	ProxyBypass  string
+	UserAgent    string
+	DownloadDir  string
+	ExtraHeaders map[string]string
+	Geolocation  *Geolocation
+	Locale       string
+	Timezone     string
+}
+
+type Geolocation struct {
+	Latitude  float64
+	Longitude float64
 }
 
 type Engine interface {
@@ -15,42 +33,155 @@ type Engine interface {
 
 type Session interface {
 	NewPage() (Page, error)
+	OnPage(fn func(Page))
 	Close() error
 	StorageState(path string) error
+	Cookies() ([]Cookie, error)
+	NetLog(limit int) ([]NetRecord, error)
+	Downloads(limit int) ([]Download, error)
 }
 
 type Page interface {
-	Goto(url string) error
-	Click(selector string) error
-	Fill(selector string, value string) error
-	Screenshot(path string, fullPage bool, selector string) error
+	Goto(url string, opts GotoOptions) error
+	Click(selector string, opts ClickOptions) error
+	ClickPopup(selector string, opts ClickOptions) (Page, error)
+	Fill(selector string, value string, opts FillOptions) error
+	FillByLabel(label string, value string) error
+	DragTo(from string, to string) error
+	Focus(selector string) error
+	Blur(selector string) error
+	MouseMove(x float64, y float64) error
+	SelectOptions(selector string, values []string, add bool) ([]string, error)
+	SetInputFiles(selector string, paths []string, opts UploadOptions) error
+	Screenshot(path string, opts ScreenshotOptions) error
+	PDF(path string, opts PDFOptions) error
+	Highlight(selector string, color string) error
+	ClearHighlight() error
 	Extract(options ExtractOptions) (ExtractResult, error)
-	Links(filter string) ([]ExtractLink, error)
+	Links(opts LinksOptions) ([]ExtractLink, error)
+	Tables(selector string) ([]ExtractTable, error)
+	Forms() ([]ExtractForm, error)
+	Outline() ([]Heading, error)
+	Content(selector string) (string, error)
+	BoundingBox(selector string) (*Box, error)
+	Count(selector string) (int, error)
+	TextContent(selector string) (ElementText, error)
+	Attribute(selector string, name string) (ElementAttr, error)
+	Attributes(selector string) ([]ElementAttrs, error)
+	StorageItem(opts StorageOptions) (StorageResult, error)
 	SetTimeout(ms int) error
-	Eval(js string) (json.RawMessage, error)
+	SetSelectorTimeout(ms int) error
+	Eval(js string, arg json.RawMessage) (json.RawMessage, error)
+	WaitForLoadState(state string, timeoutMs int) error
+	WaitForURL(pattern string, regex bool, timeoutMs int) error
+	Dismiss(selectors []string) []string
 	URL() (string, error)
 	Title() (string, error)
+	BringToFront() error
+	OnClose(fn func())
+	OnCrash(fn func())
 	Close() error
 }
 
-type ExtractOptions struct {
+type GotoOptions struct {
+	WaitUntil string
+}
+
+type ClickOptions struct {
+	Raw   bool
+	Nth   *int
+	Exact bool
+}
+
+type FillOptions struct {
+	Nth *int
+}
+
+type UploadOptions struct {
+	Nth *int
+}
+
+type ScreenshotOptions struct {
+	FullPage bool
 	Selector string
-	Main     bool
+	Clip     *Rect
+	Type     string
+	Quality  *int
+}
+
+type Rect struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type PDFOptions struct {
+	Format          string
+	Landscape       bool
+	PrintBackground bool
+}
+
+type ExtractOptions struct {
+	Selector    string
+	Main        bool
+	Format      string
+	Shadow      bool
+	OnlyVisible bool
+	Aria        bool
+}
+
+type LinksOptions struct {
+	Filter      string
+	Shadow      bool
+	OnlyVisible bool
 }
 
 type ExtractResult struct {
-	URL     string            `json:"url"`
-	Title   string            `json:"title"`
-	Text    string            `json:"text"`
-	Links   []ExtractLink     `json:"links"`
-	Buttons []ExtractButton   `json:"buttons"`
-	Inputs  []ExtractInput    `json:"inputs"`
-	Meta    map[string]string `json:"meta"`
+	URL       string            `json:"url"`
+	Title     string            `json:"title"`
+	Text      string            `json:"text"`
+	Links     []ExtractLink     `json:"links"`
+	Buttons   []ExtractButton   `json:"buttons"`
+	Inputs    []ExtractInput    `json:"inputs"`
+	Meta      map[string]string `json:"meta"`
+	Canonical string            `json:"canonical,omitempty"`
+	Lang      string            `json:"lang,omitempty"`
+	Byline    string            `json:"byline,omitempty"`
+	Aria      []AriaNode        `json:"aria,omitempty"`
+}
+
+type AriaNode struct {
+	Role string `json:"role"`
+	Name string `json:"name"`
 }
 
 type ExtractLink struct {
-	Text string `json:"text"`
-	Href string `json:"href"`
+	Text   string `json:"text"`
+	Href   string `json:"href"`
+	Rel    string `json:"rel,omitempty"`
+	Target string `json:"target,omitempty"`
+}
+
+type ExtractTable struct {
+	Caption string              `json:"caption,omitempty"`
+	Headers []string            `json:"headers"`
+	Rows    []map[string]string `json:"rows"`
+}
+
+type ExtractForm struct {
+	ID      string         `json:"id,omitempty"`
+	Name    string         `json:"name,omitempty"`
+	Action  string         `json:"action"`
+	Method  string         `json:"method"`
+	Inputs  []ExtractInput `json:"inputs"`
+	Submits []string       `json:"submits"`
+}
+
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	ID    string `json:"id,omitempty"`
 }
 
 type ExtractButton struct {
@@ -62,3 +193,40 @@ type ExtractInput struct {
 	Name  string `json:"name"`
 	Type  string `json:"type"`
 }
+
+type ElementText struct {
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
+
+type ElementAttr struct {
+	Value string `json:"value"`
+	Found bool   `json:"found"`
+}
+
+type ElementAttrs struct {
+	Attributes map[string]string `json:"attributes"`
+	Text       string            `json:"text"`
+}
+
+type StorageOptions struct {
+	Op      string
+	Key     string
+	Value   string
+	Session bool
+}
+
+type StorageResult struct {
+	Origin string            `json:"origin"`
+	Key    string            `json:"key,omitempty"`
+	Value  string            `json:"value,omitempty"`
+	Found  bool              `json:"found"`
+	Items  map[string]string `json:"items,omitempty"`
+}
+
+type Box struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
@@ -0,0 +1,42 @@
+package browser
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNetscapeCookiesRoundTrip(t *testing.T) {
+	cookies := []Cookie{
+		{Name: "session", Value: "abc123", Domain: ".example.com", Path: "/", Expires: 1893456000, HTTPOnly: true, Secure: true},
+		{Name: "pref", Value: "dark", Domain: "app.example.com", Path: "/settings", Expires: -1},
+	}
+	var buf bytes.Buffer
+	if err := WriteNetscapeCookies(&buf, cookies); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "# Netscape HTTP Cookie File\n") {
+		t.Fatalf("missing header: %q", out)
+	}
+	if !strings.Contains(out, "#HttpOnly_.example.com\tTRUE\t/\tTRUE\t1893456000\tsession\tabc123\n") {
+		t.Fatalf("unexpected session line: %q", out)
+	}
+	if !strings.Contains(out, "app.example.com\tFALSE\t/settings\tFALSE\t0\tpref\tdark\n") {
+		t.Fatalf("unexpected pref line: %q", out)
+	}
+	parsed, err := ParseNetscapeCookies(&buf)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, cookies) {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", parsed, cookies)
+	}
+}
+
+func TestParseNetscapeCookiesInvalid(t *testing.T) {
+	if _, err := ParseNetscapeCookies(strings.NewReader("example.com\tFALSE\t/\n")); err == nil {
+		t.Fatalf("expected error for short line")
+	}
+}
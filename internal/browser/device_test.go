@@ -0,0 +1,52 @@
+package browser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+func TestLookupDevice(t *testing.T) {
+	iphone := &playwright.DeviceDescriptor{UserAgent: "iphone"}
+	pixel := &playwright.DeviceDescriptor{UserAgent: "pixel"}
+	devices := map[string]*playwright.DeviceDescriptor{
+		"iPhone 13": iphone,
+		"Pixel 5":   pixel,
+		"iPad Mini": {UserAgent: "ipad"},
+	}
+	device, err := lookupDevice(devices, "iPhone 13")
+	if err != nil || device != iphone {
+		t.Fatalf("exact match: got %v, %v", device, err)
+	}
+	device, err = lookupDevice(devices, "pixel 5")
+	if err != nil || device != pixel {
+		t.Fatalf("case-insensitive match: got %v, %v", device, err)
+	}
+	_, err = lookupDevice(devices, "iPhone 31")
+	if err == nil {
+		t.Fatalf("expected unknown device error")
+	}
+	if !strings.Contains(err.Error(), `unknown device "iPhone 31"`) || !strings.Contains(err.Error(), `did you mean "iPhone 13"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = lookupDevice(nil, "iPhone 13")
+	if err == nil || strings.Contains(err.Error(), "did you mean") {
+		t.Fatalf("expected plain unknown device error, got %v", err)
+	}
+}
+
+func TestClosestMatches(t *testing.T) {
+	candidates := []string{"Pixel 5", "iPhone 13", "iPhone 12", "Galaxy S9+"}
+	got := closestMatches("iphone 13", candidates, 2)
+	if want := []string{"iPhone 13", "iPhone 12"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if got := closestMatches("pixel", candidates, 10); len(got) != len(candidates) || got[0] != "Pixel 5" {
+		t.Fatalf("expected all candidates with Pixel 5 first, got %v", got)
+	}
+	if got := closestMatches("x", nil, 3); len(got) != 0 {
+		t.Fatalf("expected no matches, got %v", got)
+	}
+}
@@ -0,0 +1,101 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxDownloads = 200
+
+// defaultDownloadTimeout bounds saving a download when the page that started
+// it has no action timeout set.
+const defaultDownloadTimeout = 20 * time.Second
+
+type Download struct {
+	Time       time.Time `json:"time"`
+	URL        string    `json:"url"`
+	Filename   string    `json:"filename"`
+	Path       string    `json:"path,omitempty"`
+	Bytes      int64     `json:"bytes"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// downloadFile is the part of playwright.Download used to save it.
+type downloadFile interface {
+	SaveAs(path string) error
+	Cancel() error
+}
+
+// saveDownloadFile saves d to path, cancelling it if that takes longer than
+// timeout, and returns the size of the saved file.
+func saveDownloadFile(d downloadFile, path string, timeout time.Duration) (int64, error) {
+	done := make(chan error, 1)
+	go func() { done <- d.SaveAs(path) }()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			return 0, err
+		}
+	case <-timer.C:
+		_ = d.Cancel()
+		return 0, fmt.Errorf("download timed out after %s", timeout)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("saved download is missing: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return 0, fmt.Errorf("saved download %s is not a regular file", path)
+	}
+	return info.Size(), nil
+}
+
+type downloadLog struct {
+	mu      sync.Mutex
+	records []Download
+}
+
+func (l *downloadLog) add(record Download) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, record)
+	if len(l.records) > maxDownloads {
+		l.records = append([]Download(nil), l.records[len(l.records)-maxDownloads:]...)
+	}
+}
+
+func (l *downloadLog) recent(limit int) []Download {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return lastDownloads(l.records, limit)
+}
+
+func lastDownloads(records []Download, limit int) []Download {
+	if limit <= 0 || limit > len(records) {
+		limit = len(records)
+	}
+	return append([]Download(nil), records[len(records)-limit:]...)
+}
+
+func uniqueDownloadPath(dir string, name string) string {
+	name = filepath.Base(strings.TrimSpace(name))
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		name = "download"
+	}
+	path := filepath.Join(dir, name)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+}
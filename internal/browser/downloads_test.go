@@ -0,0 +1,82 @@
+package browser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUniqueDownloadPath(t *testing.T) {
+	dir := t.TempDir()
+	if got := uniqueDownloadPath(dir, "report.pdf"); got != filepath.Join(dir, "report.pdf") {
+		t.Fatalf("unexpected path: %s", got)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "report.pdf"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got := uniqueDownloadPath(dir, "report.pdf"); got != filepath.Join(dir, "report (1).pdf") {
+		t.Fatalf("expected numbered path, got %s", got)
+	}
+	if got := uniqueDownloadPath(dir, "../../etc/passwd"); got != filepath.Join(dir, "passwd") {
+		t.Fatalf("expected name confined to dir, got %s", got)
+	}
+	if got := uniqueDownloadPath(dir, ""); got != filepath.Join(dir, "download") {
+		t.Fatalf("expected fallback name, got %s", got)
+	}
+}
+
+func TestDownloadLogRecent(t *testing.T) {
+	var log downloadLog
+	for i := 0; i < maxDownloads+5; i++ {
+		log.add(Download{Bytes: int64(i)})
+	}
+	all := log.recent(0)
+	if len(all) != maxDownloads || all[0].Bytes != 5 {
+		t.Fatalf("unexpected records: %d first=%d", len(all), all[0].Bytes)
+	}
+	last := log.recent(2)
+	if len(last) != 2 || last[1].Bytes != maxDownloads+4 {
+		t.Fatalf("unexpected recent: %+v", last)
+	}
+}
+
+type fakeDownloadFile struct {
+	data     []byte
+	block    chan struct{}
+	skip     bool
+	canceled bool
+}
+
+func (d *fakeDownloadFile) SaveAs(path string) error {
+	if d.block != nil {
+		<-d.block
+		return errors.New("canceled")
+	}
+	if d.skip {
+		return nil
+	}
+	return os.WriteFile(path, d.data, 0o644)
+}
+
+func (d *fakeDownloadFile) Cancel() error {
+	d.canceled = true
+	close(d.block)
+	return nil
+}
+
+func TestSaveDownloadFile(t *testing.T) {
+	dir := t.TempDir()
+	size, err := saveDownloadFile(&fakeDownloadFile{data: []byte("hello")}, filepath.Join(dir, "a.txt"), time.Second)
+	if err != nil || size != 5 {
+		t.Fatalf("expected 5 bytes saved, got %d, %v", size, err)
+	}
+	if _, err := saveDownloadFile(&fakeDownloadFile{skip: true}, filepath.Join(dir, "b.txt"), time.Second); err == nil {
+		t.Fatal("expected an error when nothing was saved")
+	}
+	slow := &fakeDownloadFile{block: make(chan struct{})}
+	if _, err := saveDownloadFile(slow, filepath.Join(dir, "c.txt"), 20*time.Millisecond); err == nil || !slow.canceled {
+		t.Fatalf("expected a canceled timeout, got %v canceled=%v", err, slow.canceled)
+	}
+}
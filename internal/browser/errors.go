@@ -0,0 +1,60 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// ErrElementDetached reports that a selector matched but the element left the
+// DOM before the action landed, which usually means the page re-rendered.
+var ErrElementDetached = errors.New("element detached")
+
+// ErrNoMatch reports that a selector or text matched no element.
+var ErrNoMatch = errors.New("no match")
+
+// IsTimeout reports whether err is a Playwright timeout.
+func IsTimeout(err error) bool {
+	return errors.Is(err, playwright.ErrTimeout)
+}
+
+var detachedMessages = []string{
+	"element is not attached to the dom",
+	"element is detached",
+	"node is detached from document",
+}
+
+// ambiguousInputError lists the inputs a fill selector matched so the caller
+// can pick one with --nth.
+func ambiguousInputError(selector string, inputs []ExtractInput) error {
+	described := make([]string, 0, len(inputs))
+	for i, input := range inputs {
+		parts := []string{}
+		if input.Label != "" {
+			parts = append(parts, fmt.Sprintf("%q", input.Label))
+		}
+		if input.Name != "" {
+			parts = append(parts, "name="+input.Name)
+		}
+		if input.Type != "" {
+			parts = append(parts, "type="+input.Type)
+		}
+		described = append(described, fmt.Sprintf("%d: %s", i, strings.Join(parts, " ")))
+	}
+	return fmt.Errorf("%s matched %d elements (%s); pass --nth to pick one", selector, len(inputs), strings.Join(described, "; "))
+}
+
+func wrapDetached(selector string, err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := strings.ToLower(err.Error())
+	for _, needle := range detachedMessages {
+		if strings.Contains(msg, needle) {
+			return fmt.Errorf("%w: %s matched but was removed from the page before the action finished; the page is likely re-rendering, retry with --retry", ErrElementDetached, selector)
+		}
+	}
+	return err
+}
@@ -0,0 +1,31 @@
+package browser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWrapDetached(t *testing.T) {
+	err := wrapDetached("css=button", errors.New("locator.click: Element is not attached to the DOM"))
+	if !errors.Is(err, ErrElementDetached) {
+		t.Fatalf("expected detached error, got %v", err)
+	}
+	other := errors.New("timeout 20000ms exceeded")
+	if got := wrapDetached("css=button", other); got != other {
+		t.Fatalf("expected other errors to pass through, got %v", got)
+	}
+	if wrapDetached("css=button", nil) != nil {
+		t.Fatalf("expected nil for nil error")
+	}
+}
+
+func TestAmbiguousFillError(t *testing.T) {
+	err := ambiguousInputError("input.q", []ExtractInput{
+		{Label: "Search", Name: "q", Type: "search"},
+		{Name: "q2", Type: "text"},
+	})
+	want := `input.q matched 2 elements (0: "Search" name=q type=search; 1: name=q2 type=text); pass --nth to pick one`
+	if err.Error() != want {
+		t.Fatalf("unexpected error:\n%s", err)
+	}
+}
@@ -3,14 +3,26 @@ package browser
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
 )
 
 type FakeEngine struct {
-	Session *FakeSession
+	Session  *FakeSession
+	StartErr error
+	Starts   int
 }
 
+// Start returns Session, replacing it with a new one once it has been closed.
 func (f *FakeEngine) Start(opts StartOptions) (Session, error) {
-	if f.Session == nil {
+	if f.StartErr != nil {
+		return nil, f.StartErr
+	}
+	f.Starts++
+	if f.Session == nil || f.Session.Closed {
 		f.Session = &FakeSession{}
 	}
 	return f.Session, nil
@@ -20,81 +32,374 @@ type FakeSession struct {
 	Pages       []*FakePage
 	Closed      bool
 	StoragePath string
+	CookiesRes  []Cookie
+	NetRecords  []NetRecord
+	DownloadRes []Download
+	StorageErr  error
+	saves       int
+	mu          sync.Mutex
+	onPage      func(Page)
 }
 
 func (s *FakeSession) NewPage() (Page, error) {
-	page := &FakePage{TitleValue: "", URLValue: ""}
+	page := &FakePage{TitleValue: "", URLValue: "", session: s}
+	s.mu.Lock()
 	s.Pages = append(s.Pages, page)
+	s.mu.Unlock()
 	return page, nil
 }
 
+func (s *FakeSession) OnPage(fn func(Page)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onPage = fn
+}
+
+func (s *FakeSession) OpenPopup() *FakePage {
+	page := &FakePage{session: s}
+	s.mu.Lock()
+	s.Pages = append(s.Pages, page)
+	fn := s.onPage
+	s.mu.Unlock()
+	if fn != nil {
+		fn(page)
+	}
+	return page
+}
+
 func (s *FakeSession) Close() error {
 	s.Closed = true
 	return nil
 }
 
 func (s *FakeSession) StorageState(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saves++
 	s.StoragePath = path
-	return nil
+	return s.StorageErr
+}
+
+// StorageSaves reports how many times StorageState has been called.
+func (s *FakeSession) StorageSaves() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saves
+}
+
+func (s *FakeSession) Cookies() ([]Cookie, error) {
+	return s.CookiesRes, nil
+}
+
+func (s *FakeSession) NetLog(limit int) ([]NetRecord, error) {
+	return lastNetRecords(s.NetRecords, limit), nil
+}
+
+func (s *FakeSession) Downloads(limit int) ([]Download, error) {
+	return lastDownloads(s.DownloadRes, limit), nil
 }
 
 type FakePage struct {
-	URLValue   string
-	TitleValue string
-	Clicks     []string
-	Fills      []string
-	Shots      []string
-	EvalResult json.RawMessage
-	ExtractRes ExtractResult
-	LinksRes   []ExtractLink
-	TimeoutMs  int
-	Closed     bool
-}
-
-func (p *FakePage) Goto(url string) error {
+	URLValue    string
+	TitleValue  string
+	WaitUntil   string
+	GotoDelay   time.Duration
+	URLBlock    chan struct{}
+	Clicks      []string
+	ClickOpts   ClickOptions
+	ClickErr    error
+	Fills       []string
+	FillOpts    FillOptions
+	LabelFills  []string
+	Drags       []string
+	Focused     string
+	Blurred     []string
+	Moves       [][2]float64
+	Selected    []string
+	Uploads     []string
+	UploadOpts  UploadOptions
+	Multiple    bool
+	Shots       []string
+	Clip        *Rect
+	ShotOpts    ScreenshotOptions
+	PDFs        []string
+	Highlights  []string
+	Highlit     bool
+	EvalResult  json.RawMessage
+	EvalArg     json.RawMessage
+	Evals       []string
+	ExtractRes  ExtractResult
+	ExtractOpts ExtractOptions
+	// ExtractByURL, when it has an entry for the current URL, overrides
+	// ExtractRes so multi-page reads can see different pages.
+	ExtractByURL map[string]ExtractResult
+	LinksOpts    LinksOptions
+	LinksRes     []ExtractLink
+	BoxRes       *Box
+	CountRes     int
+	TextRes      ElementText
+	Attrs        map[string]string
+	AttrsRes     []ElementAttrs
+	Local        map[string]string
+	Session      map[string]string
+	TablesRes    []ExtractTable
+	FormsRes     []ExtractForm
+	OutlineRes   []Heading
+	HTML         string
+	LoadStates   []string
+	WaitedURL    string
+	WaitURLErr   error
+	Dismissed    []string
+	TimeoutMs    int
+	SelectorMs   int
+	Closed       bool
+	Fronted      bool
+	session      *FakeSession
+	mu           sync.Mutex
+	onClose      func()
+	onCrash      func()
+}
+
+func (p *FakePage) Goto(url string, opts GotoOptions) error {
+	time.Sleep(p.GotoDelay)
+	p.mu.Lock()
 	p.URLValue = url
+	p.mu.Unlock()
+	p.WaitUntil = opts.WaitUntil
 	return nil
 }
 
-func (p *FakePage) Click(selector string) error {
+func (p *FakePage) Click(selector string, opts ClickOptions) error {
+	if p.ClickErr != nil {
+		return p.ClickErr
+	}
 	p.Clicks = append(p.Clicks, selector)
+	p.ClickOpts = opts
 	return nil
 }
 
-func (p *FakePage) Fill(selector string, value string) error {
+func (p *FakePage) ClickPopup(selector string, opts ClickOptions) (Page, error) {
+	if err := p.Click(selector, opts); err != nil {
+		return nil, err
+	}
+	if p.session == nil {
+		return nil, errors.New("no popup")
+	}
+	return p.session.OpenPopup(), nil
+}
+
+func (p *FakePage) Fill(selector string, value string, opts FillOptions) error {
+	p.FillOpts = opts
 	p.Fills = append(p.Fills, selector+"="+value)
 	return nil
 }
 
-func (p *FakePage) Screenshot(path string, fullPage bool, selector string) error {
+func (p *FakePage) FillByLabel(label string, value string) error {
+	p.LabelFills = append(p.LabelFills, label+"="+value)
+	return nil
+}
+
+func (p *FakePage) SelectOptions(selector string, values []string, add bool) ([]string, error) {
+	if len(values) > 1 && !p.Multiple {
+		return nil, fmt.Errorf("%s is not a multi-select; pass a single value", selector)
+	}
+	if !add {
+		p.Selected = nil
+	}
+	for _, v := range values {
+		if !slices.Contains(p.Selected, v) {
+			p.Selected = append(p.Selected, v)
+		}
+	}
+	return append([]string{}, p.Selected...), nil
+}
+
+func (p *FakePage) SetInputFiles(selector string, paths []string, opts UploadOptions) error {
+	p.Uploads = append(p.Uploads, paths...)
+	p.UploadOpts = opts
+	return nil
+}
+
+func (p *FakePage) Screenshot(path string, opts ScreenshotOptions) error {
 	p.Shots = append(p.Shots, path)
+	p.Clip = opts.Clip
+	p.ShotOpts = opts
+	return nil
+}
+
+func (p *FakePage) PDF(path string, _ PDFOptions) error {
+	if err := os.WriteFile(path, []byte("%PDF-1.4\n"), 0o644); err != nil {
+		return err
+	}
+	p.PDFs = append(p.PDFs, path)
+	return nil
+}
+
+func (p *FakePage) Highlight(selector string, color string) error {
+	p.Highlights = append(p.Highlights, selector+"="+color)
+	p.Highlit = true
+	return nil
+}
+
+func (p *FakePage) ClearHighlight() error {
+	p.Highlit = false
 	return nil
 }
 
-func (p *FakePage) Extract(_ ExtractOptions) (ExtractResult, error) {
+func (p *FakePage) Extract(opts ExtractOptions) (ExtractResult, error) {
+	p.ExtractOpts = opts
+	p.mu.Lock()
+	url := p.URLValue
+	p.mu.Unlock()
+	if res, ok := p.ExtractByURL[url]; ok {
+		return res, nil
+	}
 	if p.ExtractRes.URL != "" || p.ExtractRes.Title != "" || p.ExtractRes.Text != "" {
 		return p.ExtractRes, nil
 	}
 	return ExtractResult{URL: p.URLValue, Title: p.TitleValue, Text: ""}, nil
 }
 
-func (p *FakePage) Links(_ string) ([]ExtractLink, error) {
+func (p *FakePage) Links(opts LinksOptions) ([]ExtractLink, error) {
+	p.LinksOpts = opts
 	return p.LinksRes, nil
 }
 
+func (p *FakePage) Tables(_ string) ([]ExtractTable, error) {
+	return p.TablesRes, nil
+}
+
+func (p *FakePage) Forms() ([]ExtractForm, error) {
+	return p.FormsRes, nil
+}
+
+func (p *FakePage) Outline() ([]Heading, error) {
+	return p.OutlineRes, nil
+}
+
+func (p *FakePage) Content(selector string) (string, error) {
+	return p.HTML, nil
+}
+
+func (p *FakePage) BoundingBox(_ string) (*Box, error) {
+	return p.BoxRes, nil
+}
+
+func (p *FakePage) Count(selector string) (int, error) {
+	return p.CountRes, nil
+}
+
+func (p *FakePage) TextContent(selector string) (ElementText, error) {
+	return p.TextRes, nil
+}
+
+func (p *FakePage) Attribute(selector string, name string) (ElementAttr, error) {
+	if p.Attrs == nil {
+		return ElementAttr{}, nil
+	}
+	return ElementAttr{Value: p.Attrs[name], Found: true}, nil
+}
+
+func (p *FakePage) Attributes(selector string) ([]ElementAttrs, error) {
+	return p.AttrsRes, nil
+}
+
+func (p *FakePage) StorageItem(opts StorageOptions) (StorageResult, error) {
+	items := &p.Local
+	if opts.Session {
+		items = &p.Session
+	}
+	if *items == nil {
+		*items = map[string]string{}
+	}
+	result := StorageResult{Origin: p.URLValue, Key: opts.Key}
+	switch opts.Op {
+	case "get":
+		result.Value, result.Found = (*items)[opts.Key]
+	case "set":
+		(*items)[opts.Key] = opts.Value
+		result.Value, result.Found = opts.Value, true
+	case "list":
+		result.Items = map[string]string{}
+		for k, v := range *items {
+			result.Items[k] = v
+		}
+		result.Found = len(result.Items) > 0
+	default:
+		return StorageResult{}, fmt.Errorf("unknown storage op %q", opts.Op)
+	}
+	return result, nil
+}
+
 func (p *FakePage) SetTimeout(ms int) error {
 	p.TimeoutMs = ms
+	p.SelectorMs = ms
 	return nil
 }
 
-func (p *FakePage) Eval(js string) (json.RawMessage, error) {
+func (p *FakePage) SetSelectorTimeout(ms int) error {
+	p.SelectorMs = ms
+	return nil
+}
+
+func (p *FakePage) WaitForLoadState(state string, timeoutMs int) error {
+	p.LoadStates = append(p.LoadStates, state)
+	return nil
+}
+
+func (p *FakePage) WaitForURL(pattern string, regex bool, timeoutMs int) error {
+	p.WaitedURL = pattern
+	return p.WaitURLErr
+}
+
+// Eval returns EvalResult, or echoes arg back when no result is set.
+func (p *FakePage) Eval(js string, arg json.RawMessage) (json.RawMessage, error) {
+	p.Evals = append(p.Evals, js)
+	p.EvalArg = arg
+	if p.EvalResult == nil && arg != nil {
+		return arg, nil
+	}
 	if p.EvalResult == nil {
 		return nil, errors.New("no eval result")
 	}
 	return p.EvalResult, nil
 }
 
+func (p *FakePage) DragTo(from string, to string) error {
+	p.Drags = append(p.Drags, from+" -> "+to)
+	return nil
+}
+
+func (p *FakePage) Focus(selector string) error {
+	p.Focused = selector
+	return nil
+}
+
+func (p *FakePage) Blur(selector string) error {
+	p.Blurred = append(p.Blurred, selector)
+	if p.Focused == selector {
+		p.Focused = ""
+	}
+	return nil
+}
+
+func (p *FakePage) MouseMove(x float64, y float64) error {
+	p.Moves = append(p.Moves, [2]float64{x, y})
+	return nil
+}
+
+func (p *FakePage) Dismiss(selectors []string) []string {
+	p.Dismissed = append(p.Dismissed, selectors...)
+	return selectors
+}
+
+// URL waits for URLBlock to be closed, when set, like a wedged page.
 func (p *FakePage) URL() (string, error) {
+	if p.URLBlock != nil {
+		<-p.URLBlock
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return p.URLValue, nil
 }
 
@@ -102,7 +407,41 @@ func (p *FakePage) Title() (string, error) {
 	return p.TitleValue, nil
 }
 
+func (p *FakePage) BringToFront() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Fronted = true
+	return nil
+}
+
+func (p *FakePage) OnClose(fn func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onClose = fn
+}
+
+func (p *FakePage) OnCrash(fn func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onCrash = fn
+}
+
+func (p *FakePage) Crash() {
+	p.mu.Lock()
+	fn := p.onCrash
+	p.mu.Unlock()
+	if fn != nil {
+		fn()
+	}
+}
+
 func (p *FakePage) Close() error {
 	p.Closed = true
+	p.mu.Lock()
+	fn := p.onClose
+	p.mu.Unlock()
+	if fn != nil {
+		fn()
+	}
 	return nil
 }
@@ -0,0 +1,62 @@
+package browser
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+const maxNetRecords = 500
+
+type NetRecord struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	URL    string    `json:"url"`
+	Status int       `json:"status"`
+}
+
+type netLog struct {
+	mu      sync.Mutex
+	path    string
+	records []NetRecord
+}
+
+func newNetLog(path string) *netLog {
+	return &netLog{path: path}
+}
+
+func (l *netLog) add(record NetRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, record)
+	if len(l.records) > maxNetRecords {
+		l.records = append([]NetRecord(nil), l.records[len(l.records)-maxNetRecords:]...)
+	}
+	if l.path == "" {
+		return
+	}
+	b, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	_, _ = f.Write(append(b, '\n'))
+}
+
+func (l *netLog) recent(limit int) []NetRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return lastNetRecords(l.records, limit)
+}
+
+func lastNetRecords(records []NetRecord, limit int) []NetRecord {
+	if limit <= 0 || limit > len(records) {
+		limit = len(records)
+	}
+	return append([]NetRecord{}, records[len(records)-limit:]...)
+}
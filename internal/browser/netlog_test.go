@@ -0,0 +1,40 @@
+package browser
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNetLogRingBuffer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "network.log")
+	log := newNetLog(path)
+	for i := 0; i < maxNetRecords+10; i++ {
+		log.add(NetRecord{Method: "GET", URL: "https://example.com/", Status: i})
+	}
+	all := log.recent(0)
+	if len(all) != maxNetRecords {
+		t.Fatalf("expected %d records, got %d", maxNetRecords, len(all))
+	}
+	if all[0].Status != 10 {
+		t.Fatalf("expected oldest records dropped, first status %d", all[0].Status)
+	}
+	last := log.recent(3)
+	if len(last) != 3 || last[2].Status != maxNetRecords+9 {
+		t.Fatalf("unexpected recent records: %+v", last)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open log: %v", err)
+	}
+	defer f.Close()
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines++
+	}
+	if lines != maxNetRecords+10 {
+		t.Fatalf("expected %d log lines, got %d", maxNetRecords+10, lines)
+	}
+}
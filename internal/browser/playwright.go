@@ -4,8 +4,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/playwright-community/playwright-go"
 )
@@ -22,6 +29,14 @@ func (p PlaywrightEngine) Start(opts StartOptions) (Session, error) {
 		pw.Stop()
 		return nil, err
 	}
+	var device *playwright.DeviceDescriptor
+	if opts.Device != "" {
+		device, err = lookupDevice(pw.Devices, opts.Device)
+		if err != nil {
+			pw.Stop()
+			return nil, err
+		}
+	}
 	launchOpts := playwright.BrowserTypeLaunchOptions{
 		Headless: playwright.Bool(opts.Headless),
 	}
@@ -43,19 +58,98 @@ func (p PlaywrightEngine) Start(opts StartOptions) (Session, error) {
 			ctxOpts.StorageStatePath = playwright.String(opts.StorageIn)
 		}
 	}
+	if device != nil {
+		ctxOpts.UserAgent = playwright.String(device.UserAgent)
+		ctxOpts.Viewport = device.Viewport
+		ctxOpts.DeviceScaleFactor = playwright.Float(device.DeviceScaleFactor)
+		ctxOpts.IsMobile = playwright.Bool(device.IsMobile)
+		ctxOpts.HasTouch = playwright.Bool(device.HasTouch)
+	}
+	if opts.Width > 0 && opts.Height > 0 {
+		ctxOpts.Viewport = &playwright.Size{Width: opts.Width, Height: opts.Height}
+	}
+	if opts.UserAgent != "" {
+		ctxOpts.UserAgent = playwright.String(opts.UserAgent)
+	}
+	if opts.Proxy != "" {
+		proxy, err := proxyOption(opts.Proxy, opts.ProxyBypass)
+		if err != nil {
+			browser.Close()
+			pw.Stop()
+			return nil, err
+		}
+		ctxOpts.Proxy = proxy
+	}
+	if opts.DownloadDir != "" {
+		ctxOpts.AcceptDownloads = playwright.Bool(true)
+	}
+	if opts.Geolocation != nil {
+		ctxOpts.Geolocation = &playwright.Geolocation{Latitude: opts.Geolocation.Latitude, Longitude: opts.Geolocation.Longitude}
+		ctxOpts.Permissions = []string{"geolocation"}
+	}
+	if opts.Locale != "" {
+		ctxOpts.Locale = playwright.String(opts.Locale)
+	}
+	if opts.Timezone != "" {
+		ctxOpts.TimezoneId = playwright.String(opts.Timezone)
+	}
 	ctx, err := browser.NewContext(ctxOpts)
 	if err != nil {
 		browser.Close()
 		pw.Stop()
 		return nil, err
 	}
-	return &playwrightSession{pw: pw, browser: browser, ctx: ctx}, nil
+	if len(opts.ExtraHeaders) > 0 {
+		if err := ctx.SetExtraHTTPHeaders(opts.ExtraHeaders); err != nil {
+			browser.Close()
+			pw.Stop()
+			return nil, err
+		}
+	}
+	session := &playwrightSession{pw: pw, browser: browser, ctx: ctx, pages: make(map[playwright.Page]*playwrightPage), downloadDir: opts.DownloadDir}
+	if opts.Trace {
+		session.netLog = newNetLog(opts.NetLog)
+		ctx.OnResponse(func(resp playwright.Response) {
+			session.netLog.add(NetRecord{
+				Time:   time.Now().UTC(),
+				Method: resp.Request().Method(),
+				URL:    resp.URL(),
+				Status: resp.Status(),
+			})
+		})
+	}
+	return session, nil
+}
+
+func proxyOption(server string, bypass string) (*playwright.Proxy, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %w", server, err)
+	}
+	proxy := &playwright.Proxy{}
+	if u.User != nil {
+		proxy.Username = playwright.String(u.User.Username())
+		if password, ok := u.User.Password(); ok {
+			proxy.Password = playwright.String(password)
+		}
+		u.User = nil
+	}
+	proxy.Server = u.String()
+	if bypass != "" {
+		proxy.Bypass = playwright.String(bypass)
+	}
+	return proxy, nil
 }
 
 type playwrightSession struct {
-	pw      *playwright.Playwright
-	browser playwright.Browser
-	ctx     playwright.BrowserContext
+	pw          *playwright.Playwright
+	browser     playwright.Browser
+	ctx         playwright.BrowserContext
+	netLog      *netLog
+	downloads   downloadLog
+	downloadDir string
+	mu          sync.Mutex
+	pages       map[playwright.Page]*playwrightPage
 }
 
 func (s *playwrightSession) NewPage() (Page, error) {
@@ -63,7 +157,55 @@ func (s *playwrightSession) NewPage() (Page, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &playwrightPage{page: page}, nil
+	return s.wrap(page), nil
+}
+
+func (s *playwrightSession) OnPage(fn func(Page)) {
+	s.ctx.OnPage(func(page playwright.Page) {
+		fn(s.wrap(page))
+	})
+}
+
+func (s *playwrightSession) wrap(page playwright.Page) *playwrightPage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if wrapped, ok := s.pages[page]; ok {
+		return wrapped
+	}
+	wrapped := &playwrightPage{page: page, session: s, browserName: s.browser.BrowserType().Name()}
+	s.pages[page] = wrapped
+	page.OnClose(func(playwright.Page) {
+		s.mu.Lock()
+		delete(s.pages, page)
+		s.mu.Unlock()
+	})
+	if s.downloadDir != "" {
+		page.OnDownload(func(download playwright.Download) {
+			go s.saveDownload(download, wrapped.downloadTimeout())
+		})
+	}
+	return wrapped
+}
+
+func (s *playwrightSession) saveDownload(download playwright.Download, timeout time.Duration) {
+	start := time.Now()
+	record := Download{Time: start.UTC(), URL: download.URL(), Filename: download.SuggestedFilename()}
+	defer func() {
+		record.DurationMs = time.Since(start).Milliseconds()
+		s.downloads.add(record)
+	}()
+	if err := os.MkdirAll(s.downloadDir, 0o755); err != nil {
+		record.Error = err.Error()
+		return
+	}
+	path := uniqueDownloadPath(s.downloadDir, record.Filename)
+	size, err := saveDownloadFile(download, path, timeout)
+	if err != nil {
+		record.Error = err.Error()
+		return
+	}
+	record.Path = path
+	record.Bytes = size
 }
 
 func (s *playwrightSession) StorageState(path string) error {
@@ -71,6 +213,36 @@ func (s *playwrightSession) StorageState(path string) error {
 	return err
 }
 
+func (s *playwrightSession) Cookies() ([]Cookie, error) {
+	cookies, err := s.ctx.Cookies()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Cookie, 0, len(cookies))
+	for _, c := range cookies {
+		cookie := Cookie{Name: c.Name, Value: c.Value, Domain: c.Domain, Path: c.Path, Expires: c.Expires, HTTPOnly: c.HttpOnly, Secure: c.Secure}
+		if c.SameSite != nil {
+			cookie.SameSite = string(*c.SameSite)
+		}
+		result = append(result, cookie)
+	}
+	return result, nil
+}
+
+func (s *playwrightSession) NetLog(limit int) ([]NetRecord, error) {
+	if s.netLog == nil {
+		return nil, errors.New("network tracing is not enabled; restart with start --trace")
+	}
+	return s.netLog.recent(limit), nil
+}
+
+func (s *playwrightSession) Downloads(limit int) ([]Download, error) {
+	if s.downloadDir == "" {
+		return nil, errors.New("downloads are not enabled for this profile")
+	}
+	return s.downloads.recent(limit), nil
+}
+
 func (s *playwrightSession) Close() error {
 	if s.ctx != nil {
 		_ = s.ctx.Close()
@@ -85,32 +257,250 @@ func (s *playwrightSession) Close() error {
 }
 
 type playwrightPage struct {
-	page playwright.Page
+	page        playwright.Page
+	session     *playwrightSession
+	browserName string
+	// timeoutMs is the last action timeout set on the page, which also
+	// bounds saving downloads the page starts.
+	timeoutMs atomic.Int64
 }
 
-func (p *playwrightPage) Goto(url string) error {
-	_, err := p.page.Goto(url)
+func (p *playwrightPage) downloadTimeout() time.Duration {
+	if ms := p.timeoutMs.Load(); ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return defaultDownloadTimeout
+}
+
+func (p *playwrightPage) Goto(url string, opts GotoOptions) error {
+	gotoOpts := playwright.PageGotoOptions{}
+	if opts.WaitUntil != "" {
+		state := playwright.WaitUntilState(opts.WaitUntil)
+		gotoOpts.WaitUntil = &state
+	}
+	_, err := p.page.Goto(url, gotoOpts)
 	return err
 }
 
-func (p *playwrightPage) Click(selector string) error {
-	if strings.HasPrefix(selector, "text=") {
-		return p.clickByText(strings.TrimPrefix(selector, "text="))
+func (p *playwrightPage) Click(selector string, opts ClickOptions) error {
+	if !opts.Raw && strings.HasPrefix(selector, "text=") {
+		return wrapDetached(selector, p.clickByText(strings.TrimPrefix(selector, "text="), opts))
+	}
+	if opts.Nth != nil {
+		return wrapDetached(selector, clickNth(p.page.Locator(selector), selector, *opts.Nth))
 	}
-	return p.page.Click(selector)
+	return wrapDetached(selector, p.page.Click(selector))
 }
 
-func (p *playwrightPage) Fill(selector string, value string) error {
-	return p.page.Fill(selector, value)
+func clickNth(locator playwright.Locator, selector string, nth int) error {
+	count, err := countAfterFirst(locator)
+	if err != nil {
+		return err
+	}
+	if nth < 0 || nth >= count {
+		return fmt.Errorf("nth %d out of range: %s matched %d elements", nth, selector, count)
+	}
+	return locator.Nth(nth).Click()
+}
+
+func (p *playwrightPage) ClickPopup(selector string, opts ClickOptions) (Page, error) {
+	popup, err := p.page.ExpectPopup(func() error {
+		return p.Click(selector, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.session.wrap(popup), nil
 }
 
-func (p *playwrightPage) Screenshot(path string, fullPage bool, selector string) error {
-	if selector != "" {
-		locator := p.page.Locator(selector)
-		_, err := locator.Screenshot(playwright.LocatorScreenshotOptions{Path: playwright.String(path)})
+// countAfterFirst waits, within the selector timeout, for the first match to
+// be attached before counting, since Count alone does not wait and would miss
+// elements that are still rendering.
+func countAfterFirst(locator playwright.Locator) (int, error) {
+	err := locator.First().WaitFor(playwright.LocatorWaitForOptions{State: playwright.WaitForSelectorStateAttached})
+	if err != nil {
+		return 0, err
+	}
+	return locator.Count()
+}
+
+// Fill fills the match at opts.Nth, or the only match. Several matches without
+// Nth fail with a list of them instead of Playwright's strict-mode error.
+func (p *playwrightPage) Fill(selector string, value string, opts FillOptions) error {
+	locator, err := p.pickInput(selector, opts.Nth)
+	if err != nil {
 		return err
 	}
-	_, err := p.page.Screenshot(playwright.PageScreenshotOptions{Path: playwright.String(path), FullPage: playwright.Bool(fullPage)})
+	return wrapDetached(selector, locator.Fill(value))
+}
+
+// pickInput resolves selector to the form control at nth, or to its only
+// match when nth is nil.
+func (p *playwrightPage) pickInput(selector string, nth *int) (playwright.Locator, error) {
+	locator := p.page.Locator(selector)
+	count, err := countAfterFirst(locator)
+	if err != nil {
+		return nil, err
+	}
+	if nth != nil {
+		if *nth < 0 || *nth >= count {
+			return nil, fmt.Errorf("nth %d out of range: %s matched %d elements", *nth, selector, count)
+		}
+		return locator.Nth(*nth), nil
+	}
+	if count > 1 {
+		matches, err := locator.EvaluateAll(`(els) => els.map(i => ({
+  label: (` + inputLabelJS + `)(i),
+  name: i.name || "",
+  type: i.type || i.tagName.toLowerCase(),
+}))`)
+		if err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(matches)
+		if err != nil {
+			return nil, err
+		}
+		var inputs []ExtractInput
+		if err := json.Unmarshal(b, &inputs); err != nil {
+			return nil, err
+		}
+		return nil, ambiguousInputError(selector, inputs)
+	}
+	return locator, nil
+}
+
+// inputLabelJS names a form control by its label, aria-label, or placeholder.
+const inputLabelJS = `(i) => {
+    if (i.labels && i.labels.length) return (i.labels[0].innerText || "").trim();
+    return (i.getAttribute("aria-label") || i.placeholder || "").trim();
+  }`
+
+// DragTo drags the first match of from onto the first match of to.
+func (p *playwrightPage) DragTo(from string, to string) error {
+	return wrapDetached(from, p.page.Locator(from).First().DragTo(p.page.Locator(to).First()))
+}
+
+func (p *playwrightPage) Focus(selector string) error {
+	return wrapDetached(selector, p.page.Locator(selector).First().Focus())
+}
+
+// Blur removes focus from the first match, firing blur and change handlers
+// the way tabbing away would.
+func (p *playwrightPage) Blur(selector string) error {
+	return wrapDetached(selector, p.page.Locator(selector).First().Blur())
+}
+
+func (p *playwrightPage) MouseMove(x float64, y float64) error {
+	return p.page.Mouse().Move(x, y)
+}
+
+func (p *playwrightPage) FillByLabel(label string, value string) error {
+	return p.page.GetByLabel(label).Fill(value)
+}
+
+func (p *playwrightPage) SelectOptions(selector string, values []string, add bool) ([]string, error) {
+	locator := p.page.Locator(selector).First()
+	multiple, err := locator.Evaluate(`(el) => el instanceof HTMLSelectElement && el.multiple`, nil)
+	if err != nil {
+		return nil, err
+	}
+	isMultiple, _ := multiple.(bool)
+	if len(values) > 1 && !isMultiple {
+		return nil, fmt.Errorf("%s is not a multi-select; pass a single value", selector)
+	}
+	if add && isMultiple {
+		current, err := locator.Evaluate(`(el) => Array.from(el.selectedOptions).map((o) => o.value)`, nil)
+		if err != nil {
+			return nil, err
+		}
+		if list, ok := current.([]any); ok {
+			existing := make([]string, 0, len(list)+len(values))
+			for _, v := range list {
+				if s, ok := v.(string); ok {
+					existing = append(existing, s)
+				}
+			}
+			values = append(existing, values...)
+		}
+	}
+	return locator.SelectOption(playwright.SelectOptionValues{ValuesOrLabels: &values})
+}
+
+// SetInputFiles sets files on the match at opts.Nth, or the only match, like
+// Fill.
+func (p *playwrightPage) SetInputFiles(selector string, paths []string, opts UploadOptions) error {
+	locator, err := p.pickInput(selector, opts.Nth)
+	if err != nil {
+		return err
+	}
+	return wrapDetached(selector, locator.SetInputFiles(paths))
+}
+
+func (p *playwrightPage) Screenshot(path string, opts ScreenshotOptions) error {
+	var shotType *playwright.ScreenshotType
+	if opts.Type == "jpeg" {
+		shotType = playwright.ScreenshotTypeJpeg
+	}
+	if opts.Selector != "" {
+		locator := p.page.Locator(opts.Selector)
+		_, err := locator.Screenshot(playwright.LocatorScreenshotOptions{Path: playwright.String(path), Type: shotType, Quality: opts.Quality})
+		return err
+	}
+	shotOpts := playwright.PageScreenshotOptions{Path: playwright.String(path), FullPage: playwright.Bool(opts.FullPage), Type: shotType, Quality: opts.Quality}
+	if opts.Clip != nil {
+		shotOpts.Clip = &playwright.Rect{X: float64(opts.Clip.X), Y: float64(opts.Clip.Y), Width: float64(opts.Clip.Width), Height: float64(opts.Clip.Height)}
+	}
+	_, err := p.page.Screenshot(shotOpts)
+	return err
+}
+
+func (p *playwrightPage) PDF(path string, opts PDFOptions) error {
+	if p.browserName != "chromium" {
+		return fmt.Errorf("pdf is only supported in chromium, not %s", p.browserName)
+	}
+	pdfOpts := playwright.PagePdfOptions{
+		Path:            playwright.String(path),
+		Landscape:       playwright.Bool(opts.Landscape),
+		PrintBackground: playwright.Bool(opts.PrintBackground),
+	}
+	if opts.Format != "" {
+		pdfOpts.Format = playwright.String(opts.Format)
+	}
+	_, err := p.page.PDF(pdfOpts)
+	return err
+}
+
+func (p *playwrightPage) Highlight(selector string, color string) error {
+	count, err := p.page.Locator(selector).EvaluateAll(`(els, color) => {
+  for (const el of els) {
+    if (!el.hasAttribute("data-www-highlight")) {
+      el.setAttribute("data-www-highlight", JSON.stringify({ outline: el.style.outline, offset: el.style.outlineOffset }));
+    }
+    el.style.outline = "3px solid " + color;
+    el.style.outlineOffset = "2px";
+  }
+  return els.length;
+}`, color)
+	if err != nil {
+		return err
+	}
+	if n, ok := count.(int); ok && n == 0 {
+		return fmt.Errorf("%w for highlight selector %q", ErrNoMatch, selector)
+	}
+	return nil
+}
+
+func (p *playwrightPage) ClearHighlight() error {
+	_, err := p.page.Evaluate(`() => {
+  document.querySelectorAll("[data-www-highlight]").forEach(el => {
+    let prev = {};
+    try { prev = JSON.parse(el.getAttribute("data-www-highlight")) || {}; } catch (e) {}
+    el.style.outline = prev.outline || "";
+    el.style.outlineOffset = prev.offset || "";
+    el.removeAttribute("data-www-highlight");
+  });
+}`)
 	return err
 }
 
@@ -119,12 +509,16 @@ func (p *playwrightPage) Extract(options ExtractOptions) (ExtractResult, error)
 	v, err := p.page.Evaluate(`(opts) => {
   const selector = opts && opts.selector ? String(opts.selector) : "";
   const main = opts && opts.main;
+  const toMarkdown = `+markdownJS+`;
+  const dom = (`+shadowJS+`)(opts && opts.shadow);
+  const visible = `+visibleJS+`;
+  const ariaNodes = `+ariaJS+`;
   const pickRoot = () => {
-    if (selector) return document.querySelector(selector);
+    if (selector) return dom.query(selector);
     if (!main) return document.body;
-    const preferred = document.querySelector("[role=main]") || document.querySelector("main") || document.querySelector("article");
+    const preferred = dom.query("[role=main]") || dom.query("main") || dom.query("article");
     if (preferred) return preferred;
-    const candidates = Array.from(document.querySelectorAll("main, article, [role=main], #content, .content, .docs-content, .markdown, .markdown-body, section"));
+    const candidates = dom.queryAll("main, article, [role=main], #content, .content, .docs-content, .markdown, .markdown-body, section");
     let best = null;
     let bestLen = 0;
     for (const el of candidates) {
@@ -137,22 +531,32 @@ func (p *playwrightPage) Extract(options ExtractOptions) (ExtractResult, error)
     return best || document.body;
   };
   let root = pickRoot();
-  let text = root ? (root.innerText || root.textContent || "") : "";
+  let text = root ? dom.text(root) : "";
   if (main && (!text || !text.trim()) && root !== document.body) {
     root = document.body;
-    text = root ? (root.innerText || root.textContent || "") : "";
+    text = root ? dom.text(root) : "";
+  }
+  if (root && opts && opts.format === "markdown") {
+    text = toMarkdown(root, opts && opts.shadow);
   }
-  const links = Array.from(document.querySelectorAll("a")).map(a => ({ text: a.innerText || "", href: a.href || "" }));
-  const buttons = Array.from(document.querySelectorAll("button, [role=button]")).map(b => ({ text: b.innerText || "" }));
-  const inputs = Array.from(document.querySelectorAll("input, textarea, select")).map(i => ({
+  const links = dom.queryAll("a").filter(a => !(opts && opts.onlyVisible) || visible(a)).map(a => ({ text: a.innerText || "", href: a.href || "", rel: a.rel || "", target: a.target || "" }));
+  const buttons = dom.queryAll("button, [role=button]").map(b => ({ text: b.innerText || "" }));
+  const inputs = dom.queryAll("input, textarea, select").map(i => ({
     label: i.labels && i.labels.length ? i.labels[0].innerText || "" : "",
     name: i.name || "",
     type: i.type || i.tagName.toLowerCase(),
   }));
   const meta = {};
   document.querySelectorAll('meta[name]').forEach(m => { meta[m.name] = m.content || ""; });
-  return { url: location.href, title: document.title || "", text, links, buttons, inputs, meta };
-}`, map[string]any{"selector": options.Selector, "main": options.Main})
+  const canonicalLink = document.querySelector('link[rel=canonical]');
+  const canonical = canonicalLink ? canonicalLink.href || "" : "";
+  const langMeta = document.querySelector('meta[http-equiv="content-language" i]');
+  const lang = document.documentElement.lang || (langMeta ? langMeta.content || "" : "");
+  const bylineEl = dom.query('[rel=author], [itemprop=author], .byline, .author');
+  const byline = meta.author || (bylineEl ? (bylineEl.innerText || bylineEl.textContent || "").replace(/\s+/g, " ").trim() : "");
+  const aria = opts && opts.aria ? ariaNodes(dom) : undefined;
+  return { url: location.href, title: document.title || "", text, links, buttons, inputs, meta, canonical, lang, byline, aria };
+}`, map[string]any{"selector": options.Selector, "main": options.Main, "format": options.Format, "shadow": options.Shadow, "onlyVisible": options.OnlyVisible, "aria": options.Aria})
 	if err != nil {
 		return result, err
 	}
@@ -166,16 +570,20 @@ func (p *playwrightPage) Extract(options ExtractOptions) (ExtractResult, error)
 	return result, nil
 }
 
-func (p *playwrightPage) Links(filter string) ([]ExtractLink, error) {
-	value, err := p.page.Evaluate(`(f) => {
-  const filter = f ? String(f).toLowerCase() : "";
-  const links = Array.from(document.querySelectorAll("a")).map(a => ({
+func (p *playwrightPage) Links(opts LinksOptions) ([]ExtractLink, error) {
+	value, err := p.page.Evaluate(`(opts) => {
+  const filter = opts.filter ? String(opts.filter).toLowerCase() : "";
+  const dom = (`+shadowJS+`)(opts.shadow);
+  const visible = `+visibleJS+`;
+  const links = dom.queryAll("a").filter(a => !opts.onlyVisible || visible(a)).map(a => ({
     text: (a.innerText || "").trim(),
-    href: a.href || ""
+    href: a.href || "",
+    rel: a.rel || "",
+    target: a.target || ""
   })).filter(l => l.text && l.href);
   if (!filter) return links;
   return links.filter(l => l.text.toLowerCase().includes(filter));
-}`, filter)
+}`, map[string]any{"filter": opts.Filter, "shadow": opts.Shadow, "onlyVisible": opts.OnlyVisible})
 	if err != nil {
 		return nil, err
 	}
@@ -190,16 +598,363 @@ func (p *playwrightPage) Links(filter string) ([]ExtractLink, error) {
 	return links, nil
 }
 
+func (p *playwrightPage) Tables(selector string) ([]ExtractTable, error) {
+	value, err := p.page.Evaluate(`(selector) => {
+  let tables = [];
+  if (selector) {
+    const root = document.querySelector(selector);
+    if (!root) return [];
+    tables = root.tagName === "TABLE" ? [root] : Array.from(root.querySelectorAll("table"));
+  } else {
+    tables = Array.from(document.querySelectorAll("table"));
+  }
+  const cellText = (cell) => (cell.innerText || cell.textContent || "").replace(/\s+/g, " ").trim();
+  const expand = (row) => {
+    const values = [];
+    for (const cell of row.cells) {
+      const span = Math.max(1, Number(cell.colSpan) || 1);
+      const text = cellText(cell);
+      for (let i = 0; i < span; i++) values.push(text);
+    }
+    return values;
+  };
+  return tables.map(table => {
+    const rows = Array.from(table.rows);
+    let headerRow = table.tHead && table.tHead.rows.length ? table.tHead.rows[table.tHead.rows.length - 1] : null;
+    if (!headerRow && rows.length && Array.from(rows[0].cells).every(c => c.tagName === "TH")) headerRow = rows[0];
+    const raw = headerRow ? expand(headerRow) : [];
+    const bodyRows = rows.filter(r => r !== headerRow && !(table.tHead && table.tHead.contains(r)));
+    const width = Math.max(raw.length, ...bodyRows.map(r => expand(r).length), 0);
+    const seen = {};
+    const headers = [];
+    for (let i = 0; i < width; i++) {
+      let name = raw[i] || "col" + (i + 1);
+      if (seen[name]) {
+        seen[name]++;
+        name = name + "_" + seen[name];
+      } else {
+        seen[name] = 1;
+      }
+      headers.push(name);
+    }
+    const data = bodyRows.map(r => {
+      const values = expand(r);
+      const obj = {};
+      headers.forEach((h, i) => {
+        obj[h] = i < values.length ? values[i] : (values.length ? values[values.length - 1] : "");
+      });
+      return obj;
+    }).filter(obj => Object.values(obj).some(v => v));
+    const caption = table.caption ? cellText(table.caption) : "";
+    return { caption, headers, rows: data };
+  });
+}`, selector)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var tables []ExtractTable
+	if err := json.Unmarshal(b, &tables); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+func (p *playwrightPage) Forms() ([]ExtractForm, error) {
+	value, err := p.page.Evaluate(`() => {
+  const label = ` + inputLabelJS + `;
+  const isSubmit = (el) => {
+    const tag = el.tagName.toLowerCase();
+    const type = (el.getAttribute("type") || "").toLowerCase();
+    if (tag === "button") return type === "" || type === "submit";
+    return tag === "input" && (type === "submit" || type === "image");
+  };
+  return Array.from(document.forms).map(form => {
+    const inputs = [];
+    const submits = [];
+    for (const el of form.elements) {
+      if (isSubmit(el)) {
+        const text = (el.innerText || el.value || el.getAttribute("aria-label") || "").trim();
+        if (text) submits.push(text);
+        continue;
+      }
+      const tag = el.tagName.toLowerCase();
+      if (tag !== "input" && tag !== "textarea" && tag !== "select") continue;
+      inputs.push({ label: label(el), name: el.name || "", type: el.type || tag });
+    }
+    return {
+      id: form.id || "",
+      name: form.getAttribute("name") || "",
+      action: form.action || location.href,
+      method: (form.getAttribute("method") || "get").toLowerCase(),
+      inputs,
+      submits,
+    };
+  });
+}`)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var forms []ExtractForm
+	if err := json.Unmarshal(b, &forms); err != nil {
+		return nil, err
+	}
+	return forms, nil
+}
+
+func (p *playwrightPage) Outline() ([]Heading, error) {
+	value, err := p.page.Evaluate(`() => {
+  const visible = (el) => {
+    const style = window.getComputedStyle(el);
+    if (style.display === "none" || style.visibility === "hidden") return false;
+    return el.getClientRects().length > 0;
+  };
+  const headings = [];
+  for (const el of document.querySelectorAll("h1, h2, h3, h4, h5, h6")) {
+    const text = (el.innerText || "").replace(/\s+/g, " ").trim();
+    if (!text || !visible(el)) continue;
+    headings.push({ level: Number(el.tagName.substring(1)), text, id: el.id || "" });
+  }
+  return headings;
+}`)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var headings []Heading
+	if err := json.Unmarshal(b, &headings); err != nil {
+		return nil, err
+	}
+	return headings, nil
+}
+
+func (p *playwrightPage) Content(selector string) (string, error) {
+	if selector == "" {
+		return p.page.Content()
+	}
+	locator := p.page.Locator(selector)
+	count, err := locator.Count()
+	if err != nil {
+		return "", err
+	}
+	if count == 0 {
+		return "", fmt.Errorf("%w for %s", ErrNoMatch, selector)
+	}
+	value, err := locator.First().Evaluate(`(el) => el.outerHTML`, nil)
+	if err != nil {
+		return "", err
+	}
+	html, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected outerHTML result %T", value)
+	}
+	return html, nil
+}
+
+func (p *playwrightPage) BoundingBox(selector string) (*Box, error) {
+	locator := p.page.Locator(selector)
+	count, err := locator.Count()
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	rect, err := locator.First().BoundingBox()
+	if err != nil {
+		return nil, err
+	}
+	if rect == nil {
+		return nil, nil
+	}
+	return &Box{X: rect.X, Y: rect.Y, Width: rect.Width, Height: rect.Height}, nil
+}
+
+// dismissTimeoutMs bounds each auto-dismiss click so a banner button that is
+// visible but never actionable cannot stall navigation.
+const dismissTimeoutMs = 1000
+
+// Dismiss clicks the first visible match of each selector and returns the
+// selectors it clicked. It is best-effort: misses and click errors are skipped.
+func (p *playwrightPage) Dismiss(selectors []string) []string {
+	var clicked []string
+	for _, selector := range selectors {
+		locator := p.page.Locator(selector).First()
+		visible, err := locator.IsVisible()
+		if err != nil || !visible {
+			continue
+		}
+		if err := locator.Click(playwright.LocatorClickOptions{Timeout: playwright.Float(dismissTimeoutMs)}); err != nil {
+			continue
+		}
+		clicked = append(clicked, selector)
+	}
+	return clicked
+}
+
+func (p *playwrightPage) Count(selector string) (int, error) {
+	return p.page.Locator(selector).Count()
+}
+
+func (p *playwrightPage) TextContent(selector string) (ElementText, error) {
+	locator := p.page.Locator(selector)
+	count, err := locator.Count()
+	if err != nil {
+		return ElementText{}, err
+	}
+	if count == 0 {
+		return ElementText{}, nil
+	}
+	text, err := locator.First().InnerText()
+	if err != nil {
+		return ElementText{}, err
+	}
+	return ElementText{Text: text, Count: count}, nil
+}
+
+func (p *playwrightPage) Attribute(selector string, name string) (ElementAttr, error) {
+	locator := p.page.Locator(selector)
+	count, err := locator.Count()
+	if err != nil {
+		return ElementAttr{}, err
+	}
+	if count == 0 {
+		return ElementAttr{}, nil
+	}
+	value, err := locator.First().GetAttribute(name)
+	if err != nil {
+		return ElementAttr{}, err
+	}
+	return ElementAttr{Value: value, Found: true}, nil
+}
+
+func (p *playwrightPage) Attributes(selector string) ([]ElementAttrs, error) {
+	value, err := p.page.Locator(selector).EvaluateAll(`(els) => els.map((el) => {
+  const attributes = {};
+  for (const attr of el.attributes) attributes[attr.name] = attr.value;
+  return { attributes, text: (el.innerText || el.textContent || "").trim() };
+})`)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result []ElementAttrs
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (p *playwrightPage) StorageItem(opts StorageOptions) (StorageResult, error) {
+	var result StorageResult
+	value, err := p.page.Evaluate(`(opts) => {
+  const store = opts.session ? window.sessionStorage : window.localStorage;
+  const result = { origin: location.origin, key: opts.key, found: false };
+  if (opts.op === "get") {
+    const value = store.getItem(opts.key);
+    if (value !== null) {
+      result.value = value;
+      result.found = true;
+    }
+  } else if (opts.op === "set") {
+    store.setItem(opts.key, opts.value);
+    result.value = opts.value;
+    result.found = true;
+  } else if (opts.op === "list") {
+    result.items = {};
+    for (let i = 0; i < store.length; i++) {
+      const key = store.key(i);
+      result.items[key] = store.getItem(key);
+    }
+    result.found = store.length > 0;
+  } else {
+    throw new Error("unknown storage op " + opts.op);
+  }
+  return result;
+}`, map[string]any{"op": opts.Op, "key": opts.Key, "value": opts.Value, "session": opts.Session})
+	if err != nil {
+		return result, err
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return result, err
+	}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
 func (p *playwrightPage) SetTimeout(ms int) error {
 	if ms <= 0 {
 		return nil
 	}
+	p.timeoutMs.Store(int64(ms))
+	p.page.SetDefaultNavigationTimeout(float64(ms))
 	p.page.SetDefaultTimeout(float64(ms))
 	return nil
 }
 
-func (p *playwrightPage) Eval(js string) (json.RawMessage, error) {
-	v, err := p.page.Evaluate(js)
+func (p *playwrightPage) SetSelectorTimeout(ms int) error {
+	if ms <= 0 {
+		return nil
+	}
+	p.page.SetDefaultTimeout(float64(ms))
+	return nil
+}
+
+func (p *playwrightPage) WaitForLoadState(state string, timeoutMs int) error {
+	opts := playwright.PageWaitForLoadStateOptions{State: (*playwright.LoadState)(playwright.String(state))}
+	if timeoutMs > 0 {
+		opts.Timeout = playwright.Float(float64(timeoutMs))
+	}
+	return p.page.WaitForLoadState(opts)
+}
+
+// WaitForURL waits until the page URL matches pattern, a glob such as
+// "**/dashboard" or, with regex, a Go regular expression.
+func (p *playwrightPage) WaitForURL(pattern string, regex bool, timeoutMs int) error {
+	var match any = pattern
+	if regex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		match = re
+	}
+	opts := playwright.PageWaitForURLOptions{}
+	if timeoutMs > 0 {
+		opts.Timeout = playwright.Float(float64(timeoutMs))
+	}
+	return p.page.WaitForURL(match, opts)
+}
+
+// Eval evaluates js in the page. A non-empty arg is decoded from JSON and
+// passed as the function's argument, so js should be a function expression
+// like "(a) => a.n * 2".
+func (p *playwrightPage) Eval(js string, arg json.RawMessage) (json.RawMessage, error) {
+	var args []any
+	if len(arg) > 0 {
+		var v any
+		if err := json.Unmarshal(arg, &v); err != nil {
+			return nil, fmt.Errorf("invalid eval arg: %w", err)
+		}
+		args = append(args, v)
+	}
+	v, err := p.page.Evaluate(js, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -218,34 +973,68 @@ func (p *playwrightPage) Title() (string, error) {
 	return p.page.Title()
 }
 
+func (p *playwrightPage) BringToFront() error {
+	return p.page.BringToFront()
+}
+
+func (p *playwrightPage) OnClose(fn func()) {
+	p.page.OnClose(func(playwright.Page) {
+		fn()
+	})
+}
+
+func (p *playwrightPage) OnCrash(fn func()) {
+	p.page.OnCrash(func(playwright.Page) {
+		fn()
+	})
+}
+
 func (p *playwrightPage) Close() error {
 	return p.page.Close()
 }
 
-func (p *playwrightPage) clickByText(text string) error {
-	if err := p.page.GetByText(text, playwright.PageGetByTextOptions{Exact: playwright.Bool(true)}).Click(); err == nil {
-		return nil
-	}
-	if err := p.page.GetByText(text, playwright.PageGetByTextOptions{Exact: playwright.Bool(false)}).Click(); err == nil {
-		return nil
+func (p *playwrightPage) clickByText(text string, opts ClickOptions) error {
+	textLocators := []playwright.Locator{
+		p.page.GetByText(text, playwright.PageGetByTextOptions{Exact: playwright.Bool(true)}),
 	}
-	escaped := strings.ReplaceAll(text, "\"", "\\\"")
-	selectors := []string{
-		fmt.Sprintf("a:has-text(\"%s\")", escaped),
-		fmt.Sprintf("button:has-text(\"%s\")", escaped),
-		fmt.Sprintf("[role=button]:has-text(\"%s\")", escaped),
-		fmt.Sprintf("label:has-text(\"%s\")", escaped),
+	var fallbacks []playwright.Locator
+	if !opts.Exact {
+		textLocators = append(textLocators, p.page.GetByText(text, playwright.PageGetByTextOptions{Exact: playwright.Bool(false)}))
+		escaped := strings.ReplaceAll(text, "\"", "\\\"")
+		for _, sel := range []string{
+			fmt.Sprintf("a:has-text(\"%s\")", escaped),
+			fmt.Sprintf("button:has-text(\"%s\")", escaped),
+			fmt.Sprintf("[role=button]:has-text(\"%s\")", escaped),
+			fmt.Sprintf("label:has-text(\"%s\")", escaped),
+		} {
+			fallbacks = append(fallbacks, p.page.Locator(sel))
+		}
 	}
-	for _, sel := range selectors {
-		if err := p.page.Locator(sel).First().Click(); err == nil {
-			return nil
+	if opts.Nth != nil {
+		// Pick the first strategy that matches anything so the index refers
+		// to a stable list instead of whichever fallback happens to succeed.
+		for _, locator := range append(textLocators, fallbacks...) {
+			if count, err := locator.Count(); err == nil && count > 0 {
+				return clickNth(locator, fmt.Sprintf("text=%q", text), *opts.Nth)
+			}
+		}
+	} else {
+		for _, locator := range textLocators {
+			if err := locator.Click(); err == nil {
+				return nil
+			}
+		}
+		for _, locator := range fallbacks {
+			if err := locator.First().Click(); err == nil {
+				return nil
+			}
 		}
 	}
 	suggestion, sErr := p.suggestText(text)
 	if sErr == nil && suggestion != "" {
-		return fmt.Errorf("no match for text=%q. did you mean %q?", text, suggestion)
+		return fmt.Errorf("%w for text=%q. did you mean %q?", ErrNoMatch, text, suggestion)
 	}
-	return fmt.Errorf("no match for text=%q", text)
+	return fmt.Errorf("%w for text=%q", ErrNoMatch, text)
 }
 
 func (p *playwrightPage) suggestText(text string) (string, error) {
@@ -294,6 +1083,197 @@ func (p *playwrightPage) suggestText(text string) (string, error) {
 	return best, nil
 }
 
+const shadowJS = `(enabled) => {
+  const roots = [document];
+  if (enabled) {
+    for (let i = 0; i < roots.length; i++) {
+      for (const el of roots[i].querySelectorAll("*")) {
+        if (el.shadowRoot) roots.push(el.shadowRoot);
+      }
+    }
+  }
+  const queryAll = (sel) => roots.flatMap(r => Array.from(r.querySelectorAll(sel)));
+  const query = (sel) => {
+    for (const r of roots) {
+      const el = r.querySelector(sel);
+      if (el) return el;
+    }
+    return null;
+  };
+  const skip = new Set(["SCRIPT", "STYLE", "NOSCRIPT", "TEMPLATE"]);
+  const shadowText = (host) => {
+    const parts = [];
+    for (const child of host.shadowRoot.children) {
+      if (skip.has(child.tagName)) continue;
+      parts.push(child.innerText || "");
+    }
+    for (const el of host.shadowRoot.querySelectorAll("*")) {
+      if (el.shadowRoot) parts.push(shadowText(el));
+    }
+    return parts.map(p => p.trim()).filter(Boolean).join("\n");
+  };
+  const text = (root) => {
+    const base = root.innerText || root.textContent || "";
+    if (!enabled) return base;
+    const hosts = [root, ...root.querySelectorAll("*")].filter(el => el.shadowRoot);
+    return [base, ...hosts.map(shadowText)].map(p => p.trim()).filter(Boolean).join("\n");
+  };
+  return { query, queryAll, text };
+}`
+
+const visibleJS = `(el) => {
+  if (!el.isConnected) return false;
+  const style = getComputedStyle(el);
+  if (style.display === "none" || style.visibility === "hidden" || style.visibility === "collapse" || Number(style.opacity) === 0) return false;
+  if (el.offsetParent === null && style.position !== "fixed") return false;
+  const rect = el.getBoundingClientRect();
+  if (rect.width <= 0 || rect.height <= 0) return false;
+  return rect.right + scrollX > 0 && rect.bottom + scrollY > 0;
+}`
+
+// ariaJS lists landmark and interactive elements with their role, explicit or
+// implied by the tag, and an approximate accessible name: aria-labelledby,
+// aria-label, a label, alt or title, then the element's own text.
+const ariaJS = `(dom) => {
+  const landmarks = new Set(["banner", "complementary", "contentinfo", "form", "main", "navigation", "region", "search"]);
+  const widgets = new Set(["button", "checkbox", "combobox", "link", "listbox", "menuitem", "option", "radio", "searchbox", "slider", "spinbutton", "switch", "tab", "textbox"]);
+  const inputRoles = { button: "button", submit: "button", reset: "button", image: "button", checkbox: "checkbox", radio: "radio", range: "slider", number: "spinbutton", search: "searchbox" };
+  const clean = (s) => (s || "").replace(/\s+/g, " ").trim();
+  const implicitRole = (el) => {
+    const tag = el.tagName.toLowerCase();
+    switch (tag) {
+      case "a": return el.hasAttribute("href") ? "link" : "";
+      case "button": case "summary": return "button";
+      case "input": return el.type === "hidden" ? "" : inputRoles[el.type] || "textbox";
+      case "textarea": return "textbox";
+      case "select": return el.multiple || el.size > 1 ? "listbox" : "combobox";
+      case "option": return "option";
+      case "nav": return "navigation";
+      case "main": return "main";
+      case "aside": return "complementary";
+      case "header": return el.closest("article, aside, main, nav, section") ? "" : "banner";
+      case "footer": return el.closest("article, aside, main, nav, section") ? "" : "contentinfo";
+      case "form": case "section": return el.hasAttribute("aria-label") || el.hasAttribute("aria-labelledby") ? (tag === "form" ? "form" : "region") : "";
+    }
+    return "";
+  };
+  const accessibleName = (el, role) => {
+    const ids = (el.getAttribute("aria-labelledby") || "").split(/\s+/).filter(Boolean);
+    const labelled = clean(ids.map(id => { const ref = document.getElementById(id); return ref ? ref.textContent : ""; }).join(" "));
+    if (labelled) return labelled;
+    const label = clean(el.getAttribute("aria-label"));
+    if (label) return label;
+    if (el.labels && el.labels.length) return clean(el.labels[0].innerText || el.labels[0].textContent);
+    const alt = clean(el.getAttribute("alt") || el.getAttribute("title"));
+    if (alt) return alt;
+    if (el.tagName === "INPUT" && ["button", "submit", "reset"].includes(el.type)) return clean(el.value);
+    if (el.tagName === "INPUT" || el.tagName === "TEXTAREA") return clean(el.placeholder);
+    return landmarks.has(role) ? "" : clean(el.innerText || el.textContent).slice(0, 200);
+  };
+  const hidden = (el) => el.closest("[hidden], [aria-hidden=true]") !== null;
+  const nodes = [];
+  for (const el of dom.queryAll("*")) {
+    const role = (el.getAttribute("role") || "").trim().split(/\s+/)[0] || implicitRole(el);
+    if (!role || !(landmarks.has(role) || widgets.has(role)) || hidden(el)) continue;
+    nodes.push({ role, name: accessibleName(el, role) });
+  }
+  return nodes;
+}`
+
+const markdownJS = `(root, shadow) => {
+  const skip = new Set(["script", "style", "noscript", "template", "svg", "head"]);
+  const blockTags = new Set(["address", "article", "aside", "blockquote", "dd", "details", "div", "dl", "dt", "fieldset", "figcaption", "figure", "footer", "form", "h1", "h2", "h3", "h4", "h5", "h6", "header", "hr", "li", "main", "nav", "ol", "p", "pre", "section", "summary", "table", "tbody", "td", "tfoot", "th", "thead", "tr", "ul"]);
+  const isBlock = (node) => node.nodeType === Node.ELEMENT_NODE && blockTags.has(node.tagName.toLowerCase());
+  const isHidden = (el) => el.hidden || el.getAttribute("aria-hidden") === "true";
+  const clean = (s) => s.replace(/[ \t\r\n]+/g, " ").trim();
+  const children = (node) => {
+    if (shadow && node.shadowRoot) return Array.from(node.shadowRoot.childNodes);
+    if (shadow && node.tagName === "SLOT") {
+      const assigned = node.assignedNodes({ flatten: true });
+      if (assigned.length) return assigned;
+    }
+    return Array.from(node.childNodes);
+  };
+  const blockSelector = "p, div, ul, ol, pre, table, h1, h2, h3, h4, h5, h6, blockquote, section, article";
+  const hasBlocks = (el) => !!(el.querySelector(blockSelector) || (shadow && el.shadowRoot && el.shadowRoot.querySelector(blockSelector)));
+  const inline = (node) => {
+    if (node.nodeType === Node.TEXT_NODE) return node.textContent.replace(/[ \t\r\n]+/g, " ");
+    if (node.nodeType !== Node.ELEMENT_NODE) return "";
+    const tag = node.tagName.toLowerCase();
+    if (skip.has(tag) || isHidden(node)) return "";
+    if (tag === "br") return "\n";
+    if (tag === "img") return node.alt ? "![" + clean(node.alt) + "](" + (node.src || "") + ")" : "";
+    const inner = children(node).map(inline).join("");
+    const t = clean(inner);
+    if (!t) return "";
+    if (tag === "a" && node.href && !node.href.startsWith("javascript:")) return "[" + t + "](" + node.href + ")";
+    if (tag === "strong" || tag === "b") return "**" + t + "**";
+    if (tag === "em" || tag === "i") return "_" + t + "_";
+    if (tag === "code") return "` + "`" + `" + t + "` + "`" + `";
+    return inner;
+  };
+  const list = (el, depth) => {
+    const ordered = el.tagName.toLowerCase() === "ol";
+    const lines = [];
+    let n = 0;
+    for (const li of el.children) {
+      if (li.tagName.toLowerCase() !== "li" || isHidden(li)) continue;
+      n++;
+      const nested = [];
+      const parts = [];
+      for (const child of children(li)) {
+        const tag = child.nodeType === Node.ELEMENT_NODE ? child.tagName.toLowerCase() : "";
+        if (tag === "ul" || tag === "ol") nested.push(list(child, depth + 1));
+        else parts.push(inline(child));
+      }
+      const marker = ordered ? n + ". " : "- ";
+      lines.push("  ".repeat(depth) + marker + clean(parts.join("")));
+      lines.push(...nested.filter(Boolean));
+    }
+    return lines.join("\n");
+  };
+  const blocks = [];
+  const walk = (el) => {
+    let run = [];
+    const flush = () => {
+      const t = clean(run.join(""));
+      if (t) blocks.push(t);
+      run = [];
+    };
+    for (const child of children(el)) {
+      if (!isBlock(child) && !(child.nodeType === Node.ELEMENT_NODE && hasBlocks(child))) {
+        run.push(inline(child));
+        continue;
+      }
+      flush();
+      if (skip.has(child.tagName.toLowerCase()) || isHidden(child)) continue;
+      const tag = child.tagName.toLowerCase();
+      const h = /^h([1-6])$/.exec(tag);
+      if (h) {
+        const t = clean(inline(child));
+        if (t) blocks.push("#".repeat(Number(h[1])) + " " + t);
+      } else if (tag === "ul" || tag === "ol") {
+        const l = list(child, 0);
+        if (l) blocks.push(l);
+      } else if (tag === "pre") {
+        blocks.push("` + "```" + `\n" + (child.innerText || child.textContent || "").replace(/\n+$/, "") + "\n` + "```" + `");
+      } else if (tag === "blockquote") {
+        const start = blocks.length;
+        walk(child);
+        const quoted = blocks.splice(start).join("\n\n");
+        if (quoted) blocks.push(quoted.split("\n").map(l => "> " + l).join("\n"));
+      } else if (tag === "hr") {
+        blocks.push("---");
+      } else {
+        walk(child);
+      }
+    }
+    flush();
+  };
+  walk(root);
+  return blocks.join("\n\n");
+}`
+
 func normalizeText(s string) string {
 	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
 }
@@ -343,6 +1323,58 @@ func minInt(a, b, c int) int {
 	return c
 }
 
+// ValidateDevice reports whether name is a known Playwright device preset. It
+// starts the driver without launching a browser; when the driver is not
+// available the name is accepted and Start reports the problem instead.
+func ValidateDevice(name string) error {
+	pw, err := playwright.Run()
+	if err != nil {
+		return nil
+	}
+	defer pw.Stop()
+	_, err = lookupDevice(pw.Devices, name)
+	return err
+}
+
+func lookupDevice(devices map[string]*playwright.DeviceDescriptor, name string) (*playwright.DeviceDescriptor, error) {
+	if device, ok := devices[name]; ok {
+		return device, nil
+	}
+	names := make([]string, 0, len(devices))
+	for candidate, device := range devices {
+		if strings.EqualFold(candidate, name) {
+			return device, nil
+		}
+		names = append(names, candidate)
+	}
+	matches := closestMatches(name, names, 3)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("unknown device %q", name)
+	}
+	quoted := make([]string, len(matches))
+	for i, match := range matches {
+		quoted[i] = strconv.Quote(match)
+	}
+	return nil, fmt.Errorf("unknown device %q. did you mean %s?", name, strings.Join(quoted, ", "))
+}
+
+func closestMatches(query string, candidates []string, limit int) []string {
+	normalized := normalizeText(query)
+	sorted := append([]string(nil), candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		di := levenshteinDistance(normalized, normalizeText(sorted[i]))
+		dj := levenshteinDistance(normalized, normalizeText(sorted[j]))
+		if di != dj {
+			return di < dj
+		}
+		return sorted[i] < sorted[j]
+	})
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	return sorted
+}
+
 func isMissingChannelErr(err error) bool {
 	if err == nil {
 		return false
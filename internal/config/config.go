@@ -1,9 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,24 +13,91 @@ import (
 )
 
 type Config struct {
-	ProfileDir string
-	DefaultTTL time.Duration
+	ProfileDir     string
+	DefaultTTL     time.Duration
+	DefaultTimeout time.Duration
+	// IdleTimeout is nil when unset; zero turns the daemon's idle timeout off.
+	IdleTimeout *time.Duration
+	SocketMode  os.FileMode
+	ProfileMode os.FileMode
+	Aliases     map[string]string
+	Profiles    map[string]ProfileDefaults
+	AutoDismiss []string
+	// AutoPrune removes expired profiles in the background of every command.
+	AutoPrune bool
+	// MaxAge expires profiles unused for longer than it, whatever their TTL.
+	MaxAge time.Duration
+	// Files lists the config files that were read, in load order.
+	Files []string
+}
+
+// DefaultAutoDismiss lists the accept buttons of common consent frameworks
+// that start --auto-dismiss clicks after each navigation.
+var DefaultAutoDismiss = []string{
+	"#onetrust-accept-btn-handler",
+	"#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll",
+	"#didomi-notice-agree-button",
+	"#truste-consent-button",
+	"button.fc-cta-consent",
+	".qc-cmp2-summary-buttons button[mode=primary]",
+	".cc-window .cc-btn.cc-allow",
+}
+
+type ProfileDefaults struct {
+	Browser   string
+	Channel   string
+	Headless  *bool
+	TTL       *time.Duration
+	UserAgent string
 }
 
 type rawConfig struct {
-	ProfileDir string `toml:"profile_dir"`
-	DefaultTTL string `toml:"default_ttl"`
+	ProfileDir     string                        `toml:"profile_dir"`
+	DefaultTTL     string                        `toml:"default_ttl"`
+	DefaultTimeout string                        `toml:"default_timeout"`
+	IdleTimeout    string                        `toml:"idle_timeout"`
+	SocketMode     string                        `toml:"socket_mode"`
+	ProfileMode    string                        `toml:"profile_mode"`
+	Aliases        map[string]string             `toml:"aliases"`
+	Profiles       map[string]rawProfileDefaults `toml:"profiles"`
+	AutoDismiss    []string                      `toml:"auto_dismiss"`
+	AutoPrune      *bool                         `toml:"auto_prune"`
+	MaxAge         string                        `toml:"max_age"`
+}
+
+type rawProfileDefaults struct {
+	Browser   string `toml:"browser"`
+	Channel   string `toml:"channel"`
+	Headless  *bool  `toml:"headless"`
+	TTL       string `toml:"ttl"`
+	UserAgent string `toml:"user_agent"`
+}
+
+var systemConfigPaths = []string{
+	"/opt/homebrew/etc/www/config.toml",
+	"/usr/local/etc/www/config.toml",
 }
 
-func Load(profileDirOverride string, defaultTTLOverride string) (Config, error) {
+func Load(configPath string, profileDirOverride string, defaultTTLOverride string) (Config, error) {
 	cfg := Config{
-		ProfileDir: defaultProfileDir(),
-		DefaultTTL: 14 * 24 * time.Hour,
+		ProfileDir:  defaultProfileDir(),
+		DefaultTTL:  14 * 24 * time.Hour,
+		AutoDismiss: DefaultAutoDismiss,
 	}
 
 	if err := loadSystemConfig(&cfg); err != nil {
 		return Config{}, err
 	}
+	for _, path := range userConfigPaths() {
+		if err := loadConfigFile(&cfg, path, false); err != nil {
+			return Config{}, err
+		}
+	}
+	if strings.TrimSpace(configPath) != "" {
+		if err := loadConfigFile(&cfg, configPath, true); err != nil {
+			return Config{}, err
+		}
+	}
 
 	if v := strings.TrimSpace(os.Getenv("WWW_PROFILE_DIR")); v != "" {
 		cfg.ProfileDir = v
@@ -50,32 +119,146 @@ func Load(profileDirOverride string, defaultTTLOverride string) (Config, error)
 	return cfg, nil
 }
 
-func loadSystemConfig(cfg *Config) error {
-	paths := []string{
-		"/opt/homebrew/etc/www/config.toml",
-		"/usr/local/etc/www/config.toml",
+func (c Config) ResolveProfile(name string) (string, bool) {
+	target, ok := c.Aliases[name]
+	if !ok || strings.TrimSpace(target) == "" {
+		return name, false
 	}
-	for _, path := range paths {
+	return target, true
+}
+
+func loadSystemConfig(cfg *Config) error {
+	for _, path := range systemConfigPaths {
 		if _, err := os.Stat(path); err != nil {
 			continue
 		}
-		var raw rawConfig
-		if _, err := toml.DecodeFile(path, &raw); err != nil {
-			return err
+		return loadConfigFile(cfg, path, true)
+	}
+	return nil
+}
+
+func userConfigPaths() []string {
+	paths := []string{}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".config", "www", "config.toml"))
+	}
+	if xdg := strings.TrimSpace(os.Getenv("XDG_CONFIG_HOME")); xdg != "" {
+		path := filepath.Join(xdg, "www", "config.toml")
+		if len(paths) == 0 || paths[0] != path {
+			paths = append(paths, path)
 		}
-		if raw.ProfileDir != "" {
-			cfg.ProfileDir = raw.ProfileDir
+	}
+	return paths
+}
+
+func loadConfigFile(cfg *Config, path string, required bool) error {
+	if _, err := os.Stat(path); err != nil {
+		if !required && os.IsNotExist(err) {
+			return nil
 		}
-		if raw.DefaultTTL != "" {
-			if d, err := time.ParseDuration(raw.DefaultTTL); err == nil {
-				cfg.DefaultTTL = d
+		return err
+	}
+	var raw rawConfig
+	if _, err := toml.DecodeFile(path, &raw); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.Files = append(cfg.Files, path)
+	if raw.ProfileDir != "" {
+		cfg.ProfileDir = raw.ProfileDir
+	}
+	if raw.DefaultTTL != "" {
+		if d, err := time.ParseDuration(raw.DefaultTTL); err == nil {
+			cfg.DefaultTTL = d
+		}
+	}
+	if raw.DefaultTimeout != "" {
+		d, err := time.ParseDuration(raw.DefaultTimeout)
+		if err != nil {
+			return fmt.Errorf("%s: default_timeout: %w", path, err)
+		}
+		cfg.DefaultTimeout = d
+	}
+	if raw.IdleTimeout != "" {
+		d, err := time.ParseDuration(raw.IdleTimeout)
+		if err != nil {
+			return fmt.Errorf("%s: idle_timeout: %w", path, err)
+		}
+		cfg.IdleTimeout = &d
+	}
+	if raw.SocketMode != "" {
+		mode, err := ParseFileMode(raw.SocketMode)
+		if err != nil {
+			return fmt.Errorf("%s: socket_mode: %w", path, err)
+		}
+		cfg.SocketMode = mode
+	}
+	if raw.ProfileMode != "" {
+		mode, err := ParseFileMode(raw.ProfileMode)
+		if err != nil {
+			return fmt.Errorf("%s: profile_mode: %w", path, err)
+		}
+		cfg.ProfileMode = mode
+	}
+	if raw.AutoDismiss != nil {
+		cfg.AutoDismiss = raw.AutoDismiss
+	}
+	if raw.AutoPrune != nil {
+		cfg.AutoPrune = *raw.AutoPrune
+	}
+	if raw.MaxAge != "" {
+		d, err := time.ParseDuration(raw.MaxAge)
+		if err != nil {
+			return fmt.Errorf("%s: max_age: %w", path, err)
+		}
+		cfg.MaxAge = d
+	}
+	if len(raw.Aliases) > 0 {
+		if cfg.Aliases == nil {
+			cfg.Aliases = map[string]string{}
+		}
+		for alias, target := range raw.Aliases {
+			cfg.Aliases[alias] = target
+		}
+	}
+	for name, rawDefaults := range raw.Profiles {
+		if cfg.Profiles == nil {
+			cfg.Profiles = map[string]ProfileDefaults{}
+		}
+		defaults := cfg.Profiles[name]
+		if rawDefaults.Browser != "" {
+			defaults.Browser = rawDefaults.Browser
+		}
+		if rawDefaults.Channel != "" {
+			defaults.Channel = rawDefaults.Channel
+		}
+		if rawDefaults.Headless != nil {
+			defaults.Headless = rawDefaults.Headless
+		}
+		if rawDefaults.TTL != "" {
+			d, err := time.ParseDuration(rawDefaults.TTL)
+			if err != nil {
+				return fmt.Errorf("%s: profiles.%s.ttl: %w", path, name, err)
 			}
+			defaults.TTL = &d
+		}
+		if rawDefaults.UserAgent != "" {
+			defaults.UserAgent = rawDefaults.UserAgent
 		}
-		return nil
+		cfg.Profiles[name] = defaults
 	}
 	return nil
 }
 
+// ParseFileMode parses octal permissions such as "0600" for socket_mode,
+// profile_mode, and the matching start flags.
+func ParseFileMode(value string) (os.FileMode, error) {
+	n, err := strconv.ParseUint(strings.TrimSpace(value), 8, 32)
+	if err != nil || n == 0 || n > 0o777 {
+		return 0, fmt.Errorf("invalid mode %q: expected octal permissions like 0600", value)
+	}
+	return os.FileMode(n), nil
+}
+
 func defaultProfileDir() string {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -0,0 +1,238 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveProfile(t *testing.T) {
+	cfg := Config{Aliases: map[string]string{"w": "work-primary", "loop": "w"}}
+	name, ok := cfg.ResolveProfile("w")
+	if !ok || name != "work-primary" {
+		t.Fatalf("expected alias to resolve, got %q %t", name, ok)
+	}
+	name, ok = cfg.ResolveProfile("personal")
+	if ok || name != "personal" {
+		t.Fatalf("expected unaliased name unchanged, got %q %t", name, ok)
+	}
+	name, ok = cfg.ResolveProfile("loop")
+	if !ok || name != "w" {
+		t.Fatalf("expected single-level resolution, got %q %t", name, ok)
+	}
+}
+
+func writeConfig(t *testing.T, path string, body string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	system := filepath.Join(dir, "system", "config.toml")
+	writeConfig(t, system, "profile_dir = \"/system\"\ndefault_ttl = \"1h\"\n[aliases]\nw = \"system-work\"\ns = \"scratch\"\n")
+	orig := systemConfigPaths
+	systemConfigPaths = []string{system}
+	t.Cleanup(func() { systemConfigPaths = orig })
+
+	home := filepath.Join(dir, "home")
+	xdg := filepath.Join(dir, "xdg")
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv("WWW_PROFILE_DIR", "")
+	t.Setenv("WWW_DEFAULT_TTL", "")
+	writeConfig(t, filepath.Join(home, ".config", "www", "config.toml"), "default_ttl = \"2h\"\ndefault_timeout = \"45s\"\nidle_timeout = \"1h\"\n")
+	writeConfig(t, filepath.Join(xdg, "www", "config.toml"), "profile_dir = \"/xdg\"\n[aliases]\nw = \"xdg-work\"\n")
+
+	cfg, err := Load("", "", "")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.ProfileDir != "/xdg" || cfg.DefaultTTL != 2*time.Hour || cfg.DefaultTimeout != 45*time.Second || cfg.IdleTimeout == nil || *cfg.IdleTimeout != time.Hour {
+		t.Fatalf("expected user config to override system, got %+v", cfg)
+	}
+	if cfg.Aliases["w"] != "xdg-work" || cfg.Aliases["s"] != "scratch" {
+		t.Fatalf("expected merged aliases, got %v", cfg.Aliases)
+	}
+	if len(cfg.Files) != 3 || cfg.Files[0] != system || cfg.Files[2] != filepath.Join(xdg, "www", "config.toml") {
+		t.Fatalf("expected loaded files in order, got %v", cfg.Files)
+	}
+
+	explicit := filepath.Join(dir, "explicit.toml")
+	writeConfig(t, explicit, "profile_dir = \"/explicit\"\n")
+	cfg, err = Load(explicit, "", "")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.ProfileDir != "/explicit" {
+		t.Fatalf("expected --config to win over discovered files, got %s", cfg.ProfileDir)
+	}
+
+	t.Setenv("WWW_PROFILE_DIR", "/env")
+	cfg, err = Load(explicit, "", "")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.ProfileDir != "/env" {
+		t.Fatalf("expected env to win over config files, got %s", cfg.ProfileDir)
+	}
+	cfg, err = Load(explicit, "/flag", "")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.ProfileDir != "/flag" {
+		t.Fatalf("expected flag to win over env, got %s", cfg.ProfileDir)
+	}
+}
+
+func TestLoadMissingExplicitConfig(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.toml"), "", ""); err == nil {
+		t.Fatalf("expected error for missing --config file")
+	}
+}
+
+func TestLoadProfileDefaults(t *testing.T) {
+	dir := t.TempDir()
+	orig := systemConfigPaths
+	systemConfigPaths = nil
+	t.Cleanup(func() { systemConfigPaths = orig })
+	t.Setenv("HOME", filepath.Join(dir, "home"))
+	t.Setenv("XDG_CONFIG_HOME", "")
+	path := filepath.Join(dir, "config.toml")
+	writeConfig(t, path, "[profiles.work]\nbrowser = \"firefox\"\nheadless = false\nttl = \"48h\"\nuser_agent = \"ua/1\"\n")
+	cfg, err := Load(path, "", "")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	d, ok := cfg.Profiles["work"]
+	if !ok {
+		t.Fatalf("expected work defaults, got %v", cfg.Profiles)
+	}
+	if d.Browser != "firefox" || d.Headless == nil || *d.Headless || d.TTL == nil || *d.TTL != 48*time.Hour || d.UserAgent != "ua/1" {
+		t.Fatalf("unexpected defaults: %+v", d)
+	}
+
+	writeConfig(t, path, "[profiles.work]\nttl = \"soon\"\n")
+	if _, err := Load(path, "", ""); err == nil {
+		t.Fatalf("expected error for invalid ttl")
+	}
+}
+
+func TestLoadSocketModes(t *testing.T) {
+	dir := t.TempDir()
+	orig := systemConfigPaths
+	systemConfigPaths = nil
+	t.Cleanup(func() { systemConfigPaths = orig })
+	t.Setenv("HOME", filepath.Join(dir, "home"))
+	t.Setenv("XDG_CONFIG_HOME", "")
+	path := filepath.Join(dir, "config.toml")
+	writeConfig(t, path, "socket_mode = \"0660\"\nprofile_mode = \"750\"\n")
+	cfg, err := Load(path, "", "")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.SocketMode != 0o660 || cfg.ProfileMode != 0o750 {
+		t.Fatalf("unexpected modes: %o %o", cfg.SocketMode, cfg.ProfileMode)
+	}
+	writeConfig(t, path, "socket_mode = \"rw\"\n")
+	if _, err := Load(path, "", ""); err == nil {
+		t.Fatalf("expected error for invalid socket_mode")
+	}
+}
+
+func TestLoadAutoDismiss(t *testing.T) {
+	dir := t.TempDir()
+	orig := systemConfigPaths
+	systemConfigPaths = nil
+	t.Cleanup(func() { systemConfigPaths = orig })
+	t.Setenv("HOME", filepath.Join(dir, "home"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+
+	cfg, err := Load("", "", "")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(cfg.AutoDismiss) == 0 || cfg.AutoDismiss[0] != DefaultAutoDismiss[0] {
+		t.Fatalf("expected default auto-dismiss list, got %v", cfg.AutoDismiss)
+	}
+
+	path := filepath.Join(dir, "config.toml")
+	writeConfig(t, path, "auto_dismiss = [\"#agree\"]\n")
+	cfg, err = Load(path, "", "")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(cfg.AutoDismiss) != 1 || cfg.AutoDismiss[0] != "#agree" {
+		t.Fatalf("expected configured auto-dismiss list, got %v", cfg.AutoDismiss)
+	}
+}
+
+func TestLoadAutoPrune(t *testing.T) {
+	dir := t.TempDir()
+	orig := systemConfigPaths
+	systemConfigPaths = nil
+	t.Cleanup(func() { systemConfigPaths = orig })
+	t.Setenv("HOME", filepath.Join(dir, "home"))
+	t.Setenv("XDG_CONFIG_HOME", "")
+	path := filepath.Join(dir, "config.toml")
+	writeConfig(t, path, "auto_prune = true\nmax_age = \"720h\"\n")
+	cfg, err := Load(path, "", "")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !cfg.AutoPrune || cfg.MaxAge != 720*time.Hour {
+		t.Fatalf("unexpected auto-prune settings: %t %s", cfg.AutoPrune, cfg.MaxAge)
+	}
+	writeConfig(t, path, "max_age = \"a month\"\n")
+	if _, err := Load(path, "", ""); err == nil {
+		t.Fatalf("expected error for invalid max_age")
+	}
+}
+
+func TestLoadInvalidDefaultTimeout(t *testing.T) {
+	dir := t.TempDir()
+	orig := systemConfigPaths
+	systemConfigPaths = nil
+	t.Cleanup(func() { systemConfigPaths = orig })
+	t.Setenv("HOME", filepath.Join(dir, "home"))
+	t.Setenv("XDG_CONFIG_HOME", "")
+	path := filepath.Join(dir, "config.toml")
+	writeConfig(t, path, "default_timeout = \"30\"\n")
+	_, err := Load(path, "", "")
+	if err == nil || !strings.Contains(err.Error(), path+": default_timeout") {
+		t.Fatalf("expected default_timeout error naming the file, got %v", err)
+	}
+}
+
+func TestLoadIdleTimeoutZero(t *testing.T) {
+	dir := t.TempDir()
+	orig := systemConfigPaths
+	systemConfigPaths = nil
+	t.Cleanup(func() { systemConfigPaths = orig })
+	t.Setenv("HOME", filepath.Join(dir, "home"))
+	t.Setenv("XDG_CONFIG_HOME", "")
+	path := filepath.Join(dir, "config.toml")
+	writeConfig(t, path, "default_ttl = \"1h\"\n")
+	cfg, err := Load(path, "", "")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.IdleTimeout != nil {
+		t.Fatalf("expected unset idle timeout, got %s", *cfg.IdleTimeout)
+	}
+	writeConfig(t, path, "idle_timeout = \"0s\"\n")
+	cfg, err = Load(path, "", "")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.IdleTimeout == nil || *cfg.IdleTimeout != 0 {
+		t.Fatalf("expected idle timeout set to zero, got %v", cfg.IdleTimeout)
+	}
+}
@@ -3,9 +3,11 @@ package daemon
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"net"
 	"strconv"
 	"sync/atomic"
+	"time"
 
 	"github.com/patrickjm/www/internal/browser"
 )
@@ -14,10 +16,50 @@ type Client struct {
 	conn net.Conn
 	enc  *json.Encoder
 	dec  *json.Decoder
+	// DryRun, when set, receives state-changing requests as JSON lines
+	// instead of sending them; read-only requests still reach the daemon.
+	DryRun io.Writer
+	// borrowed clients share another client's connection and leave it open
+	// on Close.
+	borrowed bool
+}
+
+// changesState reports whether a request would change the browser, so
+// --dry-run should print it instead of sending it.
+func changesState(method string, params any) bool {
+	switch method {
+	case "TabNew", "TabActivate", "TabSwitch", "TabClose", "Goto", "Click", "Fill", "FillLabel",
+		"Drag", "Focus", "Blur", "Mouse", "Select", "Upload", "Shot", "PDF", "Eval", "Front", "Recycle", "Stop":
+		return true
+	case "StorageItem":
+		p, ok := params.(StorageItemParams)
+		return ok && p.Op == "set"
+	}
+	return false
 }
 
 var reqCounter uint64
 
+// RemoteError is an error returned by the daemon, with an optional machine
+// readable code such as CodeElementDetached.
+type RemoteError struct {
+	Message string
+	Code    string
+}
+
+func (e *RemoteError) Error() string {
+	return e.Message
+}
+
+// ErrorCode returns the daemon error code carried by err, if any.
+func ErrorCode(err error) string {
+	var remote *RemoteError
+	if errors.As(err, &remote) {
+		return remote.Code
+	}
+	return ""
+}
+
 func NewClient(socketPath string) (*Client, error) {
 	conn, err := net.Dial("unix", socketPath)
 	if err != nil {
@@ -27,10 +69,29 @@ func NewClient(socketPath string) (*Client, error) {
 }
 
 func (c *Client) Close() error {
+	if c.borrowed {
+		return nil
+	}
 	return c.conn.Close()
 }
 
+// Borrow returns a client on the same connection whose Close does nothing,
+// for lending the connection to code that closes its client when done.
+func (c *Client) Borrow() *Client {
+	borrowed := *c
+	borrowed.borrowed = true
+	return &borrowed
+}
+
 func (c *Client) Call(method string, params any, out any) error {
+	if c.DryRun != nil && changesState(method, params) {
+		enc := json.NewEncoder(c.DryRun)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(struct {
+			Method string `json:"method"`
+			Params any    `json:"params,omitempty"`
+		}{method, params})
+	}
 	id := strconv.FormatUint(atomic.AddUint64(&reqCounter, 1), 10)
 	var raw json.RawMessage
 	if params != nil {
@@ -48,7 +109,7 @@ func (c *Client) Call(method string, params any, out any) error {
 		return err
 	}
 	if resp.Error != nil {
-		return errors.New(resp.Error.Message)
+		return &RemoteError{Message: resp.Error.Message, Code: resp.Error.Code}
 	}
 	if out != nil {
 		return json.Unmarshal(resp.Result, out)
@@ -56,6 +117,18 @@ func (c *Client) Call(method string, params any, out any) error {
 	return nil
 }
 
+// Ping checks that the daemon answers requests within timeout, unlike a bare
+// dial, which succeeds as long as the listener is open. Zero waits forever.
+func (c *Client) Ping(timeout time.Duration) (PingResult, error) {
+	if timeout > 0 {
+		_ = c.conn.SetDeadline(time.Now().Add(timeout))
+		defer c.conn.SetDeadline(time.Time{})
+	}
+	var result PingResult
+	err := c.Call("Ping", nil, &result)
+	return result, err
+}
+
 func (c *Client) Status() (StatusResult, error) {
 	var result StatusResult
 	return result, c.Call("Status", nil, &result)
@@ -67,8 +140,17 @@ func (c *Client) TabList() ([]TabInfo, error) {
 }
 
 func (c *Client) TabNew(url string) (TabInfo, error) {
+	return c.TabNewWithOptions(TabNewParams{URL: url})
+}
+
+func (c *Client) TabNewWithOptions(params TabNewParams) (TabInfo, error) {
 	var result TabInfo
-	return result, c.Call("TabNew", TabNewParams{URL: url}, &result)
+	return result, c.Call("TabNew", params, &result)
+}
+
+func (c *Client) TabActivate(params TabActivateParams) (TabInfo, error) {
+	var result TabInfo
+	return result, c.Call("TabActivate", params, &result)
 }
 
 func (c *Client) TabSwitch(tab int) error {
@@ -83,18 +165,77 @@ func (c *Client) Goto(tab int, url string, timeoutMs int) error {
 	return c.Call("Goto", GotoParams{Tab: tab, URL: url, TimeoutMs: timeoutMs}, nil)
 }
 
+func (c *Client) GotoWithOptions(params GotoParams) error {
+	return c.Call("Goto", params, nil)
+}
+
 func (c *Client) Click(tab int, selector string, timeoutMs int) error {
 	return c.Call("Click", ClickParams{Tab: tab, Selector: selector, TimeoutMs: timeoutMs}, nil)
 }
 
+func (c *Client) ClickWithOptions(params ClickParams) error {
+	return c.Call("Click", params, nil)
+}
+
+func (c *Client) ClickPopup(params ClickParams) (TabInfo, error) {
+	params.ExpectPopup = true
+	var result TabInfo
+	return result, c.Call("Click", params, &result)
+}
+
 func (c *Client) Fill(tab int, selector string, value string, timeoutMs int) error {
 	return c.Call("Fill", FillParams{Tab: tab, Selector: selector, Value: value, TimeoutMs: timeoutMs}, nil)
 }
 
+func (c *Client) FillWithOptions(params FillParams) error {
+	return c.Call("Fill", params, nil)
+}
+
+func (c *Client) Drag(params DragParams) error {
+	return c.Call("Drag", params, nil)
+}
+
+func (c *Client) Focus(params FocusParams) error {
+	return c.Call("Focus", params, nil)
+}
+
+func (c *Client) Blur(params FocusParams) error {
+	return c.Call("Blur", params, nil)
+}
+
+func (c *Client) MouseMove(params MouseParams) error {
+	return c.Call("Mouse", params, nil)
+}
+
+func (c *Client) Select(params SelectParams) ([]string, error) {
+	var result []string
+	return result, c.Call("Select", params, &result)
+}
+
+func (c *Client) Upload(params UploadParams) error {
+	return c.Call("Upload", params, nil)
+}
+
+func (c *Client) FillLabel(tab int, label string, value string, timeoutMs int) error {
+	return c.Call("FillLabel", FillLabelParams{Tab: tab, Label: label, Value: value, TimeoutMs: timeoutMs}, nil)
+}
+
+func (c *Client) FillLabelWithOptions(params FillLabelParams) error {
+	return c.Call("FillLabel", params, nil)
+}
+
 func (c *Client) Shot(tab int, path string, fullPage bool, selector string, timeoutMs int) error {
 	return c.Call("Shot", ShotParams{Tab: tab, Path: path, FullPage: fullPage, Selector: selector, TimeoutMs: timeoutMs}, nil)
 }
 
+func (c *Client) ShotWithOptions(params ShotParams) error {
+	return c.Call("Shot", params, nil)
+}
+
+func (c *Client) PDF(tab int, path string, opts browser.PDFOptions, timeoutMs int) error {
+	return c.Call("PDF", PDFParams{Tab: tab, Path: path, Format: opts.Format, Landscape: opts.Landscape, PrintBackground: opts.PrintBackground, TimeoutMs: timeoutMs}, nil)
+}
+
 func (c *Client) Extract(tab int, timeoutMs int) (json.RawMessage, error) {
 	var result json.RawMessage
 	return result, c.Call("Extract", ExtractParams{Tab: tab, TimeoutMs: timeoutMs}, &result)
@@ -105,21 +246,123 @@ func (c *Client) ExtractWithOptions(tab int, selector string, main bool, timeout
 	return result, c.Call("Extract", ExtractParams{Tab: tab, Selector: selector, Main: main, TimeoutMs: timeoutMs}, &result)
 }
 
+func (c *Client) ExtractWithParams(params ExtractParams) (json.RawMessage, error) {
+	var result json.RawMessage
+	return result, c.Call("Extract", params, &result)
+}
+
+func (c *Client) WaitForURL(params WaitURLParams) error {
+	return c.Call("WaitURL", params, nil)
+}
+
+func (c *Client) WaitIdle(tab int, timeoutMs int) error {
+	return c.Call("WaitIdle", WaitIdleParams{Tab: tab, TimeoutMs: timeoutMs}, nil)
+}
+
 func (c *Client) Eval(tab int, js string, timeoutMs int) (json.RawMessage, error) {
+	return c.EvalWithOptions(EvalParams{Tab: tab, JS: js, TimeoutMs: timeoutMs})
+}
+
+func (c *Client) EvalWithOptions(params EvalParams) (json.RawMessage, error) {
 	var result json.RawMessage
-	return result, c.Call("Eval", EvalParams{Tab: tab, JS: js, TimeoutMs: timeoutMs}, &result)
+	return result, c.Call("Eval", params, &result)
+}
+
+// Recycle restarts the browser session without stopping the daemon.
+func (c *Client) Recycle() (StatusResult, error) {
+	var result StatusResult
+	return result, c.Call("Recycle", nil, &result)
 }
 
 func (c *Client) Stop() error {
 	return c.Call("Stop", nil, nil)
 }
 
-func (c *Client) URL(tab int) (string, error) {
+func (c *Client) URL(tab int, timeoutMs int) (string, error) {
 	var result string
-	return result, c.Call("URL", URLParams{Tab: tab}, &result)
+	return result, c.Call("URL", URLParams{Tab: tab, TimeoutMs: timeoutMs}, &result)
+}
+
+func (c *Client) Front(tab int, timeoutMs int) error {
+	return c.Call("Front", FrontParams{Tab: tab, TimeoutMs: timeoutMs}, nil)
+}
+
+func (c *Client) Links(tab int, filter string, timeoutMs int) ([]browser.ExtractLink, error) {
+	var result []browser.ExtractLink
+	return result, c.Call("Links", LinksParams{Tab: tab, Filter: filter, TimeoutMs: timeoutMs}, &result)
 }
 
-func (c *Client) Links(tab int, filter string) ([]browser.ExtractLink, error) {
+func (c *Client) LinksWithOptions(params LinksParams) ([]browser.ExtractLink, error) {
 	var result []browser.ExtractLink
-	return result, c.Call("Links", LinksParams{Tab: tab, Filter: filter}, &result)
+	return result, c.Call("Links", params, &result)
+}
+
+func (c *Client) Tables(tab int, selector string, timeoutMs int) ([]browser.ExtractTable, error) {
+	var result []browser.ExtractTable
+	return result, c.Call("Tables", TablesParams{Tab: tab, Selector: selector, TimeoutMs: timeoutMs}, &result)
+}
+
+func (c *Client) Forms(tab int, timeoutMs int) ([]browser.ExtractForm, error) {
+	var result []browser.ExtractForm
+	return result, c.Call("Forms", FormsParams{Tab: tab, TimeoutMs: timeoutMs}, &result)
+}
+
+func (c *Client) Outline(tab int, timeoutMs int) ([]browser.Heading, error) {
+	var result []browser.Heading
+	return result, c.Call("Outline", OutlineParams{Tab: tab, TimeoutMs: timeoutMs}, &result)
+}
+
+func (c *Client) Content(params ContentParams) (string, error) {
+	var result string
+	return result, c.Call("Content", params, &result)
+}
+
+func (c *Client) Box(tab int, selector string, timeoutMs int) (*browser.Box, error) {
+	var result *browser.Box
+	return result, c.Call("Box", BoxParams{Tab: tab, Selector: selector, TimeoutMs: timeoutMs}, &result)
+}
+
+func (c *Client) BoxWithOptions(params BoxParams) (*browser.Box, error) {
+	var result *browser.Box
+	return result, c.Call("Box", params, &result)
+}
+
+func (c *Client) Count(tab int, selector string, timeoutMs int) (int, error) {
+	var result int
+	return result, c.Call("Count", CountParams{Tab: tab, Selector: selector, TimeoutMs: timeoutMs}, &result)
+}
+
+func (c *Client) Text(tab int, selector string, timeoutMs int) (browser.ElementText, error) {
+	var result browser.ElementText
+	return result, c.Call("Text", TextParams{Tab: tab, Selector: selector, TimeoutMs: timeoutMs}, &result)
+}
+
+func (c *Client) Attr(tab int, selector string, name string, timeoutMs int) (browser.ElementAttr, error) {
+	var result browser.ElementAttr
+	return result, c.Call("Attr", AttrParams{Tab: tab, Selector: selector, Name: name, TimeoutMs: timeoutMs}, &result)
+}
+
+func (c *Client) Attrs(params AttrsParams) ([]browser.ElementAttrs, error) {
+	var result []browser.ElementAttrs
+	return result, c.Call("Attrs", params, &result)
+}
+
+func (c *Client) StorageItem(params StorageItemParams) (browser.StorageResult, error) {
+	var result browser.StorageResult
+	return result, c.Call("StorageItem", params, &result)
+}
+
+func (c *Client) Cookies() ([]browser.Cookie, error) {
+	var result []browser.Cookie
+	return result, c.Call("Cookies", nil, &result)
+}
+
+func (c *Client) Downloads(limit int) ([]browser.Download, error) {
+	var result []browser.Download
+	return result, c.Call("Downloads", DownloadsParams{Limit: limit}, &result)
+}
+
+func (c *Client) NetLog(limit int) ([]browser.NetRecord, error) {
+	var result []browser.NetRecord
+	return result, c.Call("NetLog", NetLogParams{Limit: limit}, &result)
 }
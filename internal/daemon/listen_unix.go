@@ -0,0 +1,16 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenSocket creates the unix socket with an owner-only umask, so it is
+// never reachable by other users before ServeProfile applies its final mode.
+func listenSocket(path string) (net.Listener, error) {
+	old := syscall.Umask(0o177)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -21,11 +22,22 @@ type Info struct {
 	StartedAt     time.Time `json:"started_at"`
 	BinaryPath    string    `json:"binary_path,omitempty"`
 	BinaryModTime time.Time `json:"binary_mod_time,omitempty"`
+	// NoDefaultTab and Incognito record how the daemon was started, so a
+	// restart for --fresh or a binary change starts it the same way.
+	NoDefaultTab bool `json:"no_default_tab,omitempty"`
+	Incognito    bool `json:"incognito,omitempty"`
 }
 
 type Manager struct {
-	ProfileDir string
-	BinaryPath string
+	ProfileDir   string
+	BinaryPath   string
+	LogLevel     string
+	NoDefaultTab bool
+	Incognito    bool
+	// SocketMode and ProfileMode, when set, are passed on to serve as
+	// --socket-mode and --profile-mode.
+	SocketMode  string
+	ProfileMode string
 }
 
 func (m Manager) SocketPath(profile string) string {
@@ -36,6 +48,80 @@ func (m Manager) InfoPath(profile string) string {
 	return filepath.Join(m.ProfileDir, profile, "daemon.json")
 }
 
+func (m Manager) LogPath(profile string) string {
+	return filepath.Join(m.ProfileDir, profile, "daemon.log")
+}
+
+// startupErrorName is the file in the profile directory where serve records
+// why the browser failed to start, so Start can report it.
+const startupErrorName = "daemon-error.log"
+
+func (m Manager) StartupErrorPath(profile string) string {
+	return filepath.Join(m.ProfileDir, profile, startupErrorName)
+}
+
+// RecordStartupError saves why serve failed before ServeProfile could, for
+// errors such as a bad config or an unreadable profile.
+func (m Manager) RecordStartupError(profile string, err error) {
+	_ = os.WriteFile(m.StartupErrorPath(profile), []byte(err.Error()+"\n"), 0o644)
+}
+
+func (m Manager) startupError(profile string) string {
+	b, err := os.ReadFile(m.StartupErrorPath(profile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// logTailChunk is how much of daemon.log LogTail reads at a time, working
+// back from the end until it has enough lines.
+const logTailChunk = 16 * 1024
+
+// LogTail returns the last lines of the profile's daemon log, or all of it
+// when lines is zero. It reads back from the end so a long-lived daemon's
+// log is not loaded whole for a short tail.
+func (m Manager) LogTail(profile string, lines int) (string, error) {
+	f, err := os.Open(m.LogPath(profile))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if lines <= 0 {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\n"), nil
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	var buf []byte
+	for offset := info.Size(); offset > 0; {
+		n := min(int64(logTailChunk), offset)
+		offset -= n
+		chunk := make([]byte, n)
+		if _, err := f.ReadAt(chunk, offset); err != nil {
+			return "", err
+		}
+		buf = append(chunk, buf...)
+		if bytes.Count(bytes.TrimRight(buf, "\n"), []byte("\n")) >= lines {
+			break
+		}
+	}
+	text := strings.TrimRight(string(buf), "\n")
+	if text == "" {
+		return "", nil
+	}
+	all := strings.Split(text, "\n")
+	if len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+	return strings.Join(all, "\n"), nil
+}
+
 func (m Manager) LoadInfo(profile string) (Info, error) {
 	b, err := os.ReadFile(m.InfoPath(profile))
 	if err != nil {
@@ -80,6 +166,57 @@ func (m Manager) IsRunning(profile string) (bool, Info, error) {
 	return true, info, nil
 }
 
+// IsAlive reports whether the daemon's process and socket are up. Unlike
+// IsRunning it never stops an outdated daemon or cleans up stale files, so
+// read-only checks like health leave the daemon as they found it.
+func (m Manager) IsAlive(profile string) (bool, Info, error) {
+	info, err := m.LoadInfo(profile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, Info{}, nil
+		}
+		return false, Info{}, err
+	}
+	if !processAlive(info.PID) || !socketAlive(info.Socket) {
+		return false, Info{}, nil
+	}
+	return true, info, nil
+}
+
+// StopIfOutdated stops a daemon started from a different binary and reports
+// whether it did, along with how many tabs the daemon had open.
+func (m Manager) StopIfOutdated(profile string) (bool, int, error) {
+	info, err := m.LoadInfo(profile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, 0, nil
+		}
+		return false, 0, err
+	}
+	if !processAlive(info.PID) || !socketAlive(info.Socket) || !m.binaryMismatch(info) {
+		return false, 0, nil
+	}
+	tabs := openTabs(info.Socket)
+	_ = m.Stop(profile)
+	_ = m.cleanupStale(profile)
+	return true, tabs, nil
+}
+
+// openTabs asks the daemon how many tabs it has open, or returns 0 when it
+// does not answer.
+func openTabs(socketPath string) int {
+	client, err := NewClient(socketPath)
+	if err != nil {
+		return 0
+	}
+	defer client.Close()
+	ping, err := client.Ping(time.Second)
+	if err != nil {
+		return 0
+	}
+	return ping.TabCount
+}
+
 func (m Manager) Start(profile string) error {
 	running, _, err := m.IsRunning(profile)
 	if err != nil {
@@ -99,12 +236,29 @@ func (m Manager) Start(profile string) error {
 	if err := os.MkdirAll(profileDir, 0o755); err != nil {
 		return err
 	}
-	logPath := filepath.Join(profileDir, "daemon.log")
+	_ = os.Remove(m.StartupErrorPath(profile))
+	logPath := m.LogPath(profile)
 	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
 	if err != nil {
 		logFile = nil
 	}
-	cmd := exec.Command(m.BinaryPath, "--profile", profile, "--profile-dir", m.ProfileDir, "serve")
+	args := []string{"--profile", profile, "--profile-dir", m.ProfileDir, "serve"}
+	if m.LogLevel != "" {
+		args = append(args, "--log-level", m.LogLevel)
+	}
+	if m.NoDefaultTab {
+		args = append(args, "--no-default-tab")
+	}
+	if m.Incognito {
+		args = append(args, "--incognito")
+	}
+	if m.SocketMode != "" {
+		args = append(args, "--socket-mode", m.SocketMode)
+	}
+	if m.ProfileMode != "" {
+		args = append(args, "--profile-mode", m.ProfileMode)
+	}
+	cmd := exec.Command(m.BinaryPath, args...)
 	if logFile != nil {
 		cmd.Stdout = logFile
 		cmd.Stderr = logFile
@@ -122,14 +276,24 @@ func (m Manager) Start(profile string) error {
 	if logFile != nil {
 		_ = logFile.Close()
 	}
-	deadline := time.Now().Add(15 * time.Second)
+	return m.waitReady(profile, 15*time.Second)
+}
+
+// waitReady polls for the daemon socket. A startup error recorded by serve
+// ends the wait early with that message; otherwise a timeout falls back to
+// the log tail.
+func (m Manager) waitReady(profile string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
 	for time.Now().Before(deadline) {
 		if socketAlive(m.SocketPath(profile)) {
 			return nil
 		}
+		if msg := m.startupError(profile); msg != "" {
+			return fmt.Errorf("daemon did not start: %s", msg)
+		}
 		time.Sleep(100 * time.Millisecond)
 	}
-	if tail := tailFile(logPath, 8*1024); tail != "" {
+	if tail := tailFile(m.LogPath(profile), 8*1024); tail != "" {
 		return fmt.Errorf("daemon did not start: %s", tail)
 	}
 	return errors.New("daemon did not start")
@@ -144,6 +308,24 @@ func (m Manager) Stop(profile string) error {
 	return client.Stop()
 }
 
+func (m Manager) StopWait(profile string) error {
+	running, _, err := m.IsRunning(profile)
+	if err != nil || !running {
+		return err
+	}
+	if err := m.Stop(profile); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if !socketAlive(m.SocketPath(profile)) {
+			return m.cleanupStale(profile)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return errors.New("daemon did not stop")
+}
+
 func (m Manager) cleanupStale(profile string) error {
 	_ = os.Remove(m.SocketPath(profile))
 	_ = os.Remove(m.InfoPath(profile))
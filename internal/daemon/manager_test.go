@@ -1,10 +1,15 @@
 package daemon
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/patrickjm/www/internal/browser"
 )
 
 func TestBinaryMismatch(t *testing.T) {
@@ -27,3 +32,166 @@ func TestBinaryMismatch(t *testing.T) {
 		t.Fatalf("expected mismatch for path")
 	}
 }
+
+func TestStopIfOutdatedWithoutInfo(t *testing.T) {
+	mgr := Manager{ProfileDir: t.TempDir()}
+	stopped, _, err := mgr.StopIfOutdated("missing")
+	if err != nil {
+		t.Fatalf("stop if outdated: %v", err)
+	}
+	if stopped {
+		t.Fatalf("expected no restart without daemon info")
+	}
+}
+
+func TestIsAliveLeavesOutdatedDaemonRunning(t *testing.T) {
+	mgr := Manager{ProfileDir: t.TempDir()}
+	socket := mgr.SocketPath("demo")
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ServeProfile(socket, "demo", &browser.FakeEngine{}, browser.StartOptions{Headless: true}, ServeOptions{})
+	}()
+	if err := waitForSocket(socket, 2*time.Second); err != nil {
+		t.Fatalf("wait socket: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = mgr.Stop("demo")
+		<-errCh
+	})
+	info := Info{PID: os.Getpid(), Socket: socket, BinaryPath: filepath.Join(t.TempDir(), "old-www")}
+	if err := mgr.SaveInfo("demo", info); err != nil {
+		t.Fatalf("save info: %v", err)
+	}
+	alive, _, err := mgr.IsAlive("demo")
+	if err != nil || !alive {
+		t.Fatalf("expected outdated daemon to be alive, got %t, %v", alive, err)
+	}
+	if !socketAlive(socket) {
+		t.Fatalf("expected IsAlive to leave the daemon running")
+	}
+	if _, err := os.Stat(mgr.InfoPath("demo")); err != nil {
+		t.Fatalf("expected daemon info to be kept: %v", err)
+	}
+}
+
+func TestStopIfOutdatedReportsOpenTabs(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		noDefaultTab bool
+		want         int
+	}{
+		{name: "default tab", want: 1},
+		{name: "no tabs", noDefaultTab: true, want: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mgr := Manager{ProfileDir: t.TempDir()}
+			socket := mgr.SocketPath("demo")
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- ServeProfile(socket, "demo", &browser.FakeEngine{}, browser.StartOptions{Headless: true}, ServeOptions{NoDefaultTab: tc.noDefaultTab})
+			}()
+			if err := waitForSocket(socket, 2*time.Second); err != nil {
+				t.Fatalf("wait socket: %v", err)
+			}
+			info := Info{PID: os.Getpid(), Socket: socket, BinaryPath: filepath.Join(t.TempDir(), "old-www"), BinaryModTime: time.Now()}
+			if err := mgr.SaveInfo("demo", info); err != nil {
+				t.Fatalf("save info: %v", err)
+			}
+			stopped, tabs, err := mgr.StopIfOutdated("demo")
+			if err != nil || !stopped {
+				t.Fatalf("expected outdated daemon to stop, got %t, %v", stopped, err)
+			}
+			if tabs != tc.want {
+				t.Fatalf("expected %d open tabs, got %d", tc.want, tabs)
+			}
+			if err := <-errCh; err != nil {
+				t.Fatalf("serve: %v", err)
+			}
+		})
+	}
+}
+
+func TestLogTail(t *testing.T) {
+	mgr := Manager{ProfileDir: t.TempDir()}
+	if _, err := mgr.LogTail("demo", 2); !os.IsNotExist(err) {
+		t.Fatalf("expected not exist error, got %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(mgr.ProfileDir, "demo"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(mgr.LogPath("demo"), []byte("one\ntwo\nthree\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	tail, err := mgr.LogTail("demo", 2)
+	if err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	if tail != "two\nthree" {
+		t.Fatalf("unexpected tail: %q", tail)
+	}
+	all, err := mgr.LogTail("demo", 0)
+	if err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	if all != "one\ntwo\nthree" {
+		t.Fatalf("unexpected full log: %q", all)
+	}
+
+	var long strings.Builder
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&long, "line %d\n", i)
+	}
+	if err := os.WriteFile(mgr.LogPath("demo"), []byte(long.String()), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	tail, err = mgr.LogTail("demo", 3000)
+	if err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	lines := strings.Split(tail, "\n")
+	if len(lines) != 3000 || lines[0] != "line 2000" || lines[2999] != "line 4999" {
+		t.Fatalf("unexpected tail across chunks: %d lines, first %q", len(lines), lines[0])
+	}
+}
+
+func TestWaitReadyReportsStartupError(t *testing.T) {
+	mgr := Manager{ProfileDir: t.TempDir()}
+	engine := &browser.FakeEngine{StartErr: errors.New("chromium executable doesn't exist")}
+	if err := ServeProfile(mgr.SocketPath("demo"), "demo", engine, browser.StartOptions{}, ServeOptions{}); err == nil {
+		t.Fatalf("expected serve to fail")
+	}
+	started := time.Now()
+	err := mgr.waitReady("demo", 5*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "chromium executable doesn't exist") {
+		t.Fatalf("expected startup error, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed >= time.Second {
+		t.Fatalf("expected early failure, took %s", elapsed)
+	}
+}
+
+func TestServeProfileRecordsListenError(t *testing.T) {
+	// A socket path longer than the unix socket limit makes Listen fail after
+	// Init has succeeded.
+	mgr := Manager{ProfileDir: t.TempDir()}
+	profile := strings.Repeat("p", 120)
+	if err := ServeProfile(mgr.SocketPath(profile), profile, &browser.FakeEngine{}, browser.StartOptions{}, ServeOptions{}); err == nil {
+		t.Fatalf("expected serve to fail")
+	}
+	err := mgr.waitReady(profile, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "daemon did not start: listen") {
+		t.Fatalf("expected recorded listen error, got %v", err)
+	}
+}
+
+func TestRecordStartupError(t *testing.T) {
+	mgr := Manager{ProfileDir: t.TempDir()}
+	if err := os.MkdirAll(filepath.Join(mgr.ProfileDir, "demo"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	mgr.RecordStartupError("demo", errors.New("open profile.json: no such file"))
+	err := mgr.waitReady("demo", time.Second)
+	if err == nil || !strings.Contains(err.Error(), "open profile.json: no such file") {
+		t.Fatalf("expected recorded error, got %v", err)
+	}
+}
@@ -1,6 +1,10 @@
 package daemon
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/patrickjm/www/internal/browser"
+)
 
 type Request struct {
 	ID     string          `json:"id"`
@@ -16,13 +20,38 @@ type Response struct {
 
 type RespError struct {
 	Message string `json:"message"`
+	Code    string `json:"code,omitempty"`
 }
 
+// Error codes carried in RespError.Code.
+const (
+	// CodeElementDetached marks errors where the target left the DOM mid-action.
+	CodeElementDetached = "element_detached"
+	// CodeSelectorNoMatch marks selectors or text that matched nothing.
+	CodeSelectorNoMatch = "selector_no_match"
+	// CodeTabNotFound marks requests for a tab that does not exist.
+	CodeTabNotFound = "tab_not_found"
+	// CodeTimeout marks actions that ran past their timeout.
+	CodeTimeout = "timeout"
+)
+
 type TabInfo struct {
-	ID     int    `json:"id"`
-	URL    string `json:"url"`
-	Title  string `json:"title"`
-	Active bool   `json:"active"`
+	ID      int    `json:"id"`
+	URL     string `json:"url"`
+	Title   string `json:"title"`
+	Active  bool   `json:"active"`
+	Crashed bool   `json:"crashed,omitempty"`
+	// Unresponsive is set when the page did not report its URL and title in
+	// time; they are left empty.
+	Unresponsive bool `json:"unresponsive,omitempty"`
+}
+
+// PingResult reports daemon liveness without touching the browser. Uptime is
+// in seconds.
+type PingResult struct {
+	PID      int   `json:"pid"`
+	Uptime   int64 `json:"uptime"`
+	TabCount int   `json:"tab_count"`
 }
 
 type StatusResult struct {
@@ -31,7 +60,17 @@ type StatusResult struct {
 }
 
 type TabNewParams struct {
-	URL string `json:"url,omitempty"`
+	URL        string `json:"url,omitempty"`
+	Background bool   `json:"background,omitempty"`
+	TimeoutMs  int    `json:"timeout_ms,omitempty"`
+}
+
+// TabActivateParams finds a tab whose URL matches URL (Match is prefix,
+// contains, or exact; prefix when empty) or opens one.
+type TabActivateParams struct {
+	URL       string `json:"url"`
+	Match     string `json:"match,omitempty"`
+	TimeoutMs int    `json:"timeout_ms,omitempty"`
 }
 
 type TabSwitchParams struct {
@@ -45,48 +84,219 @@ type TabCloseParams struct {
 type GotoParams struct {
 	Tab       int    `json:"tab"`
 	URL       string `json:"url"`
+	WaitUntil string `json:"wait_until,omitempty"`
 	TimeoutMs int    `json:"timeout_ms,omitempty"`
 }
 
 type ClickParams struct {
+	Tab               int    `json:"tab"`
+	Selector          string `json:"selector"`
+	Raw               bool   `json:"raw,omitempty"`
+	Nth               *int   `json:"nth,omitempty"`
+	Exact             bool   `json:"exact,omitempty"`
+	ExpectPopup       bool   `json:"expect_popup,omitempty"`
+	TimeoutMs         int    `json:"timeout_ms,omitempty"`
+	SelectorTimeoutMs int    `json:"selector_timeout_ms,omitempty"`
+}
+
+type FillParams struct {
+	Tab               int    `json:"tab"`
+	Selector          string `json:"selector"`
+	Value             string `json:"value"`
+	Nth               *int   `json:"nth,omitempty"`
+	TimeoutMs         int    `json:"timeout_ms,omitempty"`
+	SelectorTimeoutMs int    `json:"selector_timeout_ms,omitempty"`
+}
+
+type DragParams struct {
+	Tab               int    `json:"tab"`
+	From              string `json:"from"`
+	To                string `json:"to"`
+	TimeoutMs         int    `json:"timeout_ms,omitempty"`
+	SelectorTimeoutMs int    `json:"selector_timeout_ms,omitempty"`
+}
+
+// FocusParams is used by both Focus and Blur.
+type FocusParams struct {
+	Tab               int    `json:"tab"`
+	Selector          string `json:"selector"`
+	TimeoutMs         int    `json:"timeout_ms,omitempty"`
+	SelectorTimeoutMs int    `json:"selector_timeout_ms,omitempty"`
+}
+
+type MouseParams struct {
+	Tab       int     `json:"tab"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	TimeoutMs int     `json:"timeout_ms,omitempty"`
+}
+
+type SelectParams struct {
+	Tab               int      `json:"tab"`
+	Selector          string   `json:"selector"`
+	Values            []string `json:"values"`
+	Add               bool     `json:"add,omitempty"`
+	TimeoutMs         int      `json:"timeout_ms,omitempty"`
+	SelectorTimeoutMs int      `json:"selector_timeout_ms,omitempty"`
+}
+
+type UploadParams struct {
+	Tab               int      `json:"tab"`
+	Selector          string   `json:"selector"`
+	Paths             []string `json:"paths"`
+	Nth               *int     `json:"nth,omitempty"`
+	TimeoutMs         int      `json:"timeout_ms,omitempty"`
+	SelectorTimeoutMs int      `json:"selector_timeout_ms,omitempty"`
+}
+
+type FillLabelParams struct {
+	Tab               int    `json:"tab"`
+	Label             string `json:"label"`
+	Value             string `json:"value"`
+	TimeoutMs         int    `json:"timeout_ms,omitempty"`
+	SelectorTimeoutMs int    `json:"selector_timeout_ms,omitempty"`
+}
+
+type ShotParams struct {
+	Tab               int           `json:"tab"`
+	Path              string        `json:"path"`
+	FullPage          bool          `json:"full_page"`
+	Selector          string        `json:"selector,omitempty"`
+	Clip              *browser.Rect `json:"clip,omitempty"`
+	Type              string        `json:"type,omitempty"`
+	Quality           *int          `json:"quality,omitempty"`
+	Highlight         string        `json:"highlight,omitempty"`
+	HighlightColor    string        `json:"highlight_color,omitempty"`
+	Stable            bool          `json:"stable,omitempty"`
+	TimeoutMs         int           `json:"timeout_ms,omitempty"`
+	SelectorTimeoutMs int           `json:"selector_timeout_ms,omitempty"`
+}
+
+type PDFParams struct {
+	Tab             int    `json:"tab"`
+	Path            string `json:"path"`
+	Format          string `json:"format,omitempty"`
+	Landscape       bool   `json:"landscape,omitempty"`
+	PrintBackground bool   `json:"print_background,omitempty"`
+	TimeoutMs       int    `json:"timeout_ms,omitempty"`
+}
+
+type ExtractParams struct {
+	Tab         int    `json:"tab"`
+	Selector    string `json:"selector,omitempty"`
+	Main        bool   `json:"main,omitempty"`
+	Format      string `json:"format,omitempty"`
+	Shadow      bool   `json:"shadow,omitempty"`
+	OnlyVisible bool   `json:"only_visible,omitempty"`
+	Aria        bool   `json:"aria,omitempty"`
+	TimeoutMs   int    `json:"timeout_ms,omitempty"`
+}
+
+type WaitIdleParams struct {
+	Tab       int `json:"tab"`
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+}
+
+type WaitURLParams struct {
+	Tab       int    `json:"tab"`
+	Pattern   string `json:"pattern"`
+	Regex     bool   `json:"regex,omitempty"`
+	TimeoutMs int    `json:"timeout_ms,omitempty"`
+}
+
+type EvalParams struct {
+	Tab       int             `json:"tab"`
+	JS        string          `json:"js"`
+	Args      json.RawMessage `json:"args,omitempty"`
+	TimeoutMs int             `json:"timeout_ms,omitempty"`
+}
+
+type URLParams struct {
+	Tab       int `json:"tab"`
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+}
+
+type FrontParams struct {
+	Tab       int `json:"tab"`
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+}
+
+type LinksParams struct {
+	Tab         int    `json:"tab"`
+	Filter      string `json:"filter,omitempty"`
+	Shadow      bool   `json:"shadow,omitempty"`
+	OnlyVisible bool   `json:"only_visible,omitempty"`
+	TimeoutMs   int    `json:"timeout_ms,omitempty"`
+}
+
+type TablesParams struct {
+	Tab       int    `json:"tab"`
+	Selector  string `json:"selector,omitempty"`
+	TimeoutMs int    `json:"timeout_ms,omitempty"`
+}
+
+type FormsParams struct {
+	Tab       int `json:"tab"`
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+}
+
+type OutlineParams struct {
+	Tab       int `json:"tab"`
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+}
+
+type ContentParams struct {
+	Tab       int    `json:"tab"`
+	Selector  string `json:"selector,omitempty"`
+	TimeoutMs int    `json:"timeout_ms,omitempty"`
+}
+
+type BoxParams struct {
+	Tab               int    `json:"tab"`
+	Selector          string `json:"selector"`
+	TimeoutMs         int    `json:"timeout_ms,omitempty"`
+	SelectorTimeoutMs int    `json:"selector_timeout_ms,omitempty"`
+}
+
+type CountParams struct {
 	Tab       int    `json:"tab"`
 	Selector  string `json:"selector"`
 	TimeoutMs int    `json:"timeout_ms,omitempty"`
 }
 
-type FillParams struct {
+type TextParams struct {
 	Tab       int    `json:"tab"`
 	Selector  string `json:"selector"`
-	Value     string `json:"value"`
 	TimeoutMs int    `json:"timeout_ms,omitempty"`
 }
 
-type ShotParams struct {
+type AttrParams struct {
 	Tab       int    `json:"tab"`
-	Path      string `json:"path"`
-	FullPage  bool   `json:"full_page"`
-	Selector  string `json:"selector,omitempty"`
+	Selector  string `json:"selector"`
+	Name      string `json:"name"`
 	TimeoutMs int    `json:"timeout_ms,omitempty"`
 }
 
-type ExtractParams struct {
+type AttrsParams struct {
 	Tab       int    `json:"tab"`
-	Selector  string `json:"selector,omitempty"`
-	Main      bool   `json:"main,omitempty"`
+	Selector  string `json:"selector"`
+	Nth       *int   `json:"nth,omitempty"`
 	TimeoutMs int    `json:"timeout_ms,omitempty"`
 }
 
-type EvalParams struct {
+type StorageItemParams struct {
 	Tab       int    `json:"tab"`
-	JS        string `json:"js"`
+	Op        string `json:"op"`
+	Key       string `json:"key,omitempty"`
+	Value     string `json:"value,omitempty"`
+	Session   bool   `json:"session,omitempty"`
 	TimeoutMs int    `json:"timeout_ms,omitempty"`
 }
 
-type URLParams struct {
-	Tab int `json:"tab"`
+type DownloadsParams struct {
+	Limit int `json:"limit,omitempty"`
 }
 
-type LinksParams struct {
-	Tab    int    `json:"tab"`
-	Filter string `json:"filter,omitempty"`
+type NetLogParams struct {
+	Limit int `json:"limit,omitempty"`
 }
@@ -1,13 +1,18 @@
 package daemon
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/patrickjm/www/internal/browser"
@@ -18,42 +23,178 @@ type Server struct {
 	engine      browser.Engine
 	storagePath string
 	mu          sync.Mutex
-	session     browser.Session
-	tabs        map[int]browser.Page
-	activeTab   int
-	nextTabID   int
-	stop        chan struct{}
-	stopOnce    sync.Once
+	storageMu   sync.Mutex
+	// session is only replaced with both mu and storageMu held, so either
+	// lock is enough to use it.
+	session      browser.Session
+	startOpts    browser.StartOptions
+	tabs         map[int]browser.Page
+	tabLocks     map[int]chan struct{}
+	crashed      map[int]bool
+	activeTab    int
+	nextTabID    int
+	stop         chan struct{}
+	stopOnce     sync.Once
+	logger       *slog.Logger
+	idleTimeout  time.Duration
+	noDefaultTab bool
+	home         string
+	autoDismiss  []string
+	ephemeral    bool
+	startedAt    time.Time
+	// tabCount mirrors len(tabs) so Ping answers without waiting on mu.
+	tabCount atomic.Int64
 }
 
+// DefaultIdleTimeout closes connections that send no request for this long.
+// It is generous so batch connections can pause between commands.
+const DefaultIdleTimeout = 30 * time.Minute
+
+// DefaultSocketMode and DefaultDirMode are the permissions of the daemon
+// socket and profile directory unless socket_mode or profile_mode says
+// otherwise.
+const (
+	DefaultSocketMode os.FileMode = 0o600
+	DefaultDirMode    os.FileMode = 0o700
+)
+
 func NewServer(profile string, engine browser.Engine, storagePath string) *Server {
 	return &Server{
 		profile:     profile,
 		engine:      engine,
 		storagePath: storagePath,
 		tabs:        make(map[int]browser.Page),
+		tabLocks:    make(map[int]chan struct{}),
+		crashed:     make(map[int]bool),
 		nextTabID:   1,
 		stop:        make(chan struct{}),
+		logger:      slog.New(slog.DiscardHandler),
+		idleTimeout: DefaultIdleTimeout,
+		startedAt:   time.Now(),
+	}
+}
+
+func (s *Server) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
 	}
+	s.logger = logger
+}
+
+// SetIdleTimeout sets how long a connection may wait between requests before
+// it is closed. Zero disables the deadline.
+func (s *Server) SetIdleTimeout(d time.Duration) {
+	s.idleTimeout = d
 }
 
 func (s *Server) Init(opts browser.StartOptions) error {
-	session, err := s.engine.Start(opts)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+	s.startOpts = opts
+	return s.startSessionLocked(!s.noDefaultTab)
+}
+
+// startSessionLocked launches a browser session and, when openTab is set,
+// its first tab, navigated to the home URL if one is configured. Callers hold
+// s.mu and s.storageMu.
+func (s *Server) startSessionLocked(openTab bool) error {
+	session, err := s.engine.Start(s.startOpts)
 	if err != nil {
 		return err
 	}
 	s.session = session
+	session.OnPage(func(page browser.Page) {
+		go s.adoptPage(page)
+	})
+	if !openTab {
+		return nil
+	}
 	page, err := session.NewPage()
 	if err != nil {
 		return err
 	}
-	s.tabs[1] = page
-	s.activeTab = 1
-	s.nextTabID = 2
+	s.activeTab = s.registerPageLocked(page)
+	if s.home != "" {
+		if err := s.gotoPage(page, s.home, browser.GotoOptions{}); err != nil {
+			s.logger.Warn("home navigation failed", "profile", s.profile, "url", s.home, "error", err.Error())
+		}
+	}
 	return nil
 }
 
+func (s *Server) adoptPage(page browser.Page) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.registerPageLocked(page)
+}
+
+func (s *Server) registerPageLocked(page browser.Page) int {
+	if id, ok := s.tabIDLocked(page); ok {
+		return id
+	}
+	id := s.nextTabID
+	s.nextTabID++
+	s.tabs[id] = page
+	s.tabCount.Store(int64(len(s.tabs)))
+	s.tabLocks[id] = make(chan struct{}, 1)
+	page.OnClose(func() {
+		go s.forgetPage(page)
+	})
+	page.OnCrash(func() {
+		go s.markCrashed(page)
+	})
+	return id
+}
+
+func (s *Server) forgetPage(page browser.Page) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id, ok := s.tabIDLocked(page); ok {
+		s.forgetTabLocked(id)
+	}
+}
+
+func (s *Server) markCrashed(page browser.Page) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id, ok := s.tabIDLocked(page); ok {
+		s.crashed[id] = true
+	}
+}
+
+func (s *Server) tabIDLocked(page browser.Page) (int, bool) {
+	for id, existing := range s.tabs {
+		if existing == page {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+func (s *Server) forgetTabLocked(tab int) {
+	delete(s.tabs, tab)
+	s.tabCount.Store(int64(len(s.tabs)))
+	delete(s.tabLocks, tab)
+	delete(s.crashed, tab)
+	if s.activeTab != tab {
+		return
+	}
+	s.activeTab = 0
+	for id := range s.tabs {
+		if s.activeTab == 0 || id < s.activeTab {
+			s.activeTab = id
+		}
+	}
+}
+
+// Serve accepts connections on l until a Stop request closes it.
 func (s *Server) Serve(l net.Listener) error {
+	go func() {
+		<-s.stop
+		_ = l.Close()
+	}()
 	for {
 		conn, err := l.Accept()
 		if err != nil {
@@ -73,6 +214,9 @@ func (s *Server) handleConn(conn net.Conn) {
 	dec := json.NewDecoder(conn)
 	enc := json.NewEncoder(conn)
 	for {
+		if s.idleTimeout > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(s.idleTimeout))
+		}
 		var req Request
 		if err := dec.Decode(&req); err != nil {
 			return
@@ -86,9 +230,20 @@ func (s *Server) handleConn(conn net.Conn) {
 }
 
 func (s *Server) handleRequest(req Request) Response {
+	started := time.Now()
 	result, err := s.dispatch(req)
+	if err == nil && persistsAfter(req) {
+		if perr := s.persistStorage(); perr != nil {
+			if tabMethods[req.Method] {
+				s.logger.Warn("saving storage failed", "profile", s.profile, "method", req.Method, "error", perr.Error())
+			} else {
+				err = perr
+			}
+		}
+	}
+	s.logRequest(req, time.Since(started), err)
 	if err != nil {
-		return Response{ID: req.ID, Error: &RespError{Message: err.Error()}}
+		return Response{ID: req.ID, Error: &RespError{Message: err.Error(), Code: errorCode(err)}}
 	}
 	if result == nil {
 		return Response{ID: req.ID}
@@ -100,64 +255,233 @@ func (s *Server) handleRequest(req Request) Response {
 	return Response{ID: req.ID, Result: b}
 }
 
-func (s *Server) dispatch(req Request) (any, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// readOnlyMethods cannot change cookies or storage, so the daemon does not
+// rewrite the profile's storage file after them.
+var readOnlyMethods = map[string]bool{
+	"Ping": true, "Status": true, "TabList": true, "TabSwitch": true, "Front": true,
+	"Cookies": true, "NetLog": true, "Downloads": true,
+	"URL": true, "Extract": true, "Links": true, "Tables": true, "Forms": true, "Outline": true,
+	"Content": true, "Box": true, "Count": true, "Text": true, "Attr": true, "Attrs": true,
+	"Shot": true, "PDF": true,
+}
+
+// tabMethods only log a failed storage save: the tab was already opened,
+// switched, or closed, and reporting an error would make callers retry it.
+var tabMethods = map[string]bool{"TabNew": true, "TabActivate": true, "TabClose": true}
+
+// persistsAfter reports whether storage should be saved after req succeeds.
+// Stop and Recycle save it themselves before closing the session.
+func persistsAfter(req Request) bool {
+	switch {
+	case readOnlyMethods[req.Method], req.Method == "Stop", req.Method == "Recycle":
+		return false
+	case req.Method == "StorageItem":
+		var params StorageItemParams
+		return json.Unmarshal(req.Params, &params) == nil && params.Op == "set"
+	}
+	return true
+}
 
+func errorCode(err error) string {
+	var timeout timeoutError
+	switch {
+	case errors.Is(err, browser.ErrElementDetached):
+		return CodeElementDetached
+	case errors.Is(err, browser.ErrNoMatch):
+		return CodeSelectorNoMatch
+	case errors.Is(err, errTabNotFound):
+		return CodeTabNotFound
+	case browser.IsTimeout(err), errors.As(err, &timeout):
+		return CodeTimeout
+	}
+	return ""
+}
+
+func (s *Server) logRequest(req Request, elapsed time.Duration, err error) {
+	attrs := []any{"profile", s.profile, "method", req.Method, "duration_ms", elapsed.Milliseconds()}
+	if err != nil {
+		s.logger.Warn("request failed", append(attrs, "error", err.Error())...)
+		return
+	}
+	s.logger.Info("request", attrs...)
+}
+
+// dispatch runs one request. s.mu only guards the tab table and is never held
+// across a page or session call, except while the session is replaced; each
+// tab has its own lock so actions on one tab are serialized while different
+// tabs run in parallel. Lock order is tab lock, then s.mu.
+func (s *Server) dispatch(req Request) (any, error) {
 	switch req.Method {
+	case "Ping":
+		return PingResult{PID: os.Getpid(), Uptime: int64(time.Since(s.startedAt).Seconds()), TabCount: int(s.tabCount.Load())}, nil
 	case "Status":
-		return s.statusLocked()
+		return s.status(), nil
 	case "TabList":
-		return s.statusLockedTabs()
+		return s.tabInfos(), nil
 	case "TabNew":
 		var params TabNewParams
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			return nil, err
 		}
-		return s.tabNewLocked(params.URL)
+		return s.tabNew(params.URL, params.Background, params.TimeoutMs)
+	case "TabActivate":
+		var params TabActivateParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.tabActivate(params)
 	case "TabSwitch":
 		var params TabSwitchParams
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			return nil, err
 		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
 		return nil, s.tabSwitchLocked(params.Tab)
 	case "TabClose":
 		var params TabCloseParams
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			return nil, err
 		}
-		return nil, s.tabCloseLocked(params.Tab)
+		return nil, s.tabClose(params.Tab)
 	case "Goto":
 		var params GotoParams
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			return nil, err
 		}
-		return nil, s.withTabLockedTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
-			return p.Goto(params.URL)
+		return nil, s.withTabTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
+			return s.gotoPage(p, params.URL, browser.GotoOptions{WaitUntil: params.WaitUntil})
 		})
 	case "Click":
 		var params ClickParams
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			return nil, err
 		}
-		return nil, s.withTabLockedTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
-			return p.Click(params.Selector)
-		})
+		opts := browser.ClickOptions{Raw: params.Raw, Nth: params.Nth, Exact: params.Exact}
+		if !params.ExpectPopup {
+			return nil, s.withTabTimeouts(params.Tab, params.TimeoutMs, params.SelectorTimeoutMs, func(p browser.Page) error {
+				return p.Click(params.Selector, opts)
+			})
+		}
+		var popup browser.Page
+		if err := s.withTabTimeouts(params.Tab, params.TimeoutMs, params.SelectorTimeoutMs, func(p browser.Page) error {
+			var err error
+			popup, err = p.ClickPopup(params.Selector, opts)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		id := s.registerPageLocked(popup)
+		s.activeTab = id
+		s.mu.Unlock()
+		url, _ := popup.URL()
+		title, _ := popup.Title()
+		return TabInfo{ID: id, URL: url, Title: title, Active: true}, nil
 	case "Fill":
 		var params FillParams
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			return nil, err
 		}
-		return nil, s.withTabLockedTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
-			return p.Fill(params.Selector, params.Value)
+		return nil, s.withTabTimeouts(params.Tab, params.TimeoutMs, params.SelectorTimeoutMs, func(p browser.Page) error {
+			return p.Fill(params.Selector, params.Value, browser.FillOptions{Nth: params.Nth})
+		})
+	case "Drag":
+		var params DragParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.withTabTimeouts(params.Tab, params.TimeoutMs, params.SelectorTimeoutMs, func(p browser.Page) error {
+			return p.DragTo(params.From, params.To)
+		})
+	case "Focus", "Blur":
+		var params FocusParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.withTabTimeouts(params.Tab, params.TimeoutMs, params.SelectorTimeoutMs, func(p browser.Page) error {
+			if req.Method == "Blur" {
+				return p.Blur(params.Selector)
+			}
+			return p.Focus(params.Selector)
+		})
+	case "Mouse":
+		var params MouseParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.withTabTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
+			return p.MouseMove(params.X, params.Y)
+		})
+	case "Select":
+		var params SelectParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		if len(params.Values) == 0 {
+			return nil, errors.New("at least one value required")
+		}
+		var selected []string
+		err := s.withTabTimeouts(params.Tab, params.TimeoutMs, params.SelectorTimeoutMs, func(p browser.Page) error {
+			var err error
+			selected, err = p.SelectOptions(params.Selector, params.Values, params.Add)
+			return err
+		})
+		return selected, err
+	case "Upload":
+		var params UploadParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		if len(params.Paths) == 0 {
+			return nil, errors.New("at least one file required")
+		}
+		return nil, s.withTabTimeouts(params.Tab, params.TimeoutMs, params.SelectorTimeoutMs, func(p browser.Page) error {
+			return p.SetInputFiles(params.Selector, params.Paths, browser.UploadOptions{Nth: params.Nth})
+		})
+	case "FillLabel":
+		var params FillLabelParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.withTabTimeouts(params.Tab, params.TimeoutMs, params.SelectorTimeoutMs, func(p browser.Page) error {
+			return p.FillByLabel(params.Label, params.Value)
 		})
 	case "Shot":
 		var params ShotParams
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			return nil, err
 		}
-		return nil, s.withTabLockedTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
-			return p.Screenshot(params.Path, params.FullPage, params.Selector)
+		if params.Clip != nil && params.Selector != "" {
+			return nil, errors.New("clip and selector cannot be combined")
+		}
+		return nil, s.withTabTimeouts(params.Tab, params.TimeoutMs, params.SelectorTimeoutMs, func(p browser.Page) error {
+			if params.Stable {
+				if _, err := p.Eval(stableLayoutJS, nil); err != nil {
+					return fmt.Errorf("wait for stable layout: %w", err)
+				}
+			}
+			if params.Highlight != "" {
+				color := params.HighlightColor
+				if color == "" {
+					color = "red"
+				}
+				if err := p.Highlight(params.Highlight, color); err != nil {
+					_ = p.ClearHighlight()
+					return err
+				}
+				defer func() { _ = p.ClearHighlight() }()
+			}
+			return p.Screenshot(params.Path, browser.ScreenshotOptions{FullPage: params.FullPage, Selector: params.Selector, Clip: params.Clip, Type: params.Type, Quality: params.Quality})
+		})
+	case "PDF":
+		var params PDFParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		opts := browser.PDFOptions{Format: params.Format, Landscape: params.Landscape, PrintBackground: params.PrintBackground}
+		return nil, s.withTabTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
+			return p.PDF(params.Path, opts)
 		})
 	case "Extract":
 		var params ExtractParams
@@ -165,9 +489,9 @@ func (s *Server) dispatch(req Request) (any, error) {
 			return nil, err
 		}
 		var result browser.ExtractResult
-		if err := s.withTabLockedTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
+		if err := s.withTabTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
 			var err error
-			result, err = p.Extract(browser.ExtractOptions{Selector: params.Selector, Main: params.Main})
+			result, err = p.Extract(browser.ExtractOptions{Selector: params.Selector, Main: params.Main, Format: params.Format, Shadow: params.Shadow, OnlyVisible: params.OnlyVisible, Aria: params.Aria})
 			return err
 		}); err != nil {
 			return nil, err
@@ -179,7 +503,7 @@ func (s *Server) dispatch(req Request) (any, error) {
 			return nil, err
 		}
 		var value string
-		if err := s.withTabLocked(params.Tab, func(p browser.Page) error {
+		if err := s.withTab(params.Tab, params.TimeoutMs, func(p browser.Page) error {
 			var err error
 			value, err = p.URL()
 			return err
@@ -193,30 +517,229 @@ func (s *Server) dispatch(req Request) (any, error) {
 			return nil, err
 		}
 		var links []browser.ExtractLink
-		if err := s.withTabLocked(params.Tab, func(p browser.Page) error {
+		if err := s.withTab(params.Tab, params.TimeoutMs, func(p browser.Page) error {
 			var err error
-			links, err = p.Links(params.Filter)
+			links, err = p.Links(browser.LinksOptions{Filter: params.Filter, Shadow: params.Shadow, OnlyVisible: params.OnlyVisible})
 			return err
 		}); err != nil {
 			return nil, err
 		}
 		return links, nil
+	case "Tables":
+		var params TablesParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		var tables []browser.ExtractTable
+		if err := s.withTabTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
+			var err error
+			tables, err = p.Tables(params.Selector)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		return tables, nil
+	case "Forms":
+		var params FormsParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		var forms []browser.ExtractForm
+		if err := s.withTabTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
+			var err error
+			forms, err = p.Forms()
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		return forms, nil
+	case "Outline":
+		var params OutlineParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		var headings []browser.Heading
+		if err := s.withTabTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
+			var err error
+			headings, err = p.Outline()
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		return headings, nil
+	case "Content":
+		var params ContentParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		var html string
+		if err := s.withTabTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
+			var err error
+			html, err = p.Content(params.Selector)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		return html, nil
+	case "Box":
+		var params BoxParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		var box *browser.Box
+		if err := s.withTabTimeouts(params.Tab, params.TimeoutMs, params.SelectorTimeoutMs, func(p browser.Page) error {
+			var err error
+			box, err = p.BoundingBox(params.Selector)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		return box, nil
+	case "Count":
+		var params CountParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		var count int
+		if err := s.withTabTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
+			var err error
+			count, err = p.Count(params.Selector)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		return count, nil
+	case "Text":
+		var params TextParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		var text browser.ElementText
+		if err := s.withTabTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
+			var err error
+			text, err = p.TextContent(params.Selector)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		return text, nil
+	case "Attr":
+		var params AttrParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		var attr browser.ElementAttr
+		if err := s.withTabTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
+			var err error
+			attr, err = p.Attribute(params.Selector, params.Name)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		return attr, nil
+	case "Attrs":
+		var params AttrsParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		var attrs []browser.ElementAttrs
+		if err := s.withTabTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
+			var err error
+			attrs, err = p.Attributes(params.Selector)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		// No match is reported as an empty result with or without Nth, so
+		// callers see it as not found rather than out of range.
+		if params.Nth == nil || len(attrs) == 0 {
+			return attrs, nil
+		}
+		if *params.Nth < 0 || *params.Nth >= len(attrs) {
+			return nil, fmt.Errorf("nth %d out of range: %s matched %d elements", *params.Nth, params.Selector, len(attrs))
+		}
+		return attrs[*params.Nth : *params.Nth+1], nil
+	case "StorageItem":
+		var params StorageItemParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		switch params.Op {
+		case "get", "set", "list":
+		default:
+			return nil, fmt.Errorf("unknown storage op %q", params.Op)
+		}
+		var result browser.StorageResult
+		if err := s.withTabTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
+			var err error
+			result, err = p.StorageItem(browser.StorageOptions{Op: params.Op, Key: params.Key, Value: params.Value, Session: params.Session})
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		return result, nil
 	case "Eval":
 		var params EvalParams
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			return nil, err
 		}
 		var result json.RawMessage
-		if err := s.withTabLockedTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
+		if err := s.withTabTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
 			var err error
-			result, err = p.Eval(params.JS)
+			result, err = p.Eval(params.JS, params.Args)
 			return err
 		}); err != nil {
 			return nil, err
 		}
 		return result, nil
+	case "WaitIdle":
+		var params WaitIdleParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.withTabTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
+			return p.WaitForLoadState("networkidle", params.TimeoutMs)
+		})
+	case "WaitURL":
+		var params WaitURLParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.withTabTimeout(params.Tab, params.TimeoutMs, func(p browser.Page) error {
+			if err := p.WaitForURL(params.Pattern, params.Regex, params.TimeoutMs); err != nil {
+				current, _ := p.URL()
+				return fmt.Errorf("%w (current URL: %s)", err, current)
+			}
+			return nil
+		})
+	case "Front":
+		var params FrontParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.withTab(params.Tab, params.TimeoutMs, func(p browser.Page) error {
+			return p.BringToFront()
+		})
+	case "Cookies":
+		return s.currentSession().Cookies()
+	case "NetLog":
+		var params NetLogParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.currentSession().NetLog(params.Limit)
+	case "Downloads":
+		var params DownloadsParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.currentSession().Downloads(params.Limit)
+	case "Recycle":
+		return s.recycle()
 	case "Stop":
-		_ = s.persistStorageLocked()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		_ = s.persistStorage()
 		_ = s.shutdownLocked()
 		s.stopOnce.Do(func() { close(s.stop) })
 		return nil, nil
@@ -225,15 +748,160 @@ func (s *Server) dispatch(req Request) (any, error) {
 	}
 }
 
-func (s *Server) statusLocked() (StatusResult, error) {
-	tabs, err := s.statusLockedTabs()
-	if err != nil {
+// currentSession returns the browser session without holding s.mu across
+// calls into it, so a wedged browser cannot block requests that need s.mu.
+func (s *Server) currentSession() browser.Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.session
+}
+
+func (s *Server) status() StatusResult {
+	return StatusResult{Profile: s.profile, Tabs: s.tabInfos()}
+}
+
+// tabInfoTimeout bounds reading a tab's URL and title, so a wedged page shows
+// up as unresponsive instead of blocking status.
+var tabInfoTimeout = 2 * time.Second
+
+// tabInfos describes every tab. Pages are queried in parallel and without
+// s.mu, so a hung page cannot hold up other requests.
+func (s *Server) tabInfos() []TabInfo {
+	s.mu.Lock()
+	infos := make([]TabInfo, 0, len(s.tabs))
+	pages := make(map[int]browser.Page, len(s.tabs))
+	for id, page := range s.tabs {
+		infos = append(infos, TabInfo{ID: id, Active: id == s.activeTab, Crashed: s.crashed[id]})
+		pages[id] = page
+	}
+	s.mu.Unlock()
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+	var wg sync.WaitGroup
+	for i := range infos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info := &infos[i]
+			var ok bool
+			info.URL, info.Title, ok = describePage(pages[info.ID])
+			info.Unresponsive = !ok
+		}()
+	}
+	wg.Wait()
+	return infos
+}
+
+// describePage reads the page's URL and title, giving up after tabInfoTimeout.
+func describePage(page browser.Page) (string, string, bool) {
+	type description struct{ url, title string }
+	done := make(chan description, 1)
+	go func() {
+		url, _ := page.URL()
+		title, _ := page.Title()
+		done <- description{url, title}
+	}()
+	select {
+	case d := <-done:
+		return d.url, d.title, true
+	case <-time.After(tabInfoTimeout):
+		return "", "", false
+	}
+}
+
+// tabNew opens a tab and makes it active, unless background is set and
+// another tab is already active.
+func (s *Server) tabNew(url string, background bool, timeoutMs int) (TabInfo, error) {
+	var page browser.Page
+	for {
+		session := s.currentSession()
+		var err error
+		page, err = session.NewPage()
+		s.mu.Lock()
+		if s.session == session {
+			if err != nil {
+				s.mu.Unlock()
+				return TabInfo{}, err
+			}
+			break
+		}
+		// The browser was recycled while the page opened; open it again in
+		// the new session.
+		s.mu.Unlock()
+		if err == nil {
+			_ = page.Close()
+		}
+	}
+	id := s.registerPageLocked(page)
+	if _, ok := s.tabs[s.activeTab]; !background || !ok {
+		s.activeTab = id
+	}
+	active := s.activeTab == id
+	s.mu.Unlock()
+	if url != "" {
+		if err := s.withTab(id, timeoutMs, func(p browser.Page) error {
+			return s.gotoPage(p, url, browser.GotoOptions{})
+		}); err != nil {
+			return TabInfo{}, err
+		}
+		return TabInfo{ID: id, Active: active}, nil
+	}
+	return TabInfo{ID: id, Active: active}, nil
+}
+
+// recycle replaces the browser session with a fresh one while the daemon and
+// socket stay up. It waits briefly for in-flight actions, saves storage so the
+// new session keeps cookies, and leaves a single new tab. A tab still busy
+// after requestGrace is closed with the session, which fails its action.
+func (s *Server) recycle() (StatusResult, error) {
+	s.mu.Lock()
+	locks := make([]chan struct{}, 0, len(s.tabLocks))
+	for _, lock := range s.tabLocks {
+		locks = append(locks, lock)
+	}
+	s.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), requestGrace)
+	defer cancel()
+	held := make([]chan struct{}, 0, len(locks))
+	for _, lock := range locks {
+		select {
+		case lock <- struct{}{}:
+			held = append(held, lock)
+		case <-ctx.Done():
+			s.logger.Warn("recycling with busy tabs", "profile", s.profile)
+		}
+	}
+	defer func() {
+		for _, lock := range held {
+			<-lock
+		}
+	}()
+	if err := s.persistStorage(); err != nil {
 		return StatusResult{}, err
 	}
-	return StatusResult{Profile: s.profile, Tabs: tabs}, nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+	if err := s.session.Close(); err != nil {
+		s.logger.Warn("closing session for recycle failed", "profile", s.profile, "error", err.Error())
+	}
+	s.tabs = make(map[int]browser.Page)
+	s.tabCount.Store(0)
+	s.tabLocks = make(map[int]chan struct{})
+	s.crashed = make(map[int]bool)
+	s.activeTab = 0
+	s.nextTabID = 1
+	if err := s.startSessionLocked(true); err != nil {
+		return StatusResult{}, fmt.Errorf("restart browser: %w", err)
+	}
+	return StatusResult{Profile: s.profile, Tabs: s.tabInfosLocked()}, nil
 }
 
-func (s *Server) statusLockedTabs() ([]TabInfo, error) {
+// tabInfosLocked describes tabs while s.mu is held; only for freshly opened
+// pages, which cannot be wedged yet.
+func (s *Server) tabInfosLocked() []TabInfo {
 	infos := make([]TabInfo, 0, len(s.tabs))
 	for id, page := range s.tabs {
 		url, _ := page.URL()
@@ -243,86 +911,237 @@ func (s *Server) statusLockedTabs() ([]TabInfo, error) {
 	sort.Slice(infos, func(i, j int) bool {
 		return infos[i].ID < infos[j].ID
 	})
-	return infos, nil
+	return infos
 }
 
-func (s *Server) tabNewLocked(url string) (TabInfo, error) {
-	page, err := s.session.NewPage()
-	if err != nil {
-		return TabInfo{}, err
+// tabActivate switches to the first tab whose URL matches, or opens the URL
+// in a new active tab when none does.
+func (s *Server) tabActivate(params TabActivateParams) (TabInfo, error) {
+	switch params.Match {
+	case "", "prefix", "contains", "exact":
+	default:
+		return TabInfo{}, fmt.Errorf("invalid match %q: expected prefix, contains, or exact", params.Match)
 	}
-	id := s.nextTabID
-	s.nextTabID++
-	s.tabs[id] = page
-	s.activeTab = id
-	if url != "" {
-		if err := page.Goto(url); err != nil {
-			return TabInfo{}, err
+	for _, tab := range s.tabInfos() {
+		if tab.Crashed || tab.Unresponsive || !urlMatches(tab.URL, params.URL, params.Match) {
+			continue
+		}
+		s.mu.Lock()
+		if s.tabSwitchLocked(tab.ID) != nil {
+			s.mu.Unlock()
+			continue
 		}
+		s.mu.Unlock()
+		tab.Active = true
+		return tab, nil
 	}
-	_ = s.persistStorageLocked()
-	return TabInfo{ID: id, Active: true}, nil
+	return s.tabNew(params.URL, false, params.TimeoutMs)
+}
+
+func urlMatches(url string, target string, match string) bool {
+	switch match {
+	case "contains":
+		return strings.Contains(url, target)
+	case "exact":
+		return url == target
+	}
+	return strings.HasPrefix(url, target)
 }
 
 func (s *Server) tabSwitchLocked(tab int) error {
 	if _, ok := s.tabs[tab]; !ok {
-		return errors.New("tab not found")
+		return errTabNotFound
 	}
 	s.activeTab = tab
 	return nil
 }
 
-func (s *Server) tabCloseLocked(tab int) error {
+func (s *Server) tabClose(tab int) error {
+	s.mu.Lock()
+	lock, ok := s.tabLocks[tab]
+	s.mu.Unlock()
+	if !ok {
+		return errTabNotFound
+	}
+	// Wait briefly for any in-flight action on the tab. A hung page is closed
+	// anyway, which fails the stuck action and frees the tab.
+	select {
+	case lock <- struct{}{}:
+		defer func() { <-lock }()
+	case <-time.After(requestGrace):
+		s.logger.Warn("closing busy tab", "profile", s.profile, "tab", tab)
+	}
+	s.mu.Lock()
 	page, ok := s.tabs[tab]
 	if !ok {
-		return errors.New("tab not found")
+		s.mu.Unlock()
+		return errTabNotFound
 	}
+	s.forgetTabLocked(tab)
+	s.mu.Unlock()
 	_ = page.Close()
-	delete(s.tabs, tab)
-	if s.activeTab == tab {
-		s.activeTab = 0
-		for id := range s.tabs {
-			s.activeTab = id
-			break
-		}
-	}
-	_ = s.persistStorageLocked()
 	return nil
 }
 
-func (s *Server) withTabLocked(tab int, fn func(browser.Page) error) error {
+// ErrNoTabs reports that the daemon has no open tab to act on.
+var ErrNoTabs = errors.New("no tabs; open one first with tab new")
+
+var errTabNotFound = errors.New("tab not found")
+
+// timeoutError is a daemon-side timeout whose message is shown as is.
+type timeoutError string
+
+func (e timeoutError) Error() string {
+	return string(e)
+}
+
+// stableLayoutJS resolves once web fonts have loaded and the document size
+// has stayed the same for two consecutive animation frames. It gives up after
+// about a second of frames so constantly animating pages still get captured.
+const stableLayoutJS = `async () => {
+  if (document.fonts && document.fonts.ready) await document.fonts.ready;
+  const frame = () => new Promise(resolve => requestAnimationFrame(() => resolve()));
+  const layout = () => {
+    const el = document.documentElement;
+    return [el.scrollWidth, el.scrollHeight, el.getBoundingClientRect().width].join(",");
+  };
+  let last = layout();
+  let unchanged = 0;
+  for (let i = 0; i < 60 && unchanged < 2; i++) {
+    await frame();
+    const next = layout();
+    unchanged = next === last ? unchanged + 1 : 0;
+    last = next;
+  }
+  return unchanged >= 2;
+}`
+
+// requestGrace is added to a request's timeout before the server stops
+// waiting on the page, so Playwright normally reports its own timeout first.
+var requestGrace = 5 * time.Second
+
+// DefaultActionTimeout bounds a request that does not send its own timeout.
+const DefaultActionTimeout = 20 * time.Second
+
+// withTab runs fn on the tab like withTabDeadline, falling back to
+// DefaultActionTimeout so a tab held by an abandoned action cannot block the
+// request forever.
+func (s *Server) withTab(tab int, timeoutMs int, fn func(browser.Page) error) error {
+	timeout := time.Duration(timeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = DefaultActionTimeout
+	}
+	return s.withTabDeadline(tab, timeout, fn)
+}
+
+// withTabDeadline runs fn while holding the tab's lock. With a timeout, the
+// server gives up after timeout+requestGrace and answers the client even if
+// the page is wedged; Playwright calls cannot be interrupted, so the action
+// keeps the tab busy until it returns, but other requests are not blocked.
+func (s *Server) withTabDeadline(tab int, timeout time.Duration, fn func(browser.Page) error) error {
+	id, page, lock, err := s.lookupTab(tab)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout+requestGrace)
+	}
+	defer cancel()
+	select {
+	case lock <- struct{}{}:
+	case <-ctx.Done():
+		return timeoutError(fmt.Sprintf("tab %d is busy: a previous action is still running after %s", id, timeout+requestGrace))
+	}
+	// The tab may have been closed while this request waited for it.
+	s.mu.Lock()
+	current, ok := s.tabs[id]
+	s.mu.Unlock()
+	if !ok || current != page {
+		<-lock
+		return errTabNotFound
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() { <-lock }()
+		done <- fn(page)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		s.logger.Warn("request abandoned", "profile", s.profile, "tab", id, "timeout_ms", (timeout + requestGrace).Milliseconds())
+		return timeoutError(fmt.Sprintf("tab %d did not respond within %s; the page may be hung", id, timeout+requestGrace))
+	}
+}
+
+func (s *Server) lookupTab(tab int) (int, browser.Page, chan struct{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if tab == 0 {
+		if len(s.tabs) == 0 {
+			return 0, nil, nil, ErrNoTabs
+		}
 		tab = s.activeTab
 	}
 	page, ok := s.tabs[tab]
 	if !ok {
-		return errors.New("tab not found")
+		return 0, nil, nil, errTabNotFound
 	}
-	if err := fn(page); err != nil {
-		return err
+	if s.crashed[tab] {
+		return 0, nil, nil, fmt.Errorf("tab %d crashed; close it with tab close %d", tab, tab)
 	}
-	return s.persistStorageLocked()
+	return tab, page, s.tabLocks[tab], nil
 }
 
-func (s *Server) withTabLockedTimeout(tab int, timeoutMs int, fn func(browser.Page) error) error {
-	return s.withTabLocked(tab, func(p browser.Page) error {
+func (s *Server) withTabTimeout(tab int, timeoutMs int, fn func(browser.Page) error) error {
+	return s.withTabTimeouts(tab, timeoutMs, 0, fn)
+}
+
+func (s *Server) withTabTimeouts(tab int, timeoutMs int, selectorTimeoutMs int, fn func(browser.Page) error) error {
+	return s.withTabDeadline(tab, time.Duration(timeoutMs)*time.Millisecond, func(p browser.Page) error {
 		if timeoutMs > 0 {
 			_ = p.SetTimeout(timeoutMs)
 		}
+		if selectorTimeoutMs > 0 {
+			_ = p.SetSelectorTimeout(selectorTimeoutMs)
+		}
 		return fn(p)
 	})
 }
 
-func (s *Server) persistStorageLocked() error {
-	if s.storagePath == "" {
+// gotoPage navigates and then clicks any visible auto-dismiss selectors to
+// clear consent banners. Dismissal is best-effort and never fails the request.
+func (s *Server) gotoPage(p browser.Page, url string, opts browser.GotoOptions) error {
+	if err := p.Goto(url, opts); err != nil {
+		return err
+	}
+	if len(s.autoDismiss) > 0 {
+		if clicked := p.Dismiss(s.autoDismiss); len(clicked) > 0 {
+			s.logger.Debug("dismissed overlays", "profile", s.profile, "url", url, "selectors", clicked)
+		}
+	}
+	return nil
+}
+
+func (s *Server) persistStorage() error {
+	if s.storagePath == "" || s.ephemeral {
 		return nil
 	}
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
 	if err := os.MkdirAll(filepath.Dir(s.storagePath), 0o755); err != nil {
 		return err
 	}
 	return s.session.StorageState(s.storagePath)
 }
 
+func (s *Server) shutdown() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shutdownLocked()
+}
+
 func (s *Server) shutdownLocked() error {
 	if s.session != nil {
 		return s.session.Close()
@@ -330,28 +1149,71 @@ func (s *Server) shutdownLocked() error {
 	return nil
 }
 
-func ServeProfile(socketPath string, profile string, engine browser.Engine, opts browser.StartOptions) error {
-	if err := os.MkdirAll(filepath.Dir(socketPath), 0o755); err != nil {
+type ServeOptions struct {
+	Logger     *slog.Logger
+	SocketMode os.FileMode
+	DirMode    os.FileMode
+	// IdleTimeout overrides DefaultIdleTimeout when set; zero disables it.
+	IdleTimeout  *time.Duration
+	NoDefaultTab bool
+	Home         string
+	AutoDismiss  []string
+	// Ephemeral starts without the saved storage state and never writes it.
+	Ephemeral bool
+}
+
+func ServeProfile(socketPath string, profile string, engine browser.Engine, opts browser.StartOptions, serveOpts ServeOptions) error {
+	socketMode := serveOpts.SocketMode
+	if socketMode == 0 {
+		socketMode = DefaultSocketMode
+	}
+	dirMode := serveOpts.DirMode
+	if dirMode == 0 {
+		dirMode = DefaultDirMode
+	}
+	// Every error before Serve is recorded where the starting client looks
+	// for it, so start reports it instead of timing out.
+	errorPath := filepath.Join(filepath.Dir(socketPath), startupErrorName)
+	_ = os.Remove(errorPath)
+	startupFailed := func(err error) error {
+		_ = os.WriteFile(errorPath, []byte(err.Error()+"\n"), 0o644)
 		return err
 	}
+	if err := os.MkdirAll(filepath.Dir(socketPath), dirMode); err != nil {
+		return startupFailed(err)
+	}
+	if err := os.Chmod(filepath.Dir(socketPath), dirMode); err != nil {
+		return startupFailed(err)
+	}
+	if serveOpts.Ephemeral {
+		opts.StorageIn = ""
+	}
 	server := NewServer(profile, engine, opts.StorageIn)
+	server.ephemeral = serveOpts.Ephemeral
+	server.SetLogger(serveOpts.Logger)
+	server.noDefaultTab = serveOpts.NoDefaultTab
+	server.home = serveOpts.Home
+	server.autoDismiss = serveOpts.AutoDismiss
+	if serveOpts.IdleTimeout != nil {
+		server.SetIdleTimeout(*serveOpts.IdleTimeout)
+	}
 	if err := server.Init(opts); err != nil {
-		return err
+		return startupFailed(err)
 	}
 	if err := os.RemoveAll(socketPath); err != nil {
-		_ = server.shutdownLocked()
-		return err
+		_ = server.shutdown()
+		return startupFailed(err)
 	}
-	l, err := net.Listen("unix", socketPath)
+	l, err := listenSocket(socketPath)
 	if err != nil {
-		_ = server.shutdownLocked()
-		return err
+		_ = server.shutdown()
+		return startupFailed(err)
 	}
 	defer l.Close()
-	go func() {
-		<-server.stop
-		_ = l.Close()
-	}()
+	if err := os.Chmod(socketPath, socketMode); err != nil {
+		_ = server.shutdown()
+		return startupFailed(err)
+	}
 	return server.Serve(l)
 }
 
@@ -1,9 +1,16 @@
 package daemon
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -31,7 +38,7 @@ func TestServerTabLifecycle(t *testing.T) {
 
 	errCh := make(chan error, 1)
 	go func() {
-		errCh <- ServeProfile(socket, "test", engine, opts)
+		errCh <- ServeProfile(socket, "test", engine, opts, ServeOptions{})
 	}()
 	if err := waitForSocket(socket, 2*time.Second); err != nil {
 		t.Fatalf("wait socket: %v", err)
@@ -49,14 +56,14 @@ func TestServerTabLifecycle(t *testing.T) {
 	if len(tabs) != 1 {
 		t.Fatalf("expected 1 tab, got %d", len(tabs))
 	}
-	url, err := client.URL(tabs[0].ID)
+	url, err := client.URL(tabs[0].ID, 1000)
 	if err != nil {
 		t.Fatalf("url: %v", err)
 	}
 	if url != "" {
 		t.Fatalf("expected empty url, got %s", url)
 	}
-	links, err := client.Links(tabs[0].ID, "")
+	links, err := client.Links(tabs[0].ID, "", 1000)
 	if err != nil {
 		t.Fatalf("links: %v", err)
 	}
@@ -83,3 +90,1335 @@ func TestServerTabLifecycle(t *testing.T) {
 		t.Fatalf("expected pages to be created")
 	}
 }
+
+// startTestServer serves a headless fake profile with the given options and
+// stops it when the test ends.
+func startTestServer(t *testing.T, engine *browser.FakeEngine, opts browser.StartOptions, serveOpts ServeOptions) *Client {
+	t.Helper()
+	if engine.Session == nil {
+		engine.Session = &browser.FakeSession{}
+	}
+	opts.Headless = true
+	socket := filepath.Join(t.TempDir(), "daemon.sock")
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ServeProfile(socket, "test", engine, opts, serveOpts)
+	}()
+	if err := waitForSocket(socket, 2*time.Second); err != nil {
+		t.Fatalf("wait socket: %v", err)
+	}
+	client, err := NewClient(socket)
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = client.Stop()
+		_ = client.Close()
+		<-errCh
+	})
+	return client
+}
+
+func TestServerPDF(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	path := filepath.Join(t.TempDir(), "page.pdf")
+	if err := client.PDF(0, path, browser.PDFOptions{Format: "A4"}, 1000); err != nil {
+		t.Fatalf("pdf: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat pdf: %v", err)
+	}
+	if info.Size() == 0 {
+		t.Fatalf("expected non-empty pdf")
+	}
+	if pdfs := engine.Session.Pages[0].PDFs; len(pdfs) != 1 || pdfs[0] != path {
+		t.Fatalf("expected pdf recorded, got %v", pdfs)
+	}
+}
+
+func TestServerShotHighlight(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	err := client.ShotWithOptions(ShotParams{Path: "/tmp/shot.png", Highlight: "css=#login", TimeoutMs: 1000})
+	if err != nil {
+		t.Fatalf("shot: %v", err)
+	}
+	page := engine.Session.Pages[0]
+	if len(page.Highlights) != 1 || page.Highlights[0] != "css=#login=red" {
+		t.Fatalf("expected highlight recorded, got %v", page.Highlights)
+	}
+	if page.Highlit {
+		t.Fatalf("expected highlight cleared after shot")
+	}
+	if len(page.Shots) != 1 {
+		t.Fatalf("expected 1 shot, got %d", len(page.Shots))
+	}
+}
+
+func TestServerGotoWaitUntil(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	if err := client.GotoWithOptions(GotoParams{URL: "https://example.com", WaitUntil: "networkidle", TimeoutMs: 1000}); err != nil {
+		t.Fatalf("goto: %v", err)
+	}
+	page := engine.Session.Pages[0]
+	if page.URLValue != "https://example.com" {
+		t.Fatalf("expected url set, got %s", page.URLValue)
+	}
+	if page.WaitUntil != "networkidle" {
+		t.Fatalf("expected networkidle, got %q", page.WaitUntil)
+	}
+}
+
+func TestServerClickExpectPopup(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	tab, err := client.ClickPopup(ClickParams{Selector: "text=Sign in with Google", TimeoutMs: 1000})
+	if err != nil {
+		t.Fatalf("click popup: %v", err)
+	}
+	if tab.ID != 2 || !tab.Active {
+		t.Fatalf("expected active popup tab 2, got %+v", tab)
+	}
+	tabs, err := client.TabList()
+	if err != nil {
+		t.Fatalf("tab list: %v", err)
+	}
+	if len(tabs) != 2 {
+		t.Fatalf("expected 2 tabs, got %d", len(tabs))
+	}
+	if !tabs[1].Active || tabs[1].ID != 2 {
+		t.Fatalf("expected popup to be active, got %+v", tabs)
+	}
+}
+
+func TestServerAdoptsPopups(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	engine.Session.OpenPopup()
+	tabs := waitForTabs(t, client, 2)
+	if !tabs[0].Active {
+		t.Fatalf("expected adoption to keep the active tab, got %+v", tabs)
+	}
+}
+
+func TestServerForgetsClosedPages(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	popup := engine.Session.OpenPopup()
+	waitForTabs(t, client, 2)
+	if err := client.TabSwitch(2); err != nil {
+		t.Fatalf("tab switch: %v", err)
+	}
+	_ = popup.Close()
+	tabs := waitForTabs(t, client, 1)
+	if tabs[0].ID != 1 || !tabs[0].Active {
+		t.Fatalf("expected tab 1 to become active, got %+v", tabs)
+	}
+}
+
+func waitForTabs(t *testing.T, client *Client, count int) []TabInfo {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		tabs, err := client.TabList()
+		if err != nil {
+			t.Fatalf("tab list: %v", err)
+		}
+		if len(tabs) == count {
+			return tabs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d tabs, got %d", count, len(tabs))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServerSelectorTimeout(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	if err := client.ClickWithOptions(ClickParams{Selector: "#go", TimeoutMs: 30000, SelectorTimeoutMs: 2000}); err != nil {
+		t.Fatalf("click: %v", err)
+	}
+	page := engine.Session.Pages[0]
+	if page.TimeoutMs != 30000 || page.SelectorMs != 2000 {
+		t.Fatalf("expected 30000/2000 timeouts, got %d/%d", page.TimeoutMs, page.SelectorMs)
+	}
+	if err := client.Fill(0, "#q", "x", 30000); err != nil {
+		t.Fatalf("fill: %v", err)
+	}
+	if page.SelectorMs != 30000 {
+		t.Fatalf("expected selector timeout to default to action timeout, got %d", page.SelectorMs)
+	}
+}
+
+func TestServerFillNth(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	page := engine.Session.Pages[0]
+	nth := 1
+	if err := client.FillWithOptions(FillParams{Selector: "#q", Value: "y", Nth: &nth}); err != nil {
+		t.Fatalf("fill nth: %v", err)
+	}
+	if page.FillOpts.Nth == nil || *page.FillOpts.Nth != 1 {
+		t.Fatalf("expected nth to reach the page, got %+v", page.FillOpts)
+	}
+}
+
+func TestServerReportsCrashedTabs(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	engine.Session.Pages[0].Crash()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		tabs, err := client.TabList()
+		if err != nil {
+			t.Fatalf("tab list: %v", err)
+		}
+		if len(tabs) == 1 && tabs[0].Crashed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected crashed tab, got %+v", tabs)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	err := client.Click(1, "#go", 0)
+	if err == nil || !strings.Contains(err.Error(), "crashed") {
+		t.Fatalf("expected crashed error, got %v", err)
+	}
+	if err := client.TabClose(1); err != nil {
+		t.Fatalf("tab close: %v", err)
+	}
+	waitForTabs(t, client, 0)
+}
+
+func TestServerFront(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	if err := client.Front(0, 1000); err != nil {
+		t.Fatalf("front: %v", err)
+	}
+	if !engine.Session.Pages[0].Fronted {
+		t.Fatalf("expected page to be brought to front")
+	}
+}
+
+func TestServerCount(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	engine.Session.Pages[0].CountRes = 2
+	count, err := client.Count(0, "css=.captcha", 1000)
+	if err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 matches, got %d", count)
+	}
+}
+
+func TestServerText(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	engine.Session.Pages[0].TextRes = browser.ElementText{Text: "$19.99", Count: 2}
+	text, err := client.Text(0, "css=.price", 1000)
+	if err != nil {
+		t.Fatalf("text: %v", err)
+	}
+	if text.Text != "$19.99" || text.Count != 2 {
+		t.Fatalf("unexpected text: %+v", text)
+	}
+}
+
+func TestServerAttr(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	attr, err := client.Attr(0, "css=a", "href", 1000)
+	if err != nil {
+		t.Fatalf("attr: %v", err)
+	}
+	if attr.Found {
+		t.Fatalf("expected no match, got %+v", attr)
+	}
+	engine.Session.Pages[0].Attrs = map[string]string{"href": "/next"}
+	attr, err = client.Attr(0, "css=a", "href", 1000)
+	if err != nil {
+		t.Fatalf("attr: %v", err)
+	}
+	if !attr.Found || attr.Value != "/next" {
+		t.Fatalf("unexpected attr: %+v", attr)
+	}
+}
+
+func TestServerExtractLinksOptions(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	if _, err := client.ExtractWithParams(ExtractParams{Shadow: true, OnlyVisible: true}); err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	engine.Session.Pages[0].LinksRes = []browser.ExtractLink{{Text: "Docs", Href: "https://example.com/docs", Rel: "noopener", Target: "_blank"}}
+	links, err := client.LinksWithOptions(LinksParams{Filter: "docs", Shadow: true, OnlyVisible: true})
+	if err != nil {
+		t.Fatalf("links: %v", err)
+	}
+	if len(links) != 1 || links[0].Rel != "noopener" || links[0].Target != "_blank" {
+		t.Fatalf("expected rel and target, got %+v", links)
+	}
+	page := engine.Session.Pages[0]
+	if !page.ExtractOpts.Shadow || !page.ExtractOpts.OnlyVisible {
+		t.Fatalf("unexpected extract options: %+v", page.ExtractOpts)
+	}
+	if !page.LinksOpts.Shadow || !page.LinksOpts.OnlyVisible || page.LinksOpts.Filter != "docs" {
+		t.Fatalf("unexpected links options: %+v", page.LinksOpts)
+	}
+}
+
+func TestServerExtractAria(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	page := engine.Session.Pages[0]
+	page.ExtractRes = browser.ExtractResult{URL: "https://example.com", Aria: []browser.AriaNode{{Role: "navigation", Name: "Primary"}, {Role: "button", Name: "Sign in"}}}
+	raw, err := client.ExtractWithParams(ExtractParams{Aria: true})
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if !page.ExtractOpts.Aria {
+		t.Fatalf("expected aria option, got %+v", page.ExtractOpts)
+	}
+	var result browser.ExtractResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(result.Aria) != 2 || result.Aria[1].Role != "button" || result.Aria[1].Name != "Sign in" {
+		t.Fatalf("unexpected aria nodes: %+v", result.Aria)
+	}
+}
+
+func TestServerLogsRequests(t *testing.T) {
+	server := NewServer("test", &browser.FakeEngine{}, "")
+	if err := server.Init(browser.StartOptions{Headless: true}); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	var buf bytes.Buffer
+	server.SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	server.handleRequest(Request{ID: "1", Method: "Status"})
+	server.handleRequest(Request{ID: "2", Method: "Bogus"})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %q", buf.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if entry["method"] != "Status" || entry["error"] != nil {
+		t.Fatalf("unexpected entry: %v", entry)
+	}
+	if _, ok := entry["duration_ms"]; !ok {
+		t.Fatalf("expected duration in entry: %v", entry)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if entry["method"] != "Bogus" || entry["error"] == nil || entry["level"] != "WARN" {
+		t.Fatalf("unexpected error entry: %v", entry)
+	}
+}
+
+func TestServerSelectMultiple(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	page := engine.Session.Pages[0]
+	if _, err := client.Select(SelectParams{Selector: "css=#one", Values: []string{"a", "b"}}); err == nil {
+		t.Fatalf("expected error for multiple values on single select")
+	}
+	page.Multiple = true
+	selected, err := client.Select(SelectParams{Selector: "css=#many", Values: []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if strings.Join(selected, ",") != "a,b" {
+		t.Fatalf("unexpected selection: %v", selected)
+	}
+	selected, err = client.Select(SelectParams{Selector: "css=#many", Values: []string{"c"}, Add: true})
+	if err != nil {
+		t.Fatalf("select add: %v", err)
+	}
+	if strings.Join(selected, ",") != "a,b,c" {
+		t.Fatalf("expected accumulated selection, got %v", selected)
+	}
+	selected, err = client.Select(SelectParams{Selector: "css=#many", Values: []string{"d"}})
+	if err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if strings.Join(selected, ",") != "d" {
+		t.Fatalf("expected selection to be replaced, got %v", selected)
+	}
+}
+
+func TestServerStorageItem(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	got, err := client.StorageItem(StorageItemParams{Op: "get", Key: "token"})
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Found {
+		t.Fatalf("expected missing key, got %+v", got)
+	}
+	if _, err := client.StorageItem(StorageItemParams{Op: "set", Key: "token", Value: `{"a":1}`}); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	got, err = client.StorageItem(StorageItemParams{Op: "get", Key: "token"})
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if !got.Found || got.Value != `{"a":1}` {
+		t.Fatalf("unexpected value: %+v", got)
+	}
+	list, err := client.StorageItem(StorageItemParams{Op: "list", Session: true})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list.Items) != 0 {
+		t.Fatalf("expected sessionStorage to be separate, got %v", list.Items)
+	}
+	if _, err := client.StorageItem(StorageItemParams{Op: "clear"}); err == nil {
+		t.Fatalf("expected error for unknown op")
+	}
+}
+
+func TestServerAttrs(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	engine.Session.Pages[0].AttrsRes = []browser.ElementAttrs{
+		{Attributes: map[string]string{"href": "/a"}, Text: "A"},
+		{Attributes: map[string]string{"href": "/b", "class": "next"}, Text: "B"},
+	}
+	all, err := client.Attrs(AttrsParams{Selector: "css=a"})
+	if err != nil {
+		t.Fatalf("attrs: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected 2 matches, got %+v", all)
+	}
+	nth := 1
+	one, err := client.Attrs(AttrsParams{Selector: "css=a", Nth: &nth})
+	if err != nil {
+		t.Fatalf("attrs nth: %v", err)
+	}
+	if len(one) != 1 || one[0].Attributes["class"] != "next" || one[0].Text != "B" {
+		t.Fatalf("unexpected nth match: %+v", one)
+	}
+	nth = 5
+	if _, err := client.Attrs(AttrsParams{Selector: "css=a", Nth: &nth}); err == nil || !strings.Contains(err.Error(), "matched 2") {
+		t.Fatalf("expected out of range error with count, got %v", err)
+	}
+	engine.Session.Pages[0].AttrsRes = nil
+	nth = 0
+	none, err := client.Attrs(AttrsParams{Selector: "css=a", Nth: &nth})
+	if err != nil || len(none) != 0 {
+		t.Fatalf("expected no matches without an out of range error, got %+v, %v", none, err)
+	}
+}
+
+func TestServerShotOptions(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	clip := &browser.Rect{X: 1, Y: 2, Width: 30, Height: 40}
+	if err := client.ShotWithOptions(ShotParams{Path: "/tmp/clip.png", Clip: clip}); err != nil {
+		t.Fatalf("shot: %v", err)
+	}
+	page := engine.Session.Pages[0]
+	if page.Clip == nil || *page.Clip != *clip {
+		t.Fatalf("expected clip to be recorded, got %+v", page.Clip)
+	}
+	quality := 70
+	if err := client.ShotWithOptions(ShotParams{Path: "/tmp/shot.jpg", Type: "jpeg", Quality: &quality}); err != nil {
+		t.Fatalf("shot: %v", err)
+	}
+	if page.ShotOpts.Type != "jpeg" || page.ShotOpts.Quality == nil || *page.ShotOpts.Quality != 70 {
+		t.Fatalf("unexpected screenshot options: %+v", page.ShotOpts)
+	}
+	if err := client.ShotWithOptions(ShotParams{Path: "/tmp/clip.png", Clip: clip, Selector: "css=#x"}); err == nil {
+		t.Fatalf("expected error combining clip and selector")
+	}
+}
+
+func TestServeProfileSocketMode(t *testing.T) {
+	for _, tc := range []struct {
+		opts ServeOptions
+		mode os.FileMode
+	}{
+		{ServeOptions{}, 0o600},
+		{ServeOptions{SocketMode: 0o660, DirMode: 0o750}, 0o660},
+	} {
+		dir := filepath.Join(t.TempDir(), "profile")
+		socket := filepath.Join(dir, "daemon.sock")
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- ServeProfile(socket, "test", &browser.FakeEngine{}, browser.StartOptions{}, tc.opts)
+		}()
+		if err := waitForSocket(socket, 2*time.Second); err != nil {
+			t.Fatalf("wait socket: %v", err)
+		}
+		client, err := NewClient(socket)
+		if err != nil {
+			t.Fatalf("client: %v", err)
+		}
+		if _, err := client.Status(); err != nil {
+			t.Fatalf("status: %v", err)
+		}
+		info, err := os.Stat(socket)
+		if err != nil {
+			t.Fatalf("stat: %v", err)
+		}
+		if info.Mode().Perm() != tc.mode {
+			t.Fatalf("expected socket mode %o, got %o", tc.mode, info.Mode().Perm())
+		}
+		dirMode := tc.opts.DirMode
+		if dirMode == 0 {
+			dirMode = 0o700
+		}
+		dirInfo, err := os.Stat(dir)
+		if err != nil {
+			t.Fatalf("stat dir: %v", err)
+		}
+		if dirInfo.Mode().Perm() != dirMode {
+			t.Fatalf("expected dir mode %o, got %o", dirMode, dirInfo.Mode().Perm())
+		}
+		_ = client.Stop()
+		_ = client.Close()
+		<-errCh
+	}
+}
+
+func TestServerDownloads(t *testing.T) {
+	engine := &browser.FakeEngine{Session: &browser.FakeSession{DownloadRes: []browser.Download{
+		{Filename: "a.csv", Path: "/tmp/a.csv", Bytes: 10},
+		{Filename: "b.csv", Path: "/tmp/b.csv", Bytes: 20},
+	}}}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	downloads, err := client.Downloads(1)
+	if err != nil {
+		t.Fatalf("downloads: %v", err)
+	}
+	if len(downloads) != 1 || downloads[0].Filename != "b.csv" || downloads[0].Bytes != 20 {
+		t.Fatalf("unexpected downloads: %+v", downloads)
+	}
+}
+
+func TestServerUpload(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	if err := client.Upload(UploadParams{Selector: "css=input[type=file]", Paths: []string{"/tmp/a.txt", "/tmp/b.txt"}}); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	page := engine.Session.Pages[0]
+	if strings.Join(page.Uploads, ",") != "/tmp/a.txt,/tmp/b.txt" {
+		t.Fatalf("unexpected uploads: %v", page.Uploads)
+	}
+	nth := 1
+	if err := client.Upload(UploadParams{Selector: "css=input[type=file]", Paths: []string{"/tmp/c.txt"}, Nth: &nth}); err != nil {
+		t.Fatalf("upload nth: %v", err)
+	}
+	if page.UploadOpts.Nth == nil || *page.UploadOpts.Nth != 1 {
+		t.Fatalf("expected nth to reach the page, got %+v", page.UploadOpts)
+	}
+	if err := client.Upload(UploadParams{Selector: "css=input"}); err == nil {
+		t.Fatalf("expected error without files")
+	}
+}
+
+func TestServerContent(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	engine.Session.Pages[0].HTML = "<html><body><p>hi</p></body></html>"
+	html, err := client.Content(ContentParams{})
+	if err != nil {
+		t.Fatalf("content: %v", err)
+	}
+	if html != "<html><body><p>hi</p></body></html>" {
+		t.Fatalf("unexpected html: %q", html)
+	}
+}
+
+func TestServerClosesIdleConnection(t *testing.T) {
+	engine := &browser.FakeEngine{Session: &browser.FakeSession{}}
+	socket := filepath.Join(t.TempDir(), "daemon.sock")
+	idleTimeout := 100 * time.Millisecond
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ServeProfile(socket, "test", engine, browser.StartOptions{Headless: true}, ServeOptions{IdleTimeout: &idleTimeout})
+	}()
+	if err := waitForSocket(socket, 2*time.Second); err != nil {
+		t.Fatalf("wait socket: %v", err)
+	}
+	t.Cleanup(func() {
+		if client, err := NewClient(socket); err == nil {
+			_ = client.Stop()
+			_ = client.Close()
+		}
+		<-errCh
+	})
+
+	idle, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer idle.Close()
+	_ = idle.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := idle.Read(make([]byte, 1)); err == nil {
+		t.Fatalf("expected idle connection to be closed")
+	} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatalf("server did not close idle connection")
+	}
+}
+
+func TestServerWaitIdle(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	if err := client.WaitIdle(0, 1000); err != nil {
+		t.Fatalf("wait idle: %v", err)
+	}
+	page := engine.Session.Pages[0]
+	if len(page.LoadStates) != 1 || page.LoadStates[0] != "networkidle" {
+		t.Fatalf("expected networkidle wait, got %v", page.LoadStates)
+	}
+}
+
+func TestServerClickOptions(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	nth := 2
+	if err := client.ClickWithOptions(ClickParams{Selector: "text=Add to cart", Nth: &nth, Exact: true}); err != nil {
+		t.Fatalf("click: %v", err)
+	}
+	opts := engine.Session.Pages[0].ClickOpts
+	if opts.Nth == nil || *opts.Nth != 2 || !opts.Exact {
+		t.Fatalf("expected nth and exact to reach the page, got %+v", opts)
+	}
+}
+
+func TestServerClickDetachedCode(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	engine.Session.Pages[0].ClickErr = fmt.Errorf("%w: css=button matched but was removed", browser.ErrElementDetached)
+	err := client.ClickWithOptions(ClickParams{Selector: "css=button"})
+	if err == nil {
+		t.Fatalf("expected click error")
+	}
+	if code := ErrorCode(err); code != CodeElementDetached {
+		t.Fatalf("expected %s code, got %q (%v)", CodeElementDetached, code, err)
+	}
+	engine.Session.Pages[0].ClickErr = errors.New("boom")
+	if err := client.ClickWithOptions(ClickParams{Selector: "css=button"}); ErrorCode(err) != "" {
+		t.Fatalf("expected no code for other errors, got %q", ErrorCode(err))
+	}
+}
+
+func TestServerNoDefaultTab(t *testing.T) {
+	engine := &browser.FakeEngine{Session: &browser.FakeSession{}}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{NoDefaultTab: true})
+	tabs, err := client.TabList()
+	if err != nil {
+		t.Fatalf("tab list: %v", err)
+	}
+	if len(tabs) != 0 {
+		t.Fatalf("expected no tabs, got %+v", tabs)
+	}
+	if _, err := client.URL(0, 1000); err == nil || !strings.Contains(err.Error(), "no tabs") {
+		t.Fatalf("expected no tabs error, got %v", err)
+	}
+	tab, err := client.TabNew("")
+	if err != nil {
+		t.Fatalf("tab new: %v", err)
+	}
+	if _, err := client.URL(tab.ID, 1000); err != nil {
+		t.Fatalf("url: %v", err)
+	}
+}
+
+func TestServerHome(t *testing.T) {
+	engine := &browser.FakeEngine{Session: &browser.FakeSession{}}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{Home: "https://app.example"})
+	url, err := client.URL(0, 1000)
+	if err != nil {
+		t.Fatalf("url: %v", err)
+	}
+	if url != "https://app.example" {
+		t.Fatalf("expected home url, got %q", url)
+	}
+}
+
+func TestServerRunsTabsInParallel(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	second, err := client.TabNew("")
+	if err != nil {
+		t.Fatalf("tab new: %v", err)
+	}
+	for _, page := range engine.Session.Pages {
+		page.GotoDelay = 300 * time.Millisecond
+	}
+	socket := client.conn.RemoteAddr().String()
+	run := func(tabs ...int) time.Duration {
+		started := time.Now()
+		errs := make(chan error, len(tabs))
+		for _, tab := range tabs {
+			go func(tab int) {
+				c, err := NewClient(socket)
+				if err != nil {
+					errs <- err
+					return
+				}
+				defer c.Close()
+				errs <- c.GotoWithOptions(GotoParams{Tab: tab, URL: "https://example.com"})
+			}(tab)
+		}
+		for range tabs {
+			if err := <-errs; err != nil {
+				t.Fatalf("goto: %v", err)
+			}
+		}
+		return time.Since(started)
+	}
+	if elapsed := run(1, second.ID); elapsed >= 550*time.Millisecond {
+		t.Fatalf("expected different tabs to run in parallel, took %s", elapsed)
+	}
+	if elapsed := run(1, 1); elapsed < 600*time.Millisecond {
+		t.Fatalf("expected the same tab to serialize, took %s", elapsed)
+	}
+}
+
+func TestServerAbandonsHungRequest(t *testing.T) {
+	grace := requestGrace
+	requestGrace = 50 * time.Millisecond
+	t.Cleanup(func() { requestGrace = grace })
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	engine.Session.Pages[0].GotoDelay = time.Second
+
+	started := time.Now()
+	err := client.GotoWithOptions(GotoParams{URL: "https://example.com", TimeoutMs: 100})
+	if err == nil || !strings.Contains(err.Error(), "did not respond") {
+		t.Fatalf("expected hung request error, got %v", err)
+	}
+	if code := ErrorCode(err); code != CodeTimeout {
+		t.Fatalf("expected %s code, got %q", CodeTimeout, code)
+	}
+	if elapsed := time.Since(started); elapsed >= 800*time.Millisecond {
+		t.Fatalf("expected the server to give up early, took %s", elapsed)
+	}
+	if _, err := client.Status(); err != nil {
+		t.Fatalf("status while tab is hung: %v", err)
+	}
+	err = client.GotoWithOptions(GotoParams{URL: "https://example.com", TimeoutMs: 100})
+	if err == nil || !strings.Contains(err.Error(), "busy") {
+		t.Fatalf("expected busy tab error, got %v", err)
+	}
+}
+
+func TestServerURLGivesUpOnBusyTab(t *testing.T) {
+	grace := requestGrace
+	requestGrace = 50 * time.Millisecond
+	t.Cleanup(func() { requestGrace = grace })
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	engine.Session.Pages[0].GotoDelay = time.Second
+	if err := client.GotoWithOptions(GotoParams{URL: "https://example.com", TimeoutMs: 100}); err == nil {
+		t.Fatalf("expected hung goto")
+	}
+
+	started := time.Now()
+	_, err := client.URL(0, 100)
+	if err == nil || !strings.Contains(err.Error(), "busy") {
+		t.Fatalf("expected busy tab error, got %v", err)
+	}
+	if code := ErrorCode(err); code != CodeTimeout {
+		t.Fatalf("expected %s code, got %q", CodeTimeout, code)
+	}
+	if elapsed := time.Since(started); elapsed >= 600*time.Millisecond {
+		t.Fatalf("expected URL to give up on the busy tab, took %s", elapsed)
+	}
+}
+
+func TestServerActionQueuedBehindTabClose(t *testing.T) {
+	grace := requestGrace
+	requestGrace = 50 * time.Millisecond
+	t.Cleanup(func() { requestGrace = grace })
+	server := NewServer("test", &browser.FakeEngine{}, "")
+	if err := server.Init(browser.StartOptions{Headless: true}); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	// Hold the tab as an in-flight action would, so the goto queues behind it
+	// and the close gives up waiting and closes the tab anyway.
+	lock := server.tabLocks[1]
+	lock <- struct{}{}
+	params, _ := json.Marshal(GotoParams{Tab: 1, URL: "https://example.com", TimeoutMs: 1000})
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := server.dispatch(Request{Method: "Goto", Params: params})
+		errCh <- err
+	}()
+	if err := server.tabClose(1); err != nil {
+		t.Fatalf("tab close: %v", err)
+	}
+	<-lock
+	if err := <-errCh; errorCode(err) != CodeTabNotFound {
+		t.Fatalf("expected %s for the queued goto, got %v", CodeTabNotFound, err)
+	}
+}
+
+func TestServerStatusSkipsWedgedPage(t *testing.T) {
+	grace, infoTimeout := requestGrace, tabInfoTimeout
+	requestGrace, tabInfoTimeout = 50*time.Millisecond, 50*time.Millisecond
+	t.Cleanup(func() { requestGrace, tabInfoTimeout = grace, infoTimeout })
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	if _, err := client.TabNew("https://example.com"); err != nil {
+		t.Fatalf("tab new: %v", err)
+	}
+	page := engine.Session.Pages[0]
+	block := make(chan struct{})
+	t.Cleanup(func() { close(block) })
+	page.URLBlock = block
+	page.GotoDelay = time.Second
+	if err := client.GotoWithOptions(GotoParams{Tab: 1, URL: "https://example.com", TimeoutMs: 100}); err == nil {
+		t.Fatalf("expected hung goto")
+	}
+
+	status, err := client.Status()
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if len(status.Tabs) != 2 || !status.Tabs[0].Unresponsive || status.Tabs[1].Unresponsive || status.Tabs[1].URL != "https://example.com" {
+		t.Fatalf("expected tab 1 unresponsive and tab 2 described, got %+v", status.Tabs)
+	}
+	started := time.Now()
+	if err := client.TabClose(1); err != nil {
+		t.Fatalf("tab close: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed >= 800*time.Millisecond {
+		t.Fatalf("expected close not to wait for the hung action, took %s", elapsed)
+	}
+	tabs, err := client.TabList()
+	if err != nil {
+		t.Fatalf("tab list: %v", err)
+	}
+	if len(tabs) != 1 || tabs[0].ID != 2 {
+		t.Fatalf("expected only tab 2 left, got %+v", tabs)
+	}
+}
+
+func TestServerPing(t *testing.T) {
+	client := startTestServer(t, &browser.FakeEngine{}, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	ping, err := client.Ping(time.Second)
+	if err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	if ping.PID != os.Getpid() || ping.TabCount != 1 || ping.Uptime < 0 {
+		t.Fatalf("unexpected ping result: %+v", ping)
+	}
+	if _, err := client.Status(); err != nil {
+		t.Fatalf("status after ping: %v", err)
+	}
+}
+
+func TestServerPingDoesNotWaitOnTabTable(t *testing.T) {
+	server := NewServer("test", &browser.FakeEngine{}, "")
+	if err := server.Init(browser.StartOptions{Headless: true}); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	// Hold the tab table lock as a request stuck in the browser would.
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	done := make(chan any, 1)
+	go func() {
+		result, _ := server.dispatch(Request{Method: "Ping"})
+		done <- result
+	}()
+	select {
+	case result := <-done:
+		if ping, ok := result.(PingResult); !ok || ping.TabCount != 1 {
+			t.Fatalf("unexpected ping result: %+v", result)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("ping waited on the tab table lock")
+	}
+}
+
+func TestServerAutoDismiss(t *testing.T) {
+	engine := &browser.FakeEngine{Session: &browser.FakeSession{}}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{AutoDismiss: []string{"#accept"}})
+	if err := client.Goto(0, "https://example.com", 1000); err != nil {
+		t.Fatalf("goto: %v", err)
+	}
+	page := engine.Session.Pages[0]
+	if len(page.Dismissed) != 1 || page.Dismissed[0] != "#accept" {
+		t.Fatalf("expected dismiss after goto, got %v", page.Dismissed)
+	}
+	if _, err := client.Links(0, "", 1000); err != nil {
+		t.Fatalf("links: %v", err)
+	}
+	if len(page.Dismissed) != 1 {
+		t.Fatalf("expected dismiss only after navigation, got %v", page.Dismissed)
+	}
+}
+
+func TestServerTabNewBackground(t *testing.T) {
+	client := startTestServer(t, &browser.FakeEngine{}, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	tab, err := client.TabNewWithOptions(TabNewParams{URL: "https://example.com", Background: true})
+	if err != nil {
+		t.Fatalf("tab new: %v", err)
+	}
+	if tab.Active {
+		t.Fatalf("expected background tab to be inactive, got %+v", tab)
+	}
+	status, err := client.Status()
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	for _, info := range status.Tabs {
+		if info.Active != (info.ID == 1) {
+			t.Fatalf("expected tab 1 to stay active, got %+v", status.Tabs)
+		}
+	}
+	tab, err = client.TabNew("")
+	if err != nil {
+		t.Fatalf("tab new: %v", err)
+	}
+	if !tab.Active {
+		t.Fatalf("expected foreground tab to be active, got %+v", tab)
+	}
+}
+
+func TestServerGotoInactiveTab(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	second, err := client.TabNewWithOptions(TabNewParams{Background: true})
+	if err != nil {
+		t.Fatalf("tab new: %v", err)
+	}
+	if err := client.GotoWithOptions(GotoParams{Tab: second.ID, URL: "https://example.com/two"}); err != nil {
+		t.Fatalf("goto: %v", err)
+	}
+	status, err := client.Status()
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	for _, tab := range status.Tabs {
+		switch tab.ID {
+		case 1:
+			if !tab.Active || tab.URL != "" {
+				t.Fatalf("expected tab 1 active and untouched, got %+v", tab)
+			}
+		case second.ID:
+			if tab.Active || tab.URL != "https://example.com/two" {
+				t.Fatalf("expected tab %d navigated in the background, got %+v", second.ID, tab)
+			}
+		}
+	}
+}
+
+func TestServerTabActivate(t *testing.T) {
+	client := startTestServer(t, &browser.FakeEngine{}, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	if err := client.Goto(0, "https://example.com/inbox?page=2", 1000); err != nil {
+		t.Fatalf("goto: %v", err)
+	}
+	other, err := client.TabNew("https://other.example")
+	if err != nil {
+		t.Fatalf("tab new: %v", err)
+	}
+	tab, err := client.TabActivate(TabActivateParams{URL: "https://example.com/inbox"})
+	if err != nil {
+		t.Fatalf("activate: %v", err)
+	}
+	if tab.ID != 1 || !tab.Active || tab.URL != "https://example.com/inbox?page=2" {
+		t.Fatalf("expected prefix match on tab 1, got %+v", tab)
+	}
+	tab, err = client.TabActivate(TabActivateParams{URL: "other.example", Match: "contains"})
+	if err != nil {
+		t.Fatalf("activate: %v", err)
+	}
+	if tab.ID != other.ID {
+		t.Fatalf("expected contains match on tab %d, got %+v", other.ID, tab)
+	}
+	tab, err = client.TabActivate(TabActivateParams{URL: "https://example.com/inbox", Match: "exact"})
+	if err != nil {
+		t.Fatalf("activate: %v", err)
+	}
+	if tab.ID == 1 || tab.ID == other.ID || !tab.Active {
+		t.Fatalf("expected a new tab without an exact match, got %+v", tab)
+	}
+	tabs, err := client.TabList()
+	if err != nil {
+		t.Fatalf("tab list: %v", err)
+	}
+	if len(tabs) != 3 {
+		t.Fatalf("expected 3 tabs, got %+v", tabs)
+	}
+	if _, err := client.TabActivate(TabActivateParams{URL: "x", Match: "regex"}); err == nil {
+		t.Fatalf("expected invalid match error")
+	}
+}
+
+func TestServerDragAndMouse(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	if err := client.Drag(DragParams{From: "#card", To: "#done", TimeoutMs: 1000}); err != nil {
+		t.Fatalf("drag: %v", err)
+	}
+	if err := client.MouseMove(MouseParams{X: 120, Y: 48.5}); err != nil {
+		t.Fatalf("mouse: %v", err)
+	}
+	page := engine.Session.Pages[0]
+	if len(page.Drags) != 1 || page.Drags[0] != "#card -> #done" {
+		t.Fatalf("unexpected drags: %v", page.Drags)
+	}
+	if len(page.Moves) != 1 || page.Moves[0] != [2]float64{120, 48.5} {
+		t.Fatalf("unexpected moves: %v", page.Moves)
+	}
+}
+
+func TestServerFocusBlur(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	if err := client.Focus(FocusParams{Selector: "#email"}); err != nil {
+		t.Fatalf("focus: %v", err)
+	}
+	page := engine.Session.Pages[0]
+	if page.Focused != "#email" {
+		t.Fatalf("expected #email focused, got %q", page.Focused)
+	}
+	if err := client.Blur(FocusParams{Selector: "#email"}); err != nil {
+		t.Fatalf("blur: %v", err)
+	}
+	if page.Focused != "" || len(page.Blurred) != 1 {
+		t.Fatalf("expected #email blurred, got focused=%q blurred=%v", page.Focused, page.Blurred)
+	}
+}
+
+func TestServerEphemeralSkipsStorage(t *testing.T) {
+	engine := &browser.FakeEngine{Session: &browser.FakeSession{}}
+	storage := filepath.Join(t.TempDir(), "storage.json")
+	client := startTestServer(t, engine, browser.StartOptions{StorageIn: storage}, ServeOptions{Ephemeral: true})
+	if err := client.Goto(0, "https://example.com", 1000); err != nil {
+		t.Fatalf("goto: %v", err)
+	}
+	if _, err := client.TabNew(""); err != nil {
+		t.Fatalf("tab new: %v", err)
+	}
+	if err := client.Stop(); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if engine.Session.StoragePath != "" {
+		t.Fatalf("expected no storage writes, got %q", engine.Session.StoragePath)
+	}
+	if _, err := os.Stat(storage); !os.IsNotExist(err) {
+		t.Fatalf("expected no storage file, got %v", err)
+	}
+}
+
+func TestServerWaitURL(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	if err := client.WaitForURL(WaitURLParams{Pattern: "**/dashboard", TimeoutMs: 1000}); err != nil {
+		t.Fatalf("wait url: %v", err)
+	}
+	page := engine.Session.Pages[0]
+	if page.WaitedURL != "**/dashboard" {
+		t.Fatalf("unexpected pattern: %q", page.WaitedURL)
+	}
+	if err := client.Goto(0, "https://example.com/login", 1000); err != nil {
+		t.Fatalf("goto: %v", err)
+	}
+	page.WaitURLErr = errors.New("timeout 1000ms exceeded")
+	err := client.WaitForURL(WaitURLParams{Pattern: "**/dashboard", TimeoutMs: 1000})
+	if err == nil || !strings.Contains(err.Error(), "current URL: https://example.com/login") {
+		t.Fatalf("expected current URL in error, got %v", err)
+	}
+}
+
+func TestServerEvalArgs(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	result, err := client.EvalWithOptions(EvalParams{JS: "(a) => a", Args: json.RawMessage(`{"n":5}`)})
+	if err != nil {
+		t.Fatalf("eval: %v", err)
+	}
+	if string(result) != `{"n":5}` {
+		t.Fatalf("expected args echoed back, got %s", result)
+	}
+	engine.Session.Pages[0].EvalResult = json.RawMessage(`10`)
+	result, err = client.Eval(0, "1 + 9", 1000)
+	if err != nil {
+		t.Fatalf("eval: %v", err)
+	}
+	if string(result) != "10" || engine.Session.Pages[0].EvalArg != nil {
+		t.Fatalf("expected plain eval without args, got %s arg=%s", result, engine.Session.Pages[0].EvalArg)
+	}
+}
+
+func TestServerShotStable(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	page := engine.Session.Pages[0]
+	page.EvalResult = json.RawMessage(`true`)
+	path := filepath.Join(t.TempDir(), "page.png")
+	if err := client.ShotWithOptions(ShotParams{Path: path, TimeoutMs: 1000}); err != nil {
+		t.Fatalf("shot: %v", err)
+	}
+	if len(page.Evals) != 0 {
+		t.Fatalf("expected no layout wait by default, got %d evals", len(page.Evals))
+	}
+	if err := client.ShotWithOptions(ShotParams{Path: path, Stable: true, TimeoutMs: 1000}); err != nil {
+		t.Fatalf("shot: %v", err)
+	}
+	if len(page.Evals) != 1 || !strings.Contains(page.Evals[0], "document.fonts.ready") {
+		t.Fatalf("expected a stable layout wait before the shot, got %v", page.Evals)
+	}
+	if len(page.Shots) != 2 {
+		t.Fatalf("expected 2 shots, got %v", page.Shots)
+	}
+}
+
+func TestServerRecycle(t *testing.T) {
+	first := &browser.FakeSession{}
+	engine := &browser.FakeEngine{Session: first}
+	storage := filepath.Join(t.TempDir(), "storage.json")
+	client := startTestServer(t, engine, browser.StartOptions{StorageIn: storage}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	if _, err := client.TabNew("https://example.com"); err != nil {
+		t.Fatalf("tab new: %v", err)
+	}
+	status, err := client.Recycle()
+	if err != nil {
+		t.Fatalf("recycle: %v", err)
+	}
+	if !first.Closed || first.StoragePath != storage {
+		t.Fatalf("expected old session saved and closed, got closed=%t storage=%q", first.Closed, first.StoragePath)
+	}
+	if engine.Starts != 2 || engine.Session == first {
+		t.Fatalf("expected a second session, got %d starts", engine.Starts)
+	}
+	if len(status.Tabs) != 1 || status.Tabs[0].ID != 1 || !status.Tabs[0].Active || status.Tabs[0].URL != "" {
+		t.Fatalf("expected a single blank tab, got %+v", status.Tabs)
+	}
+	if err := client.Goto(0, "https://example.com", 1000); err != nil {
+		t.Fatalf("goto after recycle: %v", err)
+	}
+	if got, _ := engine.Session.Pages[0].URL(); got != "https://example.com" {
+		t.Fatalf("expected goto on the new session, got %q", got)
+	}
+}
+
+func TestServerRecycleBusyTab(t *testing.T) {
+	grace := requestGrace
+	requestGrace = 50 * time.Millisecond
+	t.Cleanup(func() { requestGrace = grace })
+	engine := &browser.FakeEngine{}
+	server := NewServer("test", engine, "")
+	if err := server.Init(browser.StartOptions{Headless: true}); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	first := engine.Session
+	// Hold the tab as an abandoned action would, with a goto queued behind it.
+	lock := server.tabLocks[1]
+	lock <- struct{}{}
+	params, _ := json.Marshal(GotoParams{Tab: 1, URL: "https://example.com", TimeoutMs: 1000})
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := server.dispatch(Request{Method: "Goto", Params: params})
+		errCh <- err
+	}()
+	time.Sleep(50 * time.Millisecond) // let the goto look up the old tab first
+	done := make(chan error, 1)
+	go func() {
+		_, err := server.recycle()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("recycle: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("recycle waited on the busy tab")
+	}
+	if !first.Closed || engine.Session == first {
+		t.Fatalf("expected the old session closed and replaced")
+	}
+	<-lock
+	if err := <-errCh; errorCode(err) != CodeTabNotFound {
+		t.Fatalf("expected %s for the goto on the old tab, got %v", CodeTabNotFound, err)
+	}
+	if got, _ := engine.Session.Pages[0].URL(); got != "" {
+		t.Fatalf("expected the new tab untouched, got %q", got)
+	}
+}
+
+// TestServerRecycleConcurrentRequests calls handleRequest directly: socket
+// I/O counts as synchronization for the race detector and would hide races.
+func TestServerRecycleConcurrentRequests(t *testing.T) {
+	server := NewServer("test", &browser.FakeEngine{}, filepath.Join(t.TempDir(), "storage.json"))
+	if err := server.Init(browser.StartOptions{Headless: true}); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	var wg sync.WaitGroup
+	for _, method := range []string{"Cookies", "TabNew"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if resp := server.handleRequest(Request{Method: method, Params: json.RawMessage("{}")}); resp.Error != nil {
+					t.Errorf("%s: %s", method, resp.Error.Message)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		if resp := server.handleRequest(Request{Method: "Recycle"}); resp.Error != nil {
+			t.Fatalf("recycle: %s", resp.Error.Message)
+		}
+	}
+	wg.Wait()
+}
+
+func TestServerPersistsOnlyAfterMutations(t *testing.T) {
+	session := &browser.FakeSession{}
+	engine := &browser.FakeEngine{Session: session}
+	storage := filepath.Join(t.TempDir(), "storage.json")
+	client := startTestServer(t, engine, browser.StartOptions{StorageIn: storage}, ServeOptions{})
+	waitForTabs(t, client, 1)
+
+	steps := []struct {
+		name  string
+		call  func() error
+		saves int
+	}{
+		{"goto", func() error { return client.Goto(0, "https://example.com", 1000) }, 1},
+		{"url", func() error { _, err := client.URL(0, 1000); return err }, 0},
+		{"links", func() error { _, err := client.Links(0, "", 1000); return err }, 0},
+		{"extract", func() error { _, err := client.Extract(0, 1000); return err }, 0},
+		{"status", func() error { _, err := client.Status(); return err }, 0},
+		{"storage get", func() error {
+			_, err := client.StorageItem(StorageItemParams{Op: "get", Key: "k"})
+			return err
+		}, 0},
+		{"storage set", func() error {
+			_, err := client.StorageItem(StorageItemParams{Op: "set", Key: "k", Value: "v"})
+			return err
+		}, 1},
+		{"click", func() error { return client.Click(0, "#go", 1000) }, 1},
+	}
+	for _, step := range steps {
+		before := session.StorageSaves()
+		if err := step.call(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := session.StorageSaves() - before; got != step.saves {
+			t.Fatalf("%s: expected %d storage saves, got %d", step.name, step.saves, got)
+		}
+	}
+
+	session.Pages[0].ClickErr = errors.New("boom")
+	before := session.StorageSaves()
+	if err := client.Click(0, "#go", 1000); err == nil {
+		t.Fatalf("expected click error")
+	}
+	if got := session.StorageSaves() - before; got != 0 {
+		t.Fatalf("expected no storage save after a failed click, got %d", got)
+	}
+}
+
+func TestServerStorageSaveErrorSparesTabMethods(t *testing.T) {
+	session := &browser.FakeSession{StorageErr: errors.New("disk full")}
+	engine := &browser.FakeEngine{Session: session}
+	storage := filepath.Join(t.TempDir(), "storage.json")
+	client := startTestServer(t, engine, browser.StartOptions{StorageIn: storage}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	tab, err := client.TabNew("")
+	if err != nil {
+		t.Fatalf("tab new should succeed despite the save error: %v", err)
+	}
+	if err := client.TabClose(tab.ID); err != nil {
+		t.Fatalf("tab close should succeed despite the save error: %v", err)
+	}
+	if err := client.Goto(0, "https://example.com", 1000); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("expected goto to report the save error, got %v", err)
+	}
+}
+
+func TestClientDryRun(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	var out bytes.Buffer
+	client.DryRun = &out
+	if err := client.Goto(0, "https://example.com/?a=1&b=2", 1000); err != nil {
+		t.Fatalf("goto: %v", err)
+	}
+	if _, err := client.StorageItem(StorageItemParams{Op: "get", Key: "k"}); err != nil {
+		t.Fatalf("storage get: %v", err)
+	}
+	status, err := client.Status()
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	client.DryRun = nil
+	if got, _ := engine.Session.Pages[0].URL(); got != "" {
+		t.Fatalf("expected goto not to be sent, got %q", got)
+	}
+	if len(status.Tabs) != 1 {
+		t.Fatalf("expected read-only requests to reach the daemon, got %+v", status)
+	}
+	want := `{"method":"Goto","params":{"tab":0,"url":"https://example.com/?a=1&b=2","timeout_ms":1000}}` + "\n"
+	if out.String() != want {
+		t.Fatalf("unexpected dry-run output: %q", out.String())
+	}
+}
+
+func TestServerErrorCodes(t *testing.T) {
+	engine := &browser.FakeEngine{}
+	client := startTestServer(t, engine, browser.StartOptions{}, ServeOptions{})
+	waitForTabs(t, client, 1)
+	err := client.TabSwitch(99)
+	if code := ErrorCode(err); code != CodeTabNotFound {
+		t.Fatalf("expected %s, got %q (%v)", CodeTabNotFound, code, err)
+	}
+	engine.Session.Pages[0].ClickErr = fmt.Errorf("%w for text=%q", browser.ErrNoMatch, "Sign in")
+	err = client.Click(0, "text=Sign in", 1000)
+	if code := ErrorCode(err); code != CodeSelectorNoMatch {
+		t.Fatalf("expected %s, got %q (%v)", CodeSelectorNoMatch, code, err)
+	}
+}
@@ -0,0 +1,53 @@
+package profile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+var ErrProfileBusy = errors.New("profile is in use")
+
+type Lock struct {
+	file *os.File
+}
+
+func (s Store) LockPath(name string) string {
+	return filepath.Join(s.Root, ".locks", sanitizeName(name)+".lock")
+}
+
+func (s Store) LockShared(name string) (*Lock, error) {
+	return s.lock(name, false)
+}
+
+func (s Store) TryLockExclusive(name string) (*Lock, error) {
+	return s.lock(name, true)
+}
+
+func (s Store) lock(name string, exclusive bool) (*Lock, error) {
+	if sanitizeName(name) == "" {
+		return nil, errors.New("profile name required")
+	}
+	path := s.LockPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := flock(f, exclusive); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &Lock{file: f}, nil
+}
+
+func (l *Lock) Unlock() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package profile
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLockExclusiveWhileShared(t *testing.T) {
+	store := Store{Root: t.TempDir()}
+	shared, err := store.LockShared("demo")
+	if err != nil {
+		t.Fatalf("shared lock: %v", err)
+	}
+	if _, err := store.TryLockExclusive("demo"); !errors.Is(err, ErrProfileBusy) {
+		t.Fatalf("expected busy, got %v", err)
+	}
+	if err := shared.Unlock(); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	exclusive, err := store.TryLockExclusive("demo")
+	if err != nil {
+		t.Fatalf("exclusive lock after unlock: %v", err)
+	}
+	_ = exclusive.Unlock()
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package profile
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// LocksEnforced reports whether profile locks exclude other processes.
+const LocksEnforced = true
+
+func flock(f *os.File, exclusive bool) error {
+	if !exclusive {
+		return syscall.Flock(int(f.Fd()), syscall.LOCK_SH)
+	}
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return ErrProfileBusy
+	}
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
@@ -12,18 +13,40 @@ import (
 )
 
 type Profile struct {
-	Name      string    `json:"name"`
-	Browser   string    `json:"browser"`
-	Channel   string    `json:"channel"`
-	Headless  bool      `json:"headless"`
-	TTL       int64     `json:"ttl_seconds"`
-	CreatedAt time.Time `json:"created_at"`
-	LastUsed  time.Time `json:"last_used"`
+	Name         string            `json:"name"`
+	Browser      string            `json:"browser"`
+	Channel      string            `json:"channel"`
+	Headless     bool              `json:"headless"`
+	Width        int               `json:"width,omitempty"`
+	Height       int               `json:"height,omitempty"`
+	Device       string            `json:"device,omitempty"`
+	Trace        bool              `json:"trace,omitempty"`
+	Proxy        string            `json:"proxy,omitempty"`
+	ProxyBypass  string            `json:"proxy_bypass,omitempty"`
+	UserAgent    string            `json:"user_agent,omitempty"`
+	DownloadDir  string            `json:"download_dir,omitempty"`
+	ExtraHeaders map[string]string `json:"extra_headers,omitempty"`
+	Geolocation  *Geolocation      `json:"geolocation,omitempty"`
+	Locale       string            `json:"locale,omitempty"`
+	Timezone     string            `json:"timezone,omitempty"`
+	Home         string            `json:"home,omitempty"`
+	AutoDismiss  []string          `json:"auto_dismiss,omitempty"`
+	TTL          int64             `json:"ttl_seconds"`
+	CreatedAt    time.Time         `json:"created_at"`
+	LastUsed     time.Time         `json:"last_used"`
+}
+
+type Geolocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
 }
 
 type Store struct {
 	Root       string
 	DefaultTTL time.Duration
+	Defaults   map[string]Overrides
+	// MaxAge, when set, also expires profiles unused for longer than it.
+	MaxAge time.Duration
 }
 
 func (s Store) EnsureDir() error {
@@ -38,6 +61,56 @@ func (s Store) ProfilePath(name string) string {
 	return filepath.Join(s.ProfileDir(name), "profile.json")
 }
 
+func (s Store) DownloadDir(p Profile) string {
+	if p.DownloadDir != "" {
+		return p.DownloadDir
+	}
+	return filepath.Join(s.ProfileDir(p.Name), "downloads")
+}
+
+func (s Store) ArtifactsDir(name string) string {
+	return filepath.Join(s.ProfileDir(name), "artifacts")
+}
+
+type Artifact struct {
+	Path    string    `json:"path"`
+	Bytes   int64     `json:"bytes"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Artifacts lists files under the profile's artifacts directory, newest first.
+func (s Store) Artifacts(name string) ([]Artifact, error) {
+	root := s.ArtifactsDir(name)
+	var artifacts []Artifact
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if path == root && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, Artifact{Path: path, Bytes: info.Size(), ModTime: info.ModTime().UTC()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(artifacts, func(i, j int) bool {
+		if artifacts[i].ModTime.Equal(artifacts[j].ModTime) {
+			return artifacts[i].Path < artifacts[j].Path
+		}
+		return artifacts[i].ModTime.After(artifacts[j].ModTime)
+	})
+	return artifacts, nil
+}
+
 func (s Store) StorageStatePath(name string) string {
 	return filepath.Join(s.ProfileDir(name), "storage.json")
 }
@@ -99,11 +172,29 @@ func (s Store) List() ([]Profile, error) {
 	return profiles, nil
 }
 
+// Remove deletes the profile directory, leaving profile.json for last so an
+// interrupted removal still lists the profile and can be retried. Callers
+// hold the exclusive lock. The lock file stays: a process already waiting on
+// it would otherwise lock an unlinked file while the next caller locks a new
+// one.
 func (s Store) Remove(name string) error {
 	if name == "" {
 		return errors.New("profile name required")
 	}
-	return os.RemoveAll(s.ProfileDir(name))
+	dir := s.ProfileDir(name)
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Name() == "profile.json" {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(dir)
 }
 
 func (s Store) Upsert(name string, overrides Overrides) (Profile, bool, error) {
@@ -125,6 +216,7 @@ func (s Store) Upsert(name string, overrides Overrides) (Profile, bool, error) {
 			CreatedAt: time.Now().UTC(),
 			LastUsed:  time.Now().UTC(),
 		}
+		applyOverrides(&p, s.Defaults[name])
 		applyOverrides(&p, overrides)
 		if err := s.Save(p); err != nil {
 			return Profile{}, false, err
@@ -150,35 +242,72 @@ func (s Store) Touch(name string) (Profile, error) {
 }
 
 func (s Store) IsExpired(p Profile) bool {
+	now := time.Now().UTC()
+	if s.MaxAge > 0 && now.Sub(p.LastUsed) > s.MaxAge {
+		return true
+	}
 	if p.TTL <= 0 {
 		return false
 	}
 	deadline := p.LastUsed.Add(time.Duration(p.TTL) * time.Second)
-	return time.Now().UTC().After(deadline)
+	return now.After(deadline)
 }
 
-func (s Store) Prune() ([]Profile, error) {
+// Prune removes expired profiles, except those skip reports and those locked
+// by a running daemon or command. Expiry is checked again under the lock, so
+// a profile used after the listing is kept.
+func (s Store) Prune(skip func(Profile) bool) ([]Profile, error) {
 	profiles, err := s.List()
 	if err != nil {
 		return nil, err
 	}
 	removed := make([]Profile, 0)
 	for _, p := range profiles {
-		if s.IsExpired(p) {
-			if err := s.Remove(p.Name); err != nil {
-				return removed, err
-			}
-			removed = append(removed, p)
+		if !s.IsExpired(p) || (skip != nil && skip(p)) {
+			continue
+		}
+		lock, err := s.TryLockExclusive(p.Name)
+		if errors.Is(err, ErrProfileBusy) {
+			continue
+		}
+		if err != nil {
+			return removed, err
+		}
+		if current, err := s.Load(p.Name); err != nil || !s.IsExpired(current) {
+			_ = lock.Unlock()
+			continue
+		}
+		err = s.Remove(p.Name)
+		_ = lock.Unlock()
+		if err != nil {
+			return removed, err
 		}
+		removed = append(removed, p)
 	}
 	return removed, nil
 }
 
 type Overrides struct {
-	Browser  string
-	Channel  string
-	Headless *bool
-	TTL      *time.Duration
+	Browser      string
+	Channel      string
+	Headless     *bool
+	TTL          *time.Duration
+	Width        int
+	Height       int
+	Device       string
+	Trace        *bool
+	Proxy        string
+	ProxyBypass  string
+	UserAgent    string
+	DownloadDir  string
+	ExtraHeaders map[string]string
+	Geolocation  *Geolocation
+	Locale       string
+	Timezone     string
+	Home         string
+	// AutoDismiss replaces the saved selector list when non-nil; an empty
+	// slice turns auto-dismiss off.
+	AutoDismiss []string
 }
 
 func applyOverrides(p *Profile, overrides Overrides) bool {
@@ -199,6 +328,62 @@ func applyOverrides(p *Profile, overrides Overrides) bool {
 		p.TTL = int64(overrides.TTL.Seconds())
 		updated = true
 	}
+	if overrides.Width > 0 && overrides.Height > 0 {
+		p.Width = overrides.Width
+		p.Height = overrides.Height
+		updated = true
+	}
+	if overrides.Device != "" {
+		p.Device = overrides.Device
+		updated = true
+	}
+	if overrides.Trace != nil {
+		p.Trace = *overrides.Trace
+		updated = true
+	}
+	if overrides.Proxy != "" {
+		p.Proxy = overrides.Proxy
+		updated = true
+	}
+	if overrides.ProxyBypass != "" {
+		p.ProxyBypass = overrides.ProxyBypass
+		updated = true
+	}
+	if overrides.UserAgent != "" {
+		p.UserAgent = overrides.UserAgent
+		updated = true
+	}
+	if overrides.DownloadDir != "" {
+		p.DownloadDir = overrides.DownloadDir
+		updated = true
+	}
+	if len(overrides.ExtraHeaders) > 0 {
+		p.ExtraHeaders = overrides.ExtraHeaders
+		updated = true
+	}
+	if overrides.Geolocation != nil {
+		p.Geolocation = overrides.Geolocation
+		updated = true
+	}
+	if overrides.Locale != "" {
+		p.Locale = overrides.Locale
+		updated = true
+	}
+	if overrides.Timezone != "" {
+		p.Timezone = overrides.Timezone
+		updated = true
+	}
+	if overrides.Home != "" {
+		p.Home = overrides.Home
+		updated = true
+	}
+	if overrides.AutoDismiss != nil {
+		p.AutoDismiss = nil
+		if len(overrides.AutoDismiss) > 0 {
+			p.AutoDismiss = overrides.AutoDismiss
+		}
+		updated = true
+	}
 	return updated
 }
 
@@ -213,6 +398,33 @@ func SafeName(name string) string {
 	return sanitizeName(name)
 }
 
+func RedactProxy(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return proxy
+	}
+	return u.Redacted()
+}
+
+// Redacted returns a copy of p that is safe to print, with proxy credentials
+// and extra header values masked.
+func (p Profile) Redacted() Profile {
+	p.Proxy = RedactProxy(p.Proxy)
+	p.ExtraHeaders = RedactHeaders(p.ExtraHeaders)
+	return p
+}
+
+func RedactHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return headers
+	}
+	redacted := make(map[string]string, len(headers))
+	for name := range headers {
+		redacted[name] = "xxxxx"
+	}
+	return redacted
+}
+
 func FormatTTL(seconds int64) string {
 	if seconds <= 0 {
 		return "never"
@@ -220,6 +432,13 @@ func FormatTTL(seconds int64) string {
 	return time.Duration(seconds * int64(time.Second)).String()
 }
 
+func FormatViewport(width, height int) string {
+	if width <= 0 || height <= 0 {
+		return "default"
+	}
+	return fmt.Sprintf("%dx%d", width, height)
+}
+
 func (p Profile) String() string {
 	return fmt.Sprintf("%s (browser=%s channel=%s headless=%t)", p.Name, p.Browser, p.Channel, p.Headless)
 }